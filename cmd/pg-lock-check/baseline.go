@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// baselineVersion is the schema version written to and accepted from --baseline files
+const baselineVersion = 1
+
+// baseline lists accepted findings; matching results are suppressed from the output
+type baseline struct {
+	Version  int             `json:"version"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+// baselineEntry is one accepted finding; File and Operation are informational, only Fingerprint is matched
+type baselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	File        string `json:"file,omitempty"`
+	Operation   string `json:"operation"`
+}
+
+// loadBaseline reads a --baseline file
+func loadBaseline(path string) (*baseline, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline: %w", err)
+	}
+
+	var b baseline
+	if err := json.Unmarshal(content, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("invalid baseline %s: unsupported version %d, want %d", path, b.Version, baselineVersion)
+	}
+	return &b, nil
+}
+
+// newBaseline records every WARNING or higher result, sorted by fingerprint so rewrites diff cleanly
+func newBaseline(parsed *parser.ParseResult, results []*analyzer.Result) *baseline {
+	b := &baseline{Version: baselineVersion, Findings: []baselineEntry{}}
+	seen := make(map[string]bool)
+	for i, result := range results {
+		if result.Severity < analyzer.SeverityWarning || i >= len(parsed.Statements) {
+			continue
+		}
+		fingerprint := findingFingerprint(parsed.Statements[i], result)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		b.Findings = append(b.Findings, baselineEntry{
+			Fingerprint: fingerprint,
+			File:        fingerprintFile(parsed.Statements[i]),
+			Operation:   result.BaseOperation(),
+		})
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		return b.Findings[i].Fingerprint < b.Findings[j].Fingerprint
+	})
+	return b
+}
+
+// write saves the baseline as indented JSON
+func (b *baseline) write(path string) error {
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writing baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing baseline: %w", err)
+	}
+	return nil
+}
+
+// apply drops the statements and results whose finding is in the baseline
+func (b *baseline) apply(parsed *parser.ParseResult, results []*analyzer.Result) (*parser.ParseResult, []*analyzer.Result) {
+	accepted := make(map[string]bool, len(b.Findings))
+	for _, entry := range b.Findings {
+		accepted[entry.Fingerprint] = true
+	}
+
+	filteredParsed := &parser.ParseResult{Errors: parsed.Errors}
+	filteredResults := make([]*analyzer.Result, 0, len(results))
+	for i, result := range results {
+		if i < len(parsed.Statements) {
+			if accepted[findingFingerprint(parsed.Statements[i], result)] {
+				continue
+			}
+			filteredParsed.Statements = append(filteredParsed.Statements, parsed.Statements[i])
+		}
+		filteredResults = append(filteredResults, result)
+	}
+	return filteredParsed, filteredResults
+}
+
+// findingFingerprint identifies a finding by file, operation and whitespace-collapsed SQL,
+// so it survives statements moving to other lines
+func findingFingerprint(stmt parser.ParsedStatement, result *analyzer.Result) string {
+	sum := sha256.Sum256([]byte(fingerprintFile(stmt) + "\x00" + result.BaseOperation() + "\x00" + strings.Join(strings.Fields(stmt.SQL), " ")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// fingerprintFile returns the file of a statement, falling back to the -f path like onelineFile;
+// SQL from an argument or stdin has no file
+func fingerprintFile(stmt parser.ParsedStatement) string {
+	if stmt.File == "" && fileFlag != "-" {
+		return fileFlag
+	}
+	return stmt.File
+}
+
+// applyBaseline rewrites the --baseline file when --baseline-update is set, then suppresses its findings
+func applyBaseline(path string, update bool, parsed *parser.ParseResult, results []*analyzer.Result) (*parser.ParseResult, []*analyzer.Result, error) {
+	if path == "" {
+		if update {
+			return nil, nil, fmt.Errorf("--baseline-update requires --baseline FILE")
+		}
+		return parsed, results, nil
+	}
+
+	var b *baseline
+	if update {
+		// Refuse to overwrite a baseline written in another schema version
+		if _, err := os.Stat(path); err == nil {
+			if _, err := loadBaseline(path); err != nil {
+				return nil, nil, err
+			}
+		}
+		b = newBaseline(parsed, results)
+		if err := b.write(path); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var err error
+		b, err = loadBaseline(path)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	parsed, results = b.apply(parsed, results)
+	return parsed, results, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBaselineUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	sql := "SELECT 1;\nUPDATE users SET active = false;\nCREATE INDEX idx ON users (email);\n"
+
+	// Accept the current findings
+	output, exitCode := runCommand(t, []string{"--baseline", path, "--baseline-update", "--fail-on", "warning", "--oneline"}, sql)
+	if exitCode != 0 {
+		t.Fatalf("update exit code = %d, want 0\n%s", exitCode, output)
+	}
+	if output != "<input>:1: INFO SELECT\n" {
+		t.Errorf("update output = %q, want only the INFO result", output)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read baseline: %v", err)
+	}
+	if !strings.Contains(string(content), `"version": 1`) ||
+		!strings.Contains(string(content), `"operation": "UPDATE without WHERE"`) ||
+		!strings.Contains(string(content), `"operation": "CREATE INDEX"`) {
+		t.Errorf("baseline missing version or findings:\n%s", content)
+	}
+
+	// Moving statements to other lines keeps them accepted; a new finding is still reported
+	moved := "SELECT 1;\n\nUPDATE users   SET active = false;\nCREATE INDEX idx ON users (email);\nDROP TABLE legacy;\n"
+	output, exitCode = runCommand(t, []string{"--baseline", path, "--fail-on", "warning", "--oneline"}, moved)
+	if exitCode != 3 || output != "<input>:1: INFO SELECT\n<input>:5: CRITICAL DROP TABLE [legacy:AccessExclusive]\n" {
+		t.Errorf("got exit %d with output %q, want exit 3 reporting only DROP TABLE", exitCode, output)
+	}
+
+	// Updating again is reproducible and accepts the new finding
+	before, _ := os.ReadFile(path)
+	runCommand(t, []string{"--baseline", path, "--baseline-update"}, sql)
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Errorf("rewriting the baseline for the same findings changed it:\n%s\n%s", before, after)
+	}
+	runCommand(t, []string{"--baseline", path, "--baseline-update"}, moved)
+	_, exitCode = runCommand(t, []string{"--baseline", path, "--fail-on", "warning"}, moved)
+	if exitCode != 0 {
+		t.Errorf("exit code after accepting all findings = %d, want 0", exitCode)
+	}
+}
+
+func TestBaselineUpdateIgnoresFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	sql := "UPDATE users SET active = false;\nCREATE INDEX idx ON users (email);\n"
+
+	// Findings hidden by the filter are still accepted
+	output, exitCode := runCommand(t, []string{"--baseline", path, "--baseline-update", "--exclude-operations", "CREATE INDEX*"}, sql)
+	if exitCode != 0 {
+		t.Fatalf("update exit code = %d, want 0\n%s", exitCode, output)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read baseline: %v", err)
+	}
+	if !strings.Contains(string(content), `"operation": "CREATE INDEX"`) {
+		t.Errorf("baseline missing the filtered CREATE INDEX finding:\n%s", content)
+	}
+
+	_, exitCode = runCommand(t, []string{"--baseline", path, "--fail-on", "warning"}, sql)
+	if exitCode != 0 {
+		t.Errorf("exit code without the filter = %d, want 0", exitCode)
+	}
+}
+
+func TestBaselineKeysOnFilePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "baseline.json")
+	a := filepath.Join(dir, "a.sql")
+	b := filepath.Join(dir, "b.sql")
+	writeMigration(t, a, "DROP TABLE legacy;\n")
+	writeMigration(t, b, "DROP TABLE legacy;\n")
+
+	// A finding accepted in one -f file stays reported in another
+	output, exitCode := runCommand(t, []string{"--baseline", path, "--baseline-update", "-f", a}, "")
+	if exitCode != 0 {
+		t.Fatalf("update exit code = %d, want 0\n%s", exitCode, output)
+	}
+	if _, exitCode = runCommand(t, []string{"--baseline", path, "--fail-on", "warning", "-f", a}, ""); exitCode != 0 {
+		t.Errorf("exit code for the accepted file = %d, want 0", exitCode)
+	}
+	output, exitCode = runCommand(t, []string{"--baseline", path, "--fail-on", "warning", "--oneline", "-f", b}, "")
+	if exitCode != 3 || !strings.Contains(output, "CRITICAL DROP TABLE") {
+		t.Errorf("got exit %d with output %q, want exit 3 reporting DROP TABLE in %s", exitCode, output, b)
+	}
+}
+
+func TestBaselineErrors(t *testing.T) {
+	dir := t.TempDir()
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version": 2, "findings": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantError string
+	}{
+		{
+			name:      "update without a baseline file",
+			args:      []string{"--baseline-update", "SELECT 1"},
+			wantError: "--baseline-update requires --baseline FILE",
+		},
+		{
+			name:      "missing baseline file",
+			args:      []string{"--baseline", filepath.Join(dir, "missing.json"), "SELECT 1"},
+			wantError: "reading baseline",
+		},
+		{
+			name:      "unsupported version is not overwritten",
+			args:      []string{"--baseline", future, "--baseline-update", "SELECT 1"},
+			wantError: "unsupported version 2, want 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, exitCode := runCommandOutputs(t, tt.args, "")
+			if exitCode != 1 || !strings.Contains(stderr, tt.wantError) {
+				t.Errorf("got exit %d with stderr %q, want exit 1 containing %q", exitCode, stderr, tt.wantError)
+			}
+		})
+	}
+}
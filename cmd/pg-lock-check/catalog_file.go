@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// loadTableOptions reads a --table-options file: a JSON object mapping each table to its current
+// storage parameters, e.g. {"users": {"fillfactor": "90"}, "orders": {}}
+func loadTableOptions(path string) (map[string]map[string]string, error) {
+	return loadCatalogFile[map[string]map[string]string](path, "table options")
+}
+
+// loadSchemaObjects reads a --schema-objects file: a JSON object mapping each schema to the number of
+// objects of each kind it holds, e.g. {"app": {"tables": 12, "functions": 3, "sequences": 4}}
+func loadSchemaObjects(path string) (map[string]map[string]int, error) {
+	return loadCatalogFile[map[string]map[string]int](path, "schema objects")
+}
+
+// loadCatalogFile reads a JSON file exported from the database catalog; what names the file in
+// errors. An empty path means no file was given.
+func loadCatalogFile[T any](path, what string) (T, error) {
+	var catalog T
+	if path == "" {
+		return catalog, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return catalog, fmt.Errorf("reading %s: %w", what, err)
+	}
+
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		var empty T
+		return empty, fmt.Errorf("invalid %s %s: %w", what, path, err)
+	}
+	return catalog, nil
+}
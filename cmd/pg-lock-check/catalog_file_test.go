@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTableOptions(t *testing.T) {
+	path := writeCatalogFile(t, "reloptions.json", `{"users": {"fillfactor": "90"}}`)
+
+	output, exitCode := runCommand(t, []string{"--table-options", path, "ALTER TABLE users SET (fillfactor = 90)"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+	}
+	if !strings.Contains(output, "Note: likely a no-op: the storage parameters of users already have these values (fillfactor = 90)") {
+		t.Errorf("Expected the no-op note, got: %s", output)
+	}
+
+	output, _ = runCommand(t, []string{"--table-options", path, "ALTER TABLE users SET (fillfactor = 80)"}, "")
+	if strings.Contains(output, "no-op") {
+		t.Errorf("Expected no no-op note for a changed option, got: %s", output)
+	}
+}
+
+func TestSchemaObjects(t *testing.T) {
+	path := writeCatalogFile(t, "schemas.json", `{"app": {"tables": 12, "functions": 3}}`)
+
+	output, exitCode := runCommand(t, []string{"--schema-objects", path, "DROP SCHEMA app CASCADE"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+	}
+	if !strings.Contains(output, "[CRITICAL]") || !strings.Contains(output, "Note: CASCADE drops everything in schema app: 3 functions, 12 tables") {
+		t.Errorf("Expected the affected objects note, got: %s", output)
+	}
+
+	output, _ = runCommand(t, []string{"DROP SCHEMA app CASCADE"}, "")
+	if !strings.Contains(output, "Note: CASCADE drops every object in schema app") {
+		t.Errorf("Expected the generic note without a catalog, got: %s", output)
+	}
+}
+
+func TestCatalogFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    string
+		what    string
+		invalid string
+	}{
+		{name: "table options are not an object of tables", flag: "--table-options", what: "table options", invalid: `["users"]`},
+		{name: "schema object counts are not numbers", flag: "--schema-objects", what: "schema objects", invalid: `{"app": {"tables": "many"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := writeCatalogFile(t, "invalid.json", tt.invalid)
+			missing := filepath.Join(t.TempDir(), "missing.json")
+
+			for path, wantError := range map[string]string{
+				missing: "reading " + tt.what,
+				invalid: "invalid " + tt.what + " " + invalid,
+			} {
+				_, stderr, exitCode := runCommandOutputs(t, []string{tt.flag, path, "SELECT 1"}, "")
+				if exitCode != 1 || !strings.Contains(stderr, wantError) {
+					t.Errorf("Expected exit 1 with %q, got %d: %s", wantError, exitCode, stderr)
+				}
+			}
+		})
+	}
+}
+
+// writeCatalogFile writes a catalog JSON file for --table-options or --schema-objects
+func writeCatalogFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the project config discovered in the current or an ancestor directory
+const configFileName = ".pg-lock-check.yaml"
+
+// configExcludedFlags cannot be set from a config file
+var configExcludedFlags = map[string]bool{
+	"config":      true,
+	"no-config":   true,
+	"profile":     true,
+	"profile-out": true,
+}
+
+// configPathFlags take paths, which a config file gives relative to its own directory
+var configPathFlags = map[string]bool{
+	"baseline":           true,
+	"table-options":      true,
+	"schema-objects":     true,
+	"export-suggestions": true,
+	"relative-to":        true,
+}
+
+// applyConfig sets flags not given on the command line from --config, or from the discovered
+// .pg-lock-check.yaml unless --no-config is set. Keys are flag names without the leading --.
+func applyConfig(cmd *cobra.Command) error {
+	if noConfigFlag {
+		if configFile != "" {
+			return fmt.Errorf("--config cannot be combined with --no-config")
+		}
+		return nil
+	}
+
+	path := configFile
+	if path == "" {
+		found, err := findConfig()
+		if err != nil || found == "" {
+			return err
+		}
+		path = found
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	// Sorted so the first invalid setting reported is stable
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := settings[name]
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || configExcludedFlags[name] {
+			return fmt.Errorf("invalid config %s: unknown setting %q", path, name)
+		}
+		// Flags on the command line override the config
+		if flag.Changed {
+			continue
+		}
+
+		text, err := configValue(flag, value)
+		if err != nil {
+			return fmt.Errorf("invalid config %s: %s: %w", path, name, err)
+		}
+		if configPathFlags[name] && text != "" && !filepath.IsAbs(text) {
+			text = filepath.Join(filepath.Dir(path), text)
+		}
+		if err := flag.Value.Set(text); err != nil {
+			return fmt.Errorf("invalid config %s: %s: invalid value %q", path, name, text)
+		}
+		flag.Changed = true
+	}
+	return nil
+}
+
+// findConfig returns the nearest .pg-lock-check.yaml in the current or an ancestor directory, or ""
+func findConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("finding config: %w", err)
+	}
+
+	for {
+		path := filepath.Join(dir, configFileName)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() {
+			return path, nil
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("finding config: %w", err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// configValue converts a YAML value to the text form the flag parses; lists are accepted for list flags
+func configValue(flag *pflag.Flag, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("missing value")
+	case []interface{}:
+		if !strings.HasSuffix(flag.Value.Type(), "Slice") {
+			return "", fmt.Errorf("a list is only accepted for list settings")
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		return "", fmt.Errorf("expected a value, got a mapping")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content as the .pg-lock-check.yaml of dir
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, configFileName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	const warningSQL = "UPDATE users SET active = true WHERE id = 1"
+
+	root := t.TempDir()
+	writeConfig(t, root, "fail-on: warning\nexclude-operations:\n  - DROP TABLE\n")
+	sub := filepath.Join(root, "db", "migrations")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		args       []string
+		wantExit   int
+		wantOutput string
+		noOutput   string
+	}{
+		{
+			name:     "config in the current directory",
+			dir:      root,
+			args:     []string{warningSQL},
+			wantExit: 3,
+		},
+		{
+			name:     "config in an ancestor directory",
+			dir:      sub,
+			args:     []string{warningSQL},
+			wantExit: 3,
+		},
+		{
+			name:     "flag overrides the config",
+			dir:      sub,
+			args:     []string{"--fail-on", "critical", warningSQL},
+			wantExit: 0,
+		},
+		{
+			name:     "list settings",
+			dir:      sub,
+			args:     []string{"--fail-on", "critical", "DROP TABLE users; SELECT 1"},
+			wantExit: 0,
+			noOutput: "DROP TABLE",
+		},
+		{
+			name:       "no-config disables discovery",
+			dir:        sub,
+			args:       []string{"--no-config", "DROP TABLE users"},
+			wantExit:   0,
+			wantOutput: "DROP TABLE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(tt.dir)
+
+			output, _, exitCode := runCommandWithConfig(t, tt.args, "")
+			if exitCode != tt.wantExit {
+				t.Errorf("Expected exit %d, got %d: %s", tt.wantExit, exitCode, output)
+			}
+			if tt.wantOutput != "" && !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("Expected output to contain %q, got: %s", tt.wantOutput, output)
+			}
+			if tt.noOutput != "" && strings.Contains(output, tt.noOutput) {
+				t.Errorf("Expected output not to contain %q, got: %s", tt.noOutput, output)
+			}
+		})
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// Paths in the config are relative to the config file
+	configDir := filepath.Join(dir, "config")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "reloptions.json"), []byte(`{"users": {"fillfactor": "90"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(configDir, "lock-check.yaml")
+	if err := os.WriteFile(path, []byte("table-options: reloptions.json\noutput: json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, _, exitCode := runCommandWithConfig(t, []string{"--config", path, "ALTER TABLE users SET (fillfactor = 90)"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+	}
+	if !strings.HasPrefix(output, "{") || !strings.Contains(output, "likely a no-op") {
+		t.Errorf("Expected JSON output with the no-op note, got: %s", output)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		args      []string
+		wantError string
+	}{
+		{
+			name:      "unknown setting",
+			config:    "pg-version: 16\n",
+			wantError: `unknown setting "pg-version"`,
+		},
+		{
+			name:      "invalid value",
+			config:    "large-insert-rows: many\n",
+			wantError: `large-insert-rows: invalid value "many"`,
+		},
+		{
+			name:      "list for a single-value setting",
+			config:    "fail-on:\n  - warning\n",
+			wantError: "fail-on: a list is only accepted for list settings",
+		},
+		{
+			name:      "validation still applies",
+			config:    "fail-on: sometimes\n",
+			wantError: `invalid --fail-on "sometimes"`,
+		},
+		{
+			name:      "config and no-config",
+			args:      []string{"--config", "other.yaml", "--no-config"},
+			wantError: "--config cannot be combined with --no-config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			if tt.config != "" {
+				writeConfig(t, dir, tt.config)
+			}
+
+			_, stderr, exitCode := runCommandWithConfig(t, append(tt.args, "SELECT 1"), "")
+			if exitCode != 1 || !strings.Contains(stderr, tt.wantError) {
+				t.Errorf("Expected exit 1 with %q, got %d: %s", tt.wantError, exitCode, stderr)
+			}
+		})
+	}
+}
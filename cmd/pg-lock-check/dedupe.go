@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// findingKey identifies identical findings for --dedupe; table names and SQL are ignored
+type findingKey struct {
+	file      string
+	severity  analyzer.Severity
+	operation string
+	lockType  analyzer.LockType
+}
+
+// findingGroup is a result reported once for --dedupe, with every statement sharing its finding
+type findingGroup struct {
+	index int
+	lines []int
+}
+
+// groupFindings collapses results with the same file, severity, operation and lock into one group
+// per finding, in order of first appearance. Without --dedupe every result is its own group.
+func groupFindings(parsed *parser.ParseResult, results []*analyzer.Result) []*findingGroup {
+	groups := make([]*findingGroup, 0, len(results))
+	byKey := make(map[findingKey]*findingGroup)
+	for i, result := range results {
+		file, line := "", 1
+		if i < len(parsed.Statements) {
+			file, line = parsed.Statements[i].File, parsed.Statements[i].LineNumber
+		}
+		if !dedupeFlag {
+			groups = append(groups, &findingGroup{index: i})
+			continue
+		}
+
+		key := findingKey{file: file, severity: result.Severity, operation: result.Operation(), lockType: result.LockType()}
+		if group, ok := byKey[key]; ok {
+			group.lines = append(group.lines, line)
+			continue
+		}
+		group := &findingGroup{index: i, lines: []int{line}}
+		byKey[key] = group
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// outputTextOccurrences prints how often a deduplicated finding occurs and where
+func outputTextOccurrences(lines []int) {
+	if len(lines) < 2 {
+		return
+	}
+	numbers := make([]string, len(lines))
+	for i, line := range lines {
+		numbers[i] = fmt.Sprint(line)
+	}
+	fmt.Printf("  Occurrences: %d (lines %s)\n", len(lines), strings.Join(numbers, ", "))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/spf13/cobra"
+)
+
+// buildDescribeLockCommand creates the describe-lock subcommand, which explains a table lock mode
+func buildDescribeLockCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe-lock LOCK_TYPE",
+		Short: "Describe what a table lock mode blocks and which statements acquire it",
+		Long: `Describe a table lock mode reported by pg-lock-check, e.g. AccessExclusive.
+
+Prints whether the lock blocks reads, writes and DDL on the table, statements that
+typically acquire it, and the lock modes it conflicts with.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lockType, err := analyzer.ParseLockType(args[0])
+			if err != nil {
+				return err
+			}
+			describeLock(os.Stdout, lockType)
+			return nil
+		},
+	}
+}
+
+// describeLock writes the description of lockType derived from the lock conflict matrix
+func describeLock(w io.Writer, lockType analyzer.LockType) {
+	// Reads take AccessShare, writes RowExclusive, and DDL usually AccessExclusive
+	blocks := func(other analyzer.LockType, statements string) string {
+		if analyzer.Conflicts(lockType, other) {
+			return "yes (" + statements + ")"
+		}
+		return "no"
+	}
+
+	conflicts := analyzer.ConflictingLocks(lockType)
+	names := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		names[i] = string(conflict)
+	}
+
+	fmt.Fprintln(w, lockType)
+	fmt.Fprintf(w, "  Blocks reads:   %s\n", blocks(analyzer.AccessShare, "SELECT"))
+	fmt.Fprintf(w, "  Blocks writes:  %s\n", blocks(analyzer.RowExclusive, "INSERT, UPDATE, DELETE, MERGE"))
+	fmt.Fprintf(w, "  Blocks DDL:     %s\n", blocks(analyzer.AccessExclusive, "ALTER TABLE, DROP TABLE, TRUNCATE"))
+	fmt.Fprintf(w, "  Acquired by:    %s\n", strings.Join(analyzer.TypicalStatements(lockType), ", "))
+	fmt.Fprintf(w, "  Conflicts with: %s\n", strings.Join(names, ", "))
+}
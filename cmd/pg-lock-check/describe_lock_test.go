@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribeLock(t *testing.T) {
+	tests := []struct {
+		lock       string
+		wantOutput []string
+	}{
+		{
+			lock: "AccessExclusive",
+			wantOutput: []string{
+				"Blocks reads:   yes (SELECT)",
+				"Blocks writes:  yes (INSERT, UPDATE, DELETE, MERGE)",
+				"Blocks DDL:     yes",
+				"Conflicts with: AccessShare, RowShare, RowExclusive, ShareUpdateExclusive, Share, ShareRowExclusive, Exclusive, AccessExclusive",
+			},
+		},
+		{
+			lock: "share",
+			wantOutput: []string{
+				"Share\n",
+				"Blocks reads:   no",
+				"Blocks writes:  yes",
+				"Acquired by:    CREATE INDEX\n",
+			},
+		},
+		{
+			lock: "AccessShareLock",
+			wantOutput: []string{
+				"Blocks reads:   no",
+				"Blocks writes:  no",
+				"Conflicts with: AccessExclusive\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lock, func(t *testing.T) {
+			output, exitCode := runCommand(t, []string{"describe-lock", tt.lock}, "")
+			if exitCode != 0 {
+				t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got: %s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeLockUnknown(t *testing.T) {
+	_, stderr, exitCode := runCommandOutputs(t, []string{"describe-lock", "SuperExclusive"}, "")
+	if exitCode != 1 || !strings.Contains(stderr, `unknown lock type "SuperExclusive": must be one of AccessShare, RowShare`) {
+		t.Errorf("Expected exit 1 with unknown lock type error, got %d: %s", exitCode, stderr)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Migration orders accepted by dir --order
+const (
+	migrationOrderNumeric = "numeric"
+	migrationOrderLexical = "lexical"
+)
+
+// migrationFile is a .sql file of a migration directory with its numeric prefix, if any
+type migrationFile struct {
+	path      string
+	number    uint64
+	hasNumber bool
+}
+
+// buildDirCommand creates the dir subcommand, which analyzes a migration directory in order
+func buildDirCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dir DIRECTORY",
+		Short: "Analyze the .sql migrations of a directory in migration order",
+		Long: `Analyze the .sql files directly in DIRECTORY in migration order.
+
+With --order numeric (default), files are sorted by their numeric prefix (0001_init.sql,
+0002_users.sql, ...) and gaps or duplicates in the numbering are reported as warnings on stderr.
+With --order lexical, files are sorted by name, e.g. for timestamp prefixes.`,
+		Args: cobra.ExactArgs(1),
+	}
+
+	cmd.Flags().StringVar(&migrationOrder, "order", migrationOrderNumeric, "`ORDER` of the migrations: numeric (by numeric prefix), lexical (by name)")
+	cmd.Flags().BoolVar(&crossFileTransactions, "cross-file-transactions", false, "carry transaction state from one file to the next, for tools that run all migrations in one transaction")
+	return cmd
+}
+
+// orderedMigrationFiles lists the .sql files directly in dir in migration order, and warns about
+// numbering gaps and duplicates for numeric order
+func orderedMigrationFiles(dir, order string) ([]string, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading migration directory: %w", err)
+	}
+
+	var migrations []migrationFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".sql") {
+			continue
+		}
+		migration := migrationFile{path: filepath.Join(dir, entry.Name())}
+		digits := len(entry.Name()) - len(strings.TrimLeft(entry.Name(), "0123456789"))
+		if digits > 0 {
+			if number, err := strconv.ParseUint(entry.Name()[:digits], 10, 64); err == nil {
+				migration.number, migration.hasNumber = number, true
+			}
+		}
+		migrations = append(migrations, migration)
+	}
+
+	var warnings []string
+	switch order {
+	case migrationOrderLexical:
+		// os.ReadDir already sorts by name
+	case migrationOrderNumeric:
+		// Unnumbered files go last, in name order
+		sort.SliceStable(migrations, func(i, j int) bool {
+			if migrations[i].hasNumber != migrations[j].hasNumber {
+				return migrations[i].hasNumber
+			}
+			return migrations[i].number < migrations[j].number
+		})
+		warnings = numberingWarnings(migrations)
+	default:
+		return nil, nil, fmt.Errorf("invalid --order %q: must be numeric or lexical", order)
+	}
+
+	files := make([]string, len(migrations))
+	for i, migration := range migrations {
+		files[i] = migration.path
+	}
+	return files, warnings, nil
+}
+
+// numberingWarnings reports duplicate numbers, gaps and unnumbered files of migrations sorted numerically
+func numberingWarnings(migrations []migrationFile) []string {
+	var warnings []string
+	for i, migration := range migrations {
+		if !migration.hasNumber {
+			warnings = append(warnings, fmt.Sprintf("%s has no numeric prefix; analyzed after the numbered migrations", migration.path))
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		previous := migrations[i-1]
+		switch {
+		case migration.number == previous.number:
+			warnings = append(warnings, fmt.Sprintf("duplicate migration number %d: %s and %s", migration.number, previous.path, migration.path))
+		case migration.number > previous.number+1:
+			warnings = append(warnings, fmt.Sprintf("gap in migration numbering: %s is followed by %s", previous.path, migration.path))
+		}
+	}
+	return warnings
+}
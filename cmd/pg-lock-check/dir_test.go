@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeMigration(t, "migrations/0010_index.sql", "CREATE INDEX idx_users_id ON users (id);\n")
+	writeMigration(t, "migrations/0001_init.sql", "CREATE TABLE users (id int);\n")
+	writeMigration(t, "migrations/0002_begin.sql", "BEGIN;\n")
+	writeMigration(t, "migrations/0003_concurrently.sql", "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\nCOMMIT;\n")
+	writeMigration(t, "migrations/notes.txt", "not SQL\n")
+
+	t.Run("numeric order", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--order", "numeric", "--transaction-mode", "auto", "--oneline", "migrations"}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, stderr)
+		}
+
+		want := "migrations/0001_init.sql:1: INFO CREATE TABLE\n" +
+			"migrations/0002_begin.sql:1: INFO BEGIN\n" +
+			"migrations/0003_concurrently.sql:1: WARNING CREATE INDEX CONCURRENTLY [users:ShareUpdateExclusive]\n" +
+			"migrations/0003_concurrently.sql:2: INFO COMMIT\n" +
+			"migrations/0010_index.sql:1: CRITICAL CREATE INDEX [users:Share]\n"
+		if stdout != want {
+			t.Errorf("Output = %q, want %q", stdout, want)
+		}
+		if !strings.Contains(stderr, "Warning: gap in migration numbering: migrations/0003_concurrently.sql is followed by migrations/0010_index.sql") {
+			t.Errorf("Expected numbering gap warning, got %q", stderr)
+		}
+	})
+
+	t.Run("cross-file transactions", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"dir", "--cross-file-transactions", "--transaction-mode", "auto", "--oneline", "migrations"}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+
+		// The BEGIN of 0002 is still open when 0003 runs
+		if !strings.Contains(output, "migrations/0003_concurrently.sql:1: ERROR CREATE INDEX CONCURRENTLY") {
+			t.Errorf("Expected CREATE INDEX CONCURRENTLY to be an ERROR across files, got %q", output)
+		}
+	})
+
+	t.Run("lexical order", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--order", "lexical", "--oneline", "migrations"}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, stderr)
+		}
+		if stderr != "" {
+			t.Errorf("Expected no warnings for lexical order, got %q", stderr)
+		}
+		if !strings.HasPrefix(stdout, "migrations/0001_init.sql:1:") {
+			t.Errorf("Expected 0001_init.sql first, got %q", stdout)
+		}
+	})
+}
+
+func TestDirOnelineParseErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeMigration(t, "migrations/0001_init.sql", "CREATE TABLE users (id int);\n")
+	writeMigration(t, "migrations/0002_typo.sql", "SELECT 1;\nSELEC * FROM users;\n")
+
+	stdout, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--continue-on-parse-error", "--oneline", "migrations"}, "")
+	if exitCode != 2 {
+		t.Fatalf("Expected exit 2, got %d: %s", exitCode, stderr)
+	}
+
+	want := `migrations/0002_typo.sql:2: PARSE ERROR syntax error at or near "SELEC"` + "\n"
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("Expected output ending in %q, got %q", want, stdout)
+	}
+}
+
+func TestDirNumberingWarnings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeMigration(t, "m/001_a.sql", "SELECT 1;\n")
+	writeMigration(t, "m/001_b.sql", "SELECT 2;\n")
+	writeMigration(t, "m/002_c.sql", "SELECT 3;\n")
+	writeMigration(t, "m/seed.sql", "SELECT 4;\n")
+
+	files, warnings, err := orderedMigrationFiles("m", migrationOrderNumeric)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []string{"m/001_a.sql", "m/001_b.sql", "m/002_c.sql", "m/seed.sql"}
+	if strings.Join(files, ",") != strings.Join(wantFiles, ",") {
+		t.Errorf("Files = %v, want %v", files, wantFiles)
+	}
+	wantWarnings := []string{
+		"duplicate migration number 1: m/001_a.sql and m/001_b.sql",
+		"m/seed.sql has no numeric prefix; analyzed after the numbered migrations",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(wantWarnings, "\n") {
+		t.Errorf("Warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestDirErrors(t *testing.T) {
+	t.Run("invalid order", func(t *testing.T) {
+		dir := t.TempDir()
+		_, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--order", "random", dir}, "")
+		if exitCode != 1 || !strings.Contains(stderr, `invalid --order "random"`) {
+			t.Errorf("Expected exit 1 with order error, got %d: %s", exitCode, stderr)
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, stderr, exitCode := runCommandOutputs(t, []string{"dir", "no-such-dir"}, "")
+		if exitCode != 1 || !strings.Contains(stderr, "reading migration directory") {
+			t.Errorf("Expected exit 1 with directory error, got %d: %s", exitCode, stderr)
+		}
+	})
+
+	t.Run("combined with --file", func(t *testing.T) {
+		dir := t.TempDir()
+		_, stderr, exitCode := runCommandOutputs(t, []string{"dir", "-f", "testdata/simple.sql", dir}, "")
+		if exitCode != 1 || !strings.Contains(stderr, "cannot be combined with") {
+			t.Errorf("Expected exit 1 with combination error, got %d: %s", exitCode, stderr)
+		}
+	})
+}
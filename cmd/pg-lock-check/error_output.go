@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputError is printed instead of results when the input fails to parse with -o json or yaml
+type OutputError struct {
+	Error OutputErrorDetail `json:"error" yaml:"error"`
+}
+
+// OutputErrorDetail describes why the run stopped
+type OutputErrorDetail struct {
+	Type      string `json:"type" yaml:"type"`
+	Message   string `json:"message" yaml:"message"`
+	File      string `json:"file,omitempty" yaml:"file,omitempty"`
+	Line      int    `json:"line" yaml:"line"`
+	Statement int    `json:"statement" yaml:"statement"`
+}
+
+// outputStructuredError prints a parse error that stopped the run as an error object on stdout
+// for JSON and YAML output, and reports whether it did. Other errors, and parse errors reported
+// with --continue-on-parse-error alongside the results, are left to the plain text message.
+func outputStructuredError(err error) bool {
+	if outputFormat != "json" && outputFormat != "yaml" {
+		return false
+	}
+	var stmtErr parser.StatementError
+	if !errors.As(err, &stmtErr) {
+		return false
+	}
+
+	detail := OutputErrorDetail{
+		Type:      "parse",
+		Message:   stmtErr.Error(),
+		File:      stmtErr.File,
+		Line:      stmtErr.ErrorLine(),
+		Statement: stmtErr.Statement,
+	}
+	if stmtErr.Err != nil {
+		detail.Message = stmtErr.Err.Error()
+	}
+	if detail.File == "" {
+		detail.File = reportedPath(stdinFilename)
+	}
+
+	output := OutputError{Error: detail}
+	if outputFormat == "yaml" {
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(indentFlag)
+		return encoder.Encode(output) == nil
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	if indentFlag > 0 {
+		encoder.SetIndent("", strings.Repeat(" ", indentFlag))
+	}
+	return encoder.Encode(output) == nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStructuredParseError(t *testing.T) {
+	sql := "SELECT 1;\nUPDATE users\nSET active = false\nWHER id = 1;\n"
+
+	t.Run("json", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"-o", "json"}, sql)
+		if exitCode != 2 {
+			t.Fatalf("Expected exit 2, got %d", exitCode)
+		}
+		if stderr != "" {
+			t.Errorf("Expected no plain text error, got %q", stderr)
+		}
+
+		var output OutputError
+		if err := json.Unmarshal([]byte(stdout), &output); err != nil {
+			t.Fatalf("Expected a JSON error object, got %q: %v", stdout, err)
+		}
+		if output.Error.Type != "parse" || output.Error.Line != 4 || output.Error.Statement != 2 {
+			t.Errorf("Unexpected error %+v", output.Error)
+		}
+		if !strings.Contains(output.Error.Message, "syntax error") {
+			t.Errorf("Expected the parser message, got %q", output.Error.Message)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		stdout, _, exitCode := runCommandOutputs(t, []string{"-o", "yaml"}, sql)
+		if exitCode != 2 {
+			t.Fatalf("Expected exit 2, got %d", exitCode)
+		}
+
+		var output OutputError
+		if err := yaml.Unmarshal([]byte(stdout), &output); err != nil {
+			t.Fatalf("Expected a YAML error object, got %q: %v", stdout, err)
+		}
+		if output.Error.Type != "parse" || output.Error.Line != 4 {
+			t.Errorf("Unexpected error %+v", output.Error)
+		}
+	})
+
+	t.Run("stdin-filename", func(t *testing.T) {
+		stdout, _, exitCode := runCommandOutputs(t, []string{"-o", "json", "--stdin-filename", "migration.sql"}, sql)
+		if exitCode != 2 {
+			t.Fatalf("Expected exit 2, got %d", exitCode)
+		}
+		var output OutputError
+		if err := json.Unmarshal([]byte(stdout), &output); err != nil {
+			t.Fatalf("Expected a JSON error object, got %q: %v", stdout, err)
+		}
+		if output.Error.File != "migration.sql" {
+			t.Errorf("Expected file migration.sql, got %q", output.Error.File)
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "002_bad.sql")
+		if err := os.WriteFile(filepath.Join(dir, "001_ok.sql"), []byte("SELECT 1;\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(sql), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		stdout, _, exitCode := runCommandOutputs(t, []string{"dir", dir, "-o", "json"}, "")
+		if exitCode != 2 {
+			t.Fatalf("Expected exit 2, got %d", exitCode)
+		}
+		var output OutputError
+		if err := json.Unmarshal([]byte(stdout), &output); err != nil {
+			t.Fatalf("Expected a JSON error object, got %q: %v", stdout, err)
+		}
+		if output.Error.File != path || output.Error.Line != 4 {
+			t.Errorf("Expected %s line 4, got %+v", path, output.Error)
+		}
+	})
+
+	t.Run("exit-code-map", func(t *testing.T) {
+		stdout, _, exitCode := runCommandOutputs(t, []string{"-o", "json", "--exit-code-map", "parse=20"}, sql)
+		if exitCode != 20 {
+			t.Fatalf("Expected exit 20, got %d", exitCode)
+		}
+		if !json.Valid([]byte(stdout)) {
+			t.Errorf("Expected valid JSON, got %q", stdout)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{}, sql)
+		if exitCode != 2 {
+			t.Fatalf("Expected exit 2, got %d", exitCode)
+		}
+		if stdout != "" || !strings.Contains(stderr, "parse error at line 2, statement 2") {
+			t.Errorf("Expected the plain text error on stderr, got stdout %q, stderr %q", stdout, stderr)
+		}
+	})
+}
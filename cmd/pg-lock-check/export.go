@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/nnaka2992/pg-lock-check/internal/suggester"
+)
+
+// nonSlugChars matches characters that are replaced when building file names
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFile is a migration file built from one or more suggestion steps
+type exportFile struct {
+	name    string
+	content strings.Builder
+}
+
+// exportSuggestions writes each suggestion shown per --suggest-for as migration files.
+// Consecutive transactional steps share a file; every step that cannot run in a transaction
+// gets its own file, since migration tools wrap each file in a transaction.
+func exportSuggestions(dir string, parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating export directory: %w", err)
+	}
+
+	for i, result := range results {
+		if !shouldShowSuggestion(result, s) {
+			continue
+		}
+
+		suggestion, err := getSuggestion(parsed, i, result, s)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range buildExportFiles(statementIndex(parsed, i), result.BaseOperation(), suggestion) {
+			path := filepath.Join(dir, file.name)
+			if err := os.WriteFile(path, []byte(file.content.String()), 0644); err != nil {
+				return fmt.Errorf("writing suggestion file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildExportFiles groups suggestion steps into migration files named by statement index and operation
+func buildExportFiles(index int, operation string, suggestion *suggester.Suggestion) []*exportFile {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(operation), "_"), "_")
+
+	var files []*exportFile
+	var current *exportFile
+	for _, step := range suggestion.Steps {
+		// Start a new file for every non-transactional step and after one
+		if current == nil || !step.CanRunInTransaction || strings.HasSuffix(current.name, "_no_transaction.sql") {
+			suffix := ".sql"
+			if !step.CanRunInTransaction {
+				suffix = "_no_transaction.sql"
+			}
+			current = &exportFile{name: fmt.Sprintf("%03d_%s_%d%s", index+1, slug, len(files)+1, suffix)}
+			files = append(files, current)
+		}
+
+		writeExportStep(&current.content, step)
+	}
+
+	return files
+}
+
+// writeExportStep writes a step as SQL; commands and instructions become comments
+func writeExportStep(b *strings.Builder, step suggester.Step) {
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "-- Step: %s\n", step.Description)
+
+	switch {
+	case step.SQL != "":
+		b.WriteString(strings.TrimRight(step.SQL, "\n"))
+		b.WriteString("\n")
+	case step.Command != "":
+		b.WriteString("-- Run outside the database:\n")
+		writeComment(b, step.Command)
+	case step.Notes != "":
+		writeComment(b, step.Notes)
+	}
+}
+
+// writeComment writes each non-empty line of text as a SQL comment
+func writeComment(b *strings.Builder, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(b, "-- %s\n", line)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExportSuggestions(t *testing.T) {
+	dir := t.TempDir()
+
+	sql := "SELECT 1;\nCREATE INDEX idx_users_email ON users(email);\nALTER TABLE users ADD CONSTRAINT age_check CHECK (age >= 0);"
+	_, exitCode := runCommand(t, []string{"--export-suggestions", dir, "-o", "json"}, sql)
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d", exitCode)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read export directory: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	expectedFiles := map[string]string{
+		// Each CONCURRENTLY step lands in its own non-transactional file
+		"002_create_index_1.sql":                "to_regclass('idx_users_email');\n-- false: a failed earlier attempt left it INVALID; drop it outside a transaction before creating it\n",
+		"002_create_index_2_no_transaction.sql": "-- Step: Use `CREATE INDEX CONCURRENTLY` outside transaction\nCREATE INDEX CONCURRENTLY idx_users_email ON users (email);\n",
+		"002_create_index_3.sql":                "-- false: the build failed; drop the index outside a transaction and create it again\n",
+		// Consecutive transactional steps share one file
+		"003_alter_table_add_constraint_check_1.sql": "NOT VALID;\n\n-- Step: Then `VALIDATE CONSTRAINT`\nALTER TABLE users VALIDATE CONSTRAINT age_check;\n",
+	}
+
+	if len(names) != len(expectedFiles) {
+		t.Fatalf("Expected %d files, got %v", len(expectedFiles), names)
+	}
+
+	for name, expectedContent := range expectedFiles {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected file %s: %v (got %v)", name, err, names)
+			continue
+		}
+		if !strings.HasSuffix(string(content), expectedContent) {
+			t.Errorf("File %s: expected content ending with %q, got %q", name, expectedContent, string(content))
+		}
+	}
+}
+
+func TestExportSuggestionsSplitsNonTransactionalSteps(t *testing.T) {
+	dir := t.TempDir()
+
+	_, exitCode := runCommand(t, []string{"--export-suggestions", dir, "-o", "json"}, "UPDATE users SET active = false")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d", exitCode)
+	}
+
+	for _, name := range []string{"001_update_without_where_1.sql", "001_update_without_where_2_no_transaction.sql"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected file %s: %v", name, err)
+		}
+		if !strings.HasPrefix(string(content), "-- Step: ") {
+			t.Errorf("File %s: expected step header, got %q", name, string(content))
+		}
+	}
+}
+
+func TestExportSuggestionsFollowsSuggestFor(t *testing.T) {
+	sql := "ALTER TABLE users ENABLE ROW LEVEL SECURITY"
+
+	// WARNING suggestions are only exported with --suggest-for warning
+	dir := t.TempDir()
+	runCommand(t, []string{"--export-suggestions", dir, "-o", "json"}, sql)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files by default, got %d", len(entries))
+	}
+
+	dir = t.TempDir()
+	runCommand(t, []string{"--export-suggestions", dir, "--suggest-for", "warning", "-o", "json"}, sql)
+	if _, err := os.Stat(filepath.Join(dir, "001_alter_table_enable_row_level_security_1.sql")); err != nil {
+		t.Errorf("Expected the WARNING suggestion with --suggest-for warning: %v", err)
+	}
+}
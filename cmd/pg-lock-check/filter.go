@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// operationFilter selects results by operation name globs from --include-operations/--exclude-operations
+type operationFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newOperationFilter compiles the include and exclude globs
+func newOperationFilter(include, exclude []string) (*operationFilter, error) {
+	f := &operationFilter{}
+	for _, pattern := range include {
+		re, err := compileOperationGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --include-operations pattern %q: %w", pattern, err)
+		}
+		f.include = append(f.include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := compileOperationGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exclude-operations pattern %q: %w", pattern, err)
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	return f, nil
+}
+
+// compileOperationGlob converts a case-insensitive glob (`*`, `?`, `[...]`) into an anchored regexp
+func compileOperationGlob(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern cannot be empty")
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 1 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// matches reports whether an operation passes the filter
+func (f *operationFilter) matches(operation string) bool {
+	if len(f.include) > 0 && !matchesAny(f.include, operation) {
+		return false
+	}
+	return !matchesAny(f.exclude, operation)
+}
+
+func matchesAny(patterns []*regexp.Regexp, operation string) bool {
+	for _, re := range patterns {
+		if re.MatchString(operation) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply keeps the statements and results whose operation passes the filter
+func (f *operationFilter) apply(parsed *parser.ParseResult, results []*analyzer.Result) (*parser.ParseResult, []*analyzer.Result) {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return parsed, results
+	}
+
+	filteredParsed := &parser.ParseResult{Errors: parsed.Errors}
+	filteredResults := make([]*analyzer.Result, 0, len(results))
+	for i, result := range results {
+		if !f.matches(result.BaseOperation()) {
+			continue
+		}
+		if i < len(parsed.Statements) {
+			filteredParsed.Statements = append(filteredParsed.Statements, parsed.Statements[i])
+		}
+		filteredResults = append(filteredResults, result)
+	}
+	return filteredParsed, filteredResults
+}
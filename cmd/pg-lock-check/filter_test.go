@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCompileOperationGlob(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		operation string
+		want      bool
+	}{
+		{"ALTER TABLE*", "ALTER TABLE ADD COLUMN without DEFAULT", true},
+		{"ALTER TABLE*", "ALTER TABLE", true},
+		{"alter table*", "ALTER TABLE DROP COLUMN", true},
+		{"ALTER TABLE*", "CREATE TABLE", false},
+		{"CREATE INDEX*", "CREATE INDEX CONCURRENTLY", true},
+		{"CREATE INDEX*", "CREATE UNIQUE INDEX", false},
+		{"CREATE * INDEX", "CREATE UNIQUE INDEX", true},
+		{"REINDEX ????", "REINDEX SCHEMA", false},
+		{"REINDEX ?????", "REINDEX TABLE", true},
+		{"[DT]*", "DROP TABLE", true},
+		{"[!DT]*", "DROP TABLE", false},
+		{"ALTER PUBLICATION *", "ALTER PUBLICATION ADD/DROP TABLE", true},
+		{"UPDATE (x)", "UPDATE (x)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.operation, func(t *testing.T) {
+			re, err := compileOperationGlob(tt.pattern)
+			if err != nil {
+				t.Fatalf("compileOperationGlob(%q) error = %v", tt.pattern, err)
+			}
+			if got := re.MatchString(tt.operation); got != tt.want {
+				t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.operation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileOperationGlobErrors(t *testing.T) {
+	for _, pattern := range []string{"", "  ", "ALTER [TABLE", "[]", "[z-a]*"} {
+		if _, err := compileOperationGlob(pattern); err == nil {
+			t.Errorf("compileOperationGlob(%q) should fail", pattern)
+		}
+	}
+}
+
+func TestOperationFilterOutput(t *testing.T) {
+	sql := `BEGIN;
+ALTER TABLE users ADD COLUMN nickname text;
+UPDATE users SET nickname = name;
+CREATE INDEX idx_users_nickname ON users(nickname);
+ALTER TABLE users DROP COLUMN name;
+COMMIT;`
+
+	output, exitCode := runCommand(t, []string{"-o", "json", "--no-suggestion", "--include-operations", "ALTER TABLE*"}, sql)
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+
+	// Filtered results keep the index of their statement in the input
+	expected := []struct {
+		operation string
+		line      int
+		index     int
+	}{
+		{"ALTER TABLE ADD COLUMN without DEFAULT", 2, 1},
+		{"ALTER TABLE DROP COLUMN", 5, 4},
+	}
+	if len(result.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d: %s", len(expected), len(result.Results), output)
+	}
+	for i, want := range expected {
+		got := result.Results[i]
+		if got.Operation != want.operation || got.LineNumber != want.line || got.Index != want.index {
+			t.Errorf("Result %d: expected %s at line %d with index %d, got %s at line %d with index %d",
+				i, want.operation, want.line, want.index, got.Operation, got.LineNumber, got.Index)
+		}
+	}
+	if result.Summary.TotalStatements != len(expected) {
+		t.Errorf("Expected total_statements %d, got %d", len(expected), result.Summary.TotalStatements)
+	}
+
+	// Exclusions apply after inclusions
+	output, _ = runCommand(t, []string{"-o", "json", "--no-suggestion", "--include-operations", "ALTER TABLE*", "--exclude-operations", "*DROP*"}, sql)
+	result = Output{}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+	if len(result.Results) != 1 || result.Results[0].Operation != "ALTER TABLE ADD COLUMN without DEFAULT" {
+		t.Errorf("Expected only ADD COLUMN after exclusion, got %+v", result.Results)
+	}
+
+	// Invalid patterns are rejected before analysis
+	_, exitCode = runCommand(t, []string{"--include-operations", "ALTER [TABLE"}, sql)
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1 for invalid pattern, got %d", exitCode)
+	}
+}
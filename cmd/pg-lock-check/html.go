@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/nnaka2992/pg-lock-check/internal/suggester"
+)
+
+// htmlReport is the data of the -o html template
+type htmlReport struct {
+	Output
+	SeverityNames []string
+}
+
+// outputHTML formats results as a self-contained HTML report with inline CSS and script
+func outputHTML(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
+	report := htmlReport{Output: buildOutput(parsed, results, s), SeverityNames: severityNames}
+	if err := htmlTemplate.Execute(os.Stdout, report); err != nil {
+		return fmt.Errorf("writing HTML: %w", err)
+	}
+	return nil
+}
+
+// highlightSQL escapes SQL for HTML and wraps keywords, literals and comments in spans.
+// SQL the lexer rejects, such as psql meta-commands, is only escaped.
+func highlightSQL(sql string) template.HTML {
+	tokens, err := parser.Scan(sql)
+	if err != nil {
+		return template.HTML(template.HTMLEscapeString(sql))
+	}
+
+	classes := map[parser.TokenKind]string{
+		parser.TokenKeyword: "kw",
+		parser.TokenString:  "str",
+		parser.TokenNumber:  "num",
+		parser.TokenComment: "cmt",
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, token := range tokens {
+		if token.Start < pos || token.End > len(sql) {
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(sql[pos:token.Start]))
+		text := template.HTMLEscapeString(sql[token.Start:token.End])
+		if class, ok := classes[token.Kind]; ok {
+			fmt.Fprintf(&b, `<span class="%s">%s</span>`, class, text)
+		} else {
+			b.WriteString(text)
+		}
+		pos = token.End
+	}
+	b.WriteString(template.HTMLEscapeString(sql[pos:]))
+	return template.HTML(b.String())
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"highlight": highlightSQL,
+	"rank":      severityRank,
+	"lower":     strings.ToLower,
+	"location": func(result OutputResult) string {
+		file := result.File
+		if file == "" {
+			file = onelineFile("")
+		}
+		return fmt.Sprintf("%s:%d", file, result.LineNumber)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>pg-lock-check report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #d0d7de; padding: 6px 10px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+#findings th { cursor: pointer; user-select: none; }
+#findings th::after { content: " \2195"; color: #8c959f; }
+.summary { width: auto; }
+.meta { color: #656d76; }
+.severity { font-weight: bold; }
+.error { color: #8250df; }
+.critical { color: #cf222e; }
+.warning { color: #9a6700; }
+.info { color: #1a7f37; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; margin: 4px 0; }
+.kw { color: #0550ae; font-weight: bold; }
+.str { color: #0a3069; }
+.num { color: #953800; }
+.cmt { color: #6e7781; font-style: italic; }
+.tx { color: #656d76; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>pg-lock-check report</h1>
+<p class="meta">pg-lock-check {{.Meta.Version}} &middot; PostgreSQL {{.Meta.PgVersion}} grammar &middot; transaction mode {{.Meta.TransactionMode}} &middot; {{.Meta.FilesAnalyzed}} file(s) &middot; generated {{.Meta.GeneratedAt}}</p>
+
+<h2>Summary</h2>
+<table class="summary">
+<tr><th>Statements analyzed</th><td>{{.Summary.TotalStatements}}</td></tr>
+{{- range .SeverityNames}}
+<tr><th class="severity {{lower .}}">{{.}}</th><td id="count-{{lower .}}">{{index $.Summary.BySeverity .}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Findings</h2>
+<table id="findings">
+<thead>
+<tr><th>#</th><th>Location</th><th>Severity</th><th>Operation</th><th>Lock</th><th>Tables</th><th>Details</th></tr>
+</thead>
+<tbody>
+{{- range .Results}}
+<tr class="finding">
+<td data-sort="{{.Index}}">{{.Index}}</td>
+<td>{{location .}}</td>
+<td class="severity {{lower .Severity}}" data-sort="{{rank .Severity}}">{{.Severity}}</td>
+<td>{{.Operation}}{{if gt .Occurrences 1}} &times;{{.Occurrences}}{{end}}</td>
+<td>{{.LockType}}</td>
+<td>{{range $i, $t := .Tables}}{{if $i}}, {{end}}{{$t.Name}}: {{$t.LockType}}{{end}}</td>
+<td>
+<pre><code>{{highlight .SQL}}</code></pre>
+{{- range .Notes}}
+<p>Note: {{.}}</p>
+{{- end}}
+{{- with .Suggestion}}
+<details>
+<summary>Safe migration suggestion ({{len .Steps}} steps)</summary>
+{{- range .Steps}}
+<h4>{{.Description}} <span class="tx">{{if .CanRunInTransaction}}can run in a transaction{{else}}must run outside a transaction{{end}}</span></h4>
+<pre><code>{{highlight .Output}}</code></pre>
+{{- end}}
+</details>
+{{- end}}
+</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{- if .ParseErrors}}
+
+<h2>Parse Errors</h2>
+<table>
+<tr><th>Line</th><th>SQL</th><th>Error</th></tr>
+{{- range .ParseErrors}}
+<tr><td>{{.LineNumber}}</td><td><pre><code>{{.SQL}}</code></pre></td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<script>
+document.querySelectorAll("#findings th").forEach(function (th, column) {
+  var ascending = true;
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#findings tbody");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    var value = function (row) {
+      var cell = row.cells[column];
+      return cell.dataset.sort !== undefined ? Number(cell.dataset.sort) : cell.textContent.trim();
+    };
+    rows.sort(function (a, b) {
+      var x = value(a), y = value(b);
+      var order = typeof x === "number" ? x - y : x.localeCompare(y);
+      return ascending ? order : -order;
+    });
+    ascending = !ascending;
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/nnaka2992/pg-lock-check/internal/suggester"
+)
+
+// fileGroup holds the statements, results and parse errors of one input file
+type fileGroup struct {
+	file    string
+	parsed  *parser.ParseResult
+	results []*analyzer.Result
+}
+
+// outputJSONLinesByFile writes one compact Output per input file, one per line, in input order
+func outputJSONLinesByFile(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, group := range groupByFile(parsed, results) {
+		output := buildOutput(group.parsed, group.results, s)
+		output.File = onelineFile(group.file)
+		if err := encoder.Encode(output); err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+// groupByFile splits statements, results and parse errors by file, in order of first appearance
+func groupByFile(parsed *parser.ParseResult, results []*analyzer.Result) []*fileGroup {
+	var groups []*fileGroup
+	byFile := make(map[string]*fileGroup)
+	group := func(file string) *fileGroup {
+		if g, ok := byFile[file]; ok {
+			return g
+		}
+		g := &fileGroup{file: file, parsed: &parser.ParseResult{}}
+		byFile[file] = g
+		groups = append(groups, g)
+		return g
+	}
+
+	for i, stmt := range parsed.Statements {
+		g := group(stmt.File)
+		g.parsed.Statements = append(g.parsed.Statements, stmt)
+		if i < len(results) {
+			g.results = append(g.results, results[i])
+		}
+	}
+	for _, parseErr := range parsed.Errors {
+		g := group(parseErr.File)
+		g.parsed.Errors = append(g.parsed.Errors, parseErr)
+	}
+	return groups
+}
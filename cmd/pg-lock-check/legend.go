@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+)
+
+// legendSeverities lists the severities of the legend from most to least severe, as severityNames
+var legendSeverities = []analyzer.Severity{
+	analyzer.SeverityError,
+	analyzer.SeverityCritical,
+	analyzer.SeverityWarning,
+	analyzer.SeverityInfo,
+}
+
+// severityLegend maps each severity name to its meaning, for the legend object of JSON/YAML output
+func severityLegend() map[string]string {
+	legend := make(map[string]string, len(legendSeverities))
+	for _, severity := range legendSeverities {
+		legend[getSeverityName(severity)] = severity.Description()
+	}
+	return legend
+}
+
+// writeLegend writes one "SEVERITY  meaning" line per severity, as in --help and text output
+func writeLegend(w io.Writer, indent string) {
+	for _, severity := range legendSeverities {
+		fmt.Fprintf(w, "%s%-9s %s\n", indent, getSeverityName(severity), severity.Description())
+	}
+}
+
+// legendHelp is the severity legend shown in --help
+func legendHelp() string {
+	var b strings.Builder
+	b.WriteString("Severities:\n")
+	writeLegend(&b, "  ")
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+)
+
+func TestLegend(t *testing.T) {
+	want := map[string]string{
+		"ERROR":    "fails as written: PostgreSQL rejects it in the given transaction mode or context",
+		"CRITICAL": "runs, but blocks reads or writes on the table for its whole duration or destroys data; use a safer migration",
+		"WARNING":  "runs, but its lock blocks some operations or DDL while it runs; review before deploying",
+		"INFO":     "safe to run; takes no lock that blocks normal reads or writes for long",
+	}
+
+	t.Run("json", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"-o", "json", "--legend", "SELECT 1"}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		if len(result.Legend) != len(want) {
+			t.Errorf("Legend = %v, want %v", result.Legend, want)
+		}
+		for severity, meaning := range want {
+			if result.Legend[severity] != meaning {
+				t.Errorf("Legend[%s] = %q, want %q", severity, result.Legend[severity], meaning)
+			}
+		}
+	})
+
+	t.Run("json without --legend", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"-o", "json", "SELECT 1"}, "")
+		if strings.Contains(output, `"legend"`) {
+			t.Errorf("Expected no legend without --legend, got %s", output)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"--legend", "SELECT 1"}, "")
+		if !strings.Contains(output, "\nSeverities:\n  ERROR     "+want["ERROR"]+"\n") {
+			t.Errorf("Expected the legend after the summary, got %q", output)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"--help"}, "")
+		for _, severity := range severityNames {
+			if !strings.Contains(output, severity) || !strings.Contains(output, want[severity]) {
+				t.Errorf("Expected --help to explain %s, got %q", severity, output)
+			}
+		}
+	})
+
+	// The legend covers every severity the analyzer reports
+	for _, severity := range []analyzer.Severity{analyzer.SeverityInfo, analyzer.SeverityWarning, analyzer.SeverityCritical, analyzer.SeverityError} {
+		if severityLegend()[getSeverityName(severity)] == "" {
+			t.Errorf("No legend entry for %s", severity)
+		}
+	}
+}
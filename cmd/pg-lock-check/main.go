@@ -1,11 +1,16 @@
 package main
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
 	"github.com/nnaka2992/pg-lock-check/internal/metadata"
@@ -15,18 +20,86 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Supported --transaction-mode values
+const (
+	transactionModeAlways = "always"
+	transactionModeAuto   = "auto"
+	transactionModeNever  = "never"
+)
+
 // CLI configuration
 var (
 	version = "0.1.2"
 
+	// now is the clock of generated_at, replaced in tests for deterministic output
+	now = time.Now
+
 	// Flags
-	fileFlag          string
-	outputFormat      string
-	noTransactionFlag bool
-	noColorFlag       bool
-	quietFlag         bool
-	verboseFlag       bool
-	noSuggestionFlag  bool
+	fileFlag               string
+	outputFormat           string
+	noTransactionFlag      bool
+	transactionMode        string
+	noColorFlag            bool
+	quietFlag              bool
+	verboseFlag            bool
+	noSuggestionFlag       bool
+	exitCodeMapFlag        string
+	inputFormat            string
+	stdinFilename          string
+	relativeTo             string
+	exportDir              string
+	repackTool             string
+	repackFlags            string
+	summaryOnlyFlag        bool
+	legendFlag             bool
+	groupBySeverityFlag    bool
+	onelineFlag            bool
+	quietIfCleanFlag       bool
+	failOnFlag             string
+	failOnUnrecognizedFlag bool
+	includeOperations      []string
+	excludeOperations      []string
+	sinceRef               string
+	baselineFile           string
+	baselineUpdate         bool
+	partitionedTables      []string
+	publishedTables        []string
+	tableOptionsFile       string
+	schemaObjectsFile      string
+	configFile             string
+	noConfigFlag           bool
+	treatIfExistsAsWarn    bool
+	jsonLinesByFile        bool
+	dedupeFlag             bool
+	indentFlag             int
+	suggestFor             string
+	onlyCriticalSuggest    bool
+	canonicalSQLFlag       bool
+	showAllLocksFlag       bool
+	transactionSummaryFlag bool
+	profileKind            string
+	profileOut             string
+
+	// migrationDir is the directory analyzed by the dir subcommand
+	migrationDir          string
+	migrationOrder        string
+	crossFileTransactions bool
+
+	// transactionSummaries are the --transaction-summary blocks, built before results are filtered
+	transactionSummaries []OutputTransaction
+
+	// suggestionThreshold is the lowest severity whose suggestions are shown, set from --suggest-for
+	suggestionThreshold = analyzer.SeverityCritical
+
+	// filesAnalyzed is the number of input files behind the results, reported in the output meta
+	filesAnalyzed int
+
+	checkStatementTimeoutFlag bool
+	longTransactionThreshold  int
+	checkDDLDMLMixFlag        bool
+	largeInsertRows           int
+	maxLockLevelFlag          string
+	continueOnParseError      bool
 )
 
 func main() {
@@ -38,28 +111,73 @@ func run(args []string) int {
 	cmd.SetArgs(args)
 
 	var exitCode int
-	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		err := runAnalysis(cmd, args)
+	exitCodeSet := false
+	runE := func(cmd *cobra.Command, args []string) error {
+		// Project defaults fill in the flags not given on the command line
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+
+		// Validate the exit code map before doing any work
+		codes, err := parseExitCodeMap(exitCodeMapFlag)
+		if err != nil {
+			return err
+		}
+		failOn, err := parseFailOn(failOnFlag)
+		if err != nil {
+			return err
+		}
+		stopProfile, err := startProfile(profileKind, profileOut)
+		if err != nil {
+			return err
+		}
+
+		results, err := runAnalysis(cmd, args)
+		if stopErr := stopProfile(); err == nil {
+			err = stopErr
+		}
+		exitCodeSet = true
 		if err != nil {
-			exitCode = determineExitCode(err)
+			exitCode = determineExitCode(err, codes)
+			// Machine-readable output gets the error as an object instead of the plain text message
+			if outputStructuredError(err) {
+				cmd.SilenceErrors = true
+			}
+			return err
 		}
-		return err
+		exitCode = codes.forResults(results, failOn)
+		return nil
+	}
+	cmd.RunE = runE
+
+	// The dir subcommand shares every flag of the root command
+	dirCmd := buildDirCommand()
+	dirCmd.Flags().AddFlagSet(cmd.Flags())
+	dirCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		migrationDir = args[0]
+		return runE(cmd, nil)
 	}
+	cmd.AddCommand(dirCmd)
+	cmd.AddCommand(buildDescribeLockCommand())
+	cmd.CompletionOptions.DisableDefaultCmd = true
 
 	if err := cmd.Execute(); err != nil {
-		if exitCode == 0 {
+		if !exitCodeSet {
 			return 1 // Default error code for flag parsing errors
 		}
 		return exitCode
 	}
 
-	return 0
+	return exitCode
 }
 
 func buildCommand() *cobra.Command {
+	migrationDir = ""
+
 	cmd := &cobra.Command{
 		Use:          "pg-lock-check [SQL]",
 		Short:        "PostgreSQL lock analyzer",
+		Long:         "PostgreSQL lock analyzer\n\n" + legendHelp(),
 		Version:      version,
 		Args:         cobra.MaximumNArgs(1),
 		SilenceUsage: true,
@@ -67,61 +185,371 @@ func buildCommand() *cobra.Command {
 
 	// Add flags
 	cmd.Flags().StringVarP(&fileFlag, "file", "f", "", "read SQL from file")
-	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "output format: text, json, yaml")
-	cmd.Flags().BoolVar(&noTransactionFlag, "no-transaction", false, "analyze without transaction wrapper")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "output format: text, json, yaml, html (aliases: txt, yml, htm)")
+	cmd.Flags().BoolVar(&noTransactionFlag, "no-transaction", false, "analyze without transaction wrapper (same as --transaction-mode auto)")
+	cmd.Flags().StringVar(&transactionMode, "transaction-mode", transactionModeAlways, "`MODE`: always (the whole input in one transaction; when not given, start in a transaction and follow BEGIN/COMMIT), auto (start outside, follow BEGIN/COMMIT), never (every statement in autocommit)")
 	cmd.Flags().BoolVar(&noColorFlag, "no-color", false, "disable colored output")
 	cmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "quiet mode")
 	cmd.Flags().BoolVar(&verboseFlag, "verbose", false, "verbose output")
 	cmd.Flags().BoolVar(&noSuggestionFlag, "no-suggestion", false, "disable safe migration suggestions")
+	cmd.Flags().BoolVar(&groupBySeverityFlag, "group-by-severity", false, "order results from most to least severe; text output adds a header per severity")
+	cmd.Flags().BoolVar(&quietIfCleanFlag, "quiet-if-clean", false, "print nothing when no result reaches the --fail-on severity (WARNING when unset)")
+	cmd.Flags().StringVar(&failOnFlag, "fail-on", "", "exit 3 when the highest severity is at or above `SEVERITY`: error, critical, warning, info")
+	cmd.Flags().BoolVar(&failOnUnrecognizedFlag, "fail-on-unrecognized", false, "exit 4 when a statement is not specifically analyzed and only got the default INFO severity")
+	cmd.Flags().BoolVar(&onelineFlag, "oneline", false, "text output as one `file:line: SEVERITY OPERATION [table:lock,...]` line per statement, without suggestions or summary")
+	cmd.Flags().BoolVar(&jsonLinesByFile, "json-lines-by-file", false, "write one compact JSON document per input file, one per line, each with its own summary")
+	cmd.Flags().IntVar(&indentFlag, "indent", 2, "indent JSON and YAML output by `N` spaces (0 writes compact JSON)")
+	cmd.Flags().BoolVar(&canonicalSQLFlag, "canonical-sql", false, "report each statement deparsed from its AST, with consistent keyword casing and spacing; JSON/YAML keep the original as original_sql")
+	cmd.Flags().BoolVar(&showAllLocksFlag, "show-all-locks", false, "list every table lock of a statement in the order PostgreSQL acquires them, the operation's own lock first")
+	cmd.Flags().BoolVar(&dedupeFlag, "dedupe", false, "report identical findings (same operation, severity and lock in a file) once, with their occurrences and line numbers")
+	cmd.Flags().BoolVar(&transactionSummaryFlag, "transaction-summary", false, "summarize each BEGIN ... COMMIT block: the strongest lock held on each table until it ends")
+	cmd.Flags().BoolVar(&legendFlag, "legend", false, "explain each severity: after the summary in text output, as a legend object in JSON/YAML output")
+	cmd.Flags().BoolVar(&summaryOnlyFlag, "summary-only", false, "print only the summary with severity counts, without per-statement results")
+	cmd.Flags().BoolVar(&continueOnParseError, "continue-on-parse-error", false, "analyze the statements that parse and report each parse error, instead of stopping at the first one")
+	cmd.Flags().StringVar(&relativeTo, "relative-to", "", "report file paths relative to `DIR` (default: the current directory for dir); paths outside DIR are kept as given")
+	cmd.Flags().StringVar(&stdinFilename, "stdin-filename", "", "label SQL read from stdin with `NAME` as its file in the output")
+	cmd.Flags().StringVar(&sinceRef, "since", "", "analyze the .sql files under the current directory added or modified since git `REF`, instead of SQL input")
+	cmd.Flags().StringVar(&inputFormat, "input-format", "sql", "input format: sql, json (array of {\"sql\", \"file\", \"line\"})")
+	cmd.Flags().StringSliceVar(&includeOperations, "include-operations", nil, "only report operations matching these globs, e.g. 'ALTER TABLE*,CREATE INDEX*'")
+	cmd.Flags().StringSliceVar(&excludeOperations, "exclude-operations", nil, "do not report operations matching these globs")
+	cmd.Flags().BoolVar(&checkStatementTimeoutFlag, "check-statement-timeout", false, "note strong-lock statements running with statement_timeout disabled or very large")
+	cmd.Flags().StringSliceVar(&partitionedTables, "partitioned-table", nil, "treat these tables as partitioned, in addition to those created with PARTITION BY in the input")
+	cmd.Flags().StringSliceVar(&publishedTables, "published-table", nil, "treat these tables as published for logical replication, in addition to those added to a publication in the input")
+	cmd.Flags().StringVar(&tableOptionsFile, "table-options", "", "compare ALTER TABLE SET/RESET with the current storage parameters in the JSON `FILE` ({\"table\": {\"fillfactor\": \"90\"}})")
+	cmd.Flags().StringVar(&schemaObjectsFile, "schema-objects", "", "list what DROP SCHEMA ... CASCADE removes from the object counts in the JSON `FILE` ({\"schema\": {\"tables\": 12}})")
+	cmd.Flags().BoolVar(&treatIfExistsAsWarn, "treat-if-exists-as-warning", false, "report DROP TABLE IF EXISTS as WARNING instead of CRITICAL, for teardown scripts")
+	cmd.Flags().StringVar(&maxLockLevelFlag, "max-lock-level", "", "report statements taking a lock stronger than `LOCK` (e.g. ShareUpdateExclusive) as at least CRITICAL")
+	cmd.Flags().StringVar(&suggestFor, "suggest-for", "critical", "show suggestions for operations at or above `SEVERITY`: critical, warning, info (alias: all)")
+	cmd.Flags().BoolVar(&onlyCriticalSuggest, "only-critical-suggestions", false, "show suggestions only for CRITICAL operations, overriding --suggest-for")
+	cmd.Flags().StringVar(&repackTool, "repack-tool", suggester.RepackToolPgRepack, "tool used in VACUUM FULL and CLUSTER suggestions: pg_repack, pg_squeeze")
+	cmd.Flags().StringVar(&repackFlags, "repack-flags", "", "extra `OPTIONS` for pg_repack in its suggested commands, e.g. \"--no-order -j 4 --wait-timeout 60\"")
+	cmd.Flags().IntVar(&longTransactionThreshold, "check-long-transaction", 0, "note AccessExclusive statements followed by at least `N` statements before their transaction ends (0 disables)")
+	cmd.Flags().BoolVar(&checkDDLDMLMixFlag, "check-ddl-dml-mix", false, "note transactions combining an AccessExclusive schema change with a full-table UPDATE, DELETE, MERGE or INSERT ... SELECT")
+	cmd.Flags().IntVar(&largeInsertRows, "large-insert-rows", 0, "note INSERT ... VALUES statements with at least `N` rows, suggesting batches or COPY (0 disables)")
+	cmd.Flags().StringVar(&baselineFile, "baseline", "", "suppress WARNING and higher findings accepted in the baseline `FILE`")
+	cmd.Flags().BoolVar(&baselineUpdate, "baseline-update", false, "rewrite the --baseline file with the current findings, accepting them")
+	cmd.Flags().StringVar(&exportDir, "export-suggestions", "", "write the suggestions shown per --suggest-for as migration .sql files into `DIR`")
+	cmd.Flags().StringVar(&configFile, "config", "", "read defaults from the YAML `FILE` instead of the nearest .pg-lock-check.yaml")
+	cmd.Flags().BoolVar(&noConfigFlag, "no-config", false, "do not read defaults from .pg-lock-check.yaml")
+	cmd.Flags().StringVar(&exitCodeMapFlag, "exit-code-map", "", "exit codes per max severity, e.g. critical=10,error=11,warning=0 (keys: error, critical, warning, info, parse, unrecognized)")
+
+	// Profiling is for performance work on the tool itself, so it stays out of --help
+	cmd.Flags().StringVar(&profileKind, "profile", "", "write a `KIND` profile of the run to --profile-out: cpu, mem")
+	cmd.Flags().StringVar(&profileOut, "profile-out", "", "write the --profile profile to `FILE`")
+	_ = cmd.Flags().MarkHidden("profile")
+	_ = cmd.Flags().MarkHidden("profile-out")
 
 	return cmd
 }
 
-func runAnalysis(cmd *cobra.Command, args []string) error {
-	// Get SQL input
-	sql, err := getSQLInput(cmd, args)
+func runAnalysis(cmd *cobra.Command, args []string) ([]*analyzer.Result, error) {
+	format, err := normalizeOutputFormat(outputFormat)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	outputFormat = format
 
-	// Parse SQL
-	p := parser.NewParser()
-	parsed, err := p.ParseSQL(sql)
+	if jsonLinesByFile {
+		if cmd.Flags().Changed("output") && outputFormat != "json" {
+			return nil, fmt.Errorf("--json-lines-by-file writes JSON and cannot be combined with --output %s", outputFormat)
+		}
+		outputFormat = "json"
+	}
+
+	if transactionSummaryFlag && onelineFlag {
+		return nil, fmt.Errorf("--transaction-summary cannot be combined with --oneline, which reports every statement on its own line")
+	}
+	if dedupeFlag && onelineFlag {
+		return nil, fmt.Errorf("--dedupe cannot be combined with --oneline, which reports every statement on its own line")
+	}
+
+	if indentFlag < 0 {
+		return nil, fmt.Errorf("invalid --indent %d: must be 0 or greater", indentFlag)
+	}
+	// The YAML encoder only indents blocks by 2 to 9 spaces and has no compact form
+	if outputFormat == "yaml" && (indentFlag < 2 || indentFlag > 9) {
+		return nil, fmt.Errorf("invalid --indent %d for YAML output: must be between 2 and 9", indentFlag)
+	}
+
+	switch repackTool {
+	case suggester.RepackToolPgRepack, suggester.RepackToolPgSqueeze:
+	default:
+		return nil, fmt.Errorf("invalid repack tool %q: must be pg_repack or pg_squeeze", repackTool)
+	}
+	if repackFlags != "" && repackTool != suggester.RepackToolPgRepack {
+		return nil, fmt.Errorf("--repack-flags only applies to --repack-tool pg_repack")
+	}
+
+	suggestionThreshold, err = parseSuggestFor(suggestFor)
 	if err != nil {
-		return fmt.Errorf("parse error: %w", err)
+		return nil, err
+	}
+	// --only-critical-suggestions wins over a lower --suggest-for, e.g. one from the config file
+	if onlyCriticalSuggest {
+		suggestionThreshold = analyzer.SeverityCritical
+	}
+
+	if longTransactionThreshold < 0 {
+		return nil, fmt.Errorf("invalid --check-long-transaction %d: must be 0 or greater", longTransactionThreshold)
+	}
+	if largeInsertRows < 0 {
+		return nil, fmt.Errorf("invalid --large-insert-rows %d: must be 0 or greater", largeInsertRows)
+	}
+
+	filter, err := newOperationFilter(includeOperations, excludeOperations)
+	if err != nil {
+		return nil, err
+	}
+
+	if migrationDir != "" && (sinceRef != "" || fileFlag != "" || inputFormat != "sql" || stdinFilename != "") {
+		return nil, fmt.Errorf("dir analyzes the migrations of a directory and cannot be combined with --since, --file, --input-format or --stdin-filename")
+	}
+
+	if sinceRef != "" && (fileFlag != "" || len(args) > 0 || inputFormat != "sql") {
+		return nil, fmt.Errorf("--since analyzes changed .sql files and cannot be combined with SQL input or --input-format")
+	}
+
+	fromStdin := sinceRef == "" && migrationDir == "" && (fileFlag == "-" || (fileFlag == "" && len(args) == 0))
+	if stdinFilename != "" && !fromStdin {
+		return nil, fmt.Errorf("--stdin-filename only applies to SQL read from stdin")
+	}
+
+	pathBase, err = resolvePathBase(relativeTo, migrationDir != "")
+	if err != nil {
+		return nil, err
 	}
 
-	// Analyze
-	mode := analyzer.InTransaction
 	if noTransactionFlag {
+		if cmd.Flags().Changed("transaction-mode") && transactionMode != transactionModeAuto {
+			return nil, fmt.Errorf("--no-transaction conflicts with --transaction-mode %s", transactionMode)
+		}
+		transactionMode = transactionModeAuto
+	}
+
+	var mode analyzer.TransactionMode
+	switch transactionMode {
+	case transactionModeAlways:
+		mode = analyzer.InTransaction
+	case transactionModeAuto, transactionModeNever:
 		mode = analyzer.NoTransaction
+	default:
+		return nil, fmt.Errorf("invalid --transaction-mode %q: must be always, auto or never", transactionMode)
 	}
 
-	a := analyzer.New()
-	results, err := a.Analyze(parsed, mode)
+	tableOptions, err := loadTableOptions(tableOptionsFile)
+	if err != nil {
+		return nil, err
+	}
+	schemaObjects, err := loadSchemaObjects(schemaObjectsFile)
 	if err != nil {
-		return fmt.Errorf("analysis error: %w", err)
+		return nil, err
+	}
+
+	var maxLockLevel analyzer.LockType
+	if maxLockLevelFlag != "" {
+		maxLockLevel, err = analyzer.ParseLockType(maxLockLevelFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-lock-level: %w", err)
+		}
+	}
+
+	// Without --transaction-mode, the default always mode keeps following BEGIN/COMMIT/ROLLBACK
+	// as it always has; only an explicit always wraps the whole input in one transaction
+	ignoreTransactionControl := transactionMode == transactionModeNever ||
+		(transactionMode == transactionModeAlways && cmd.Flags().Changed("transaction-mode"))
+
+	a := analyzer.NewWithOptions(analyzer.Options{
+		CheckStatementTimeout:    checkStatementTimeoutFlag,
+		LongTransactionThreshold: longTransactionThreshold,
+		CheckDDLDMLMix:           checkDDLDMLMixFlag,
+		LargeInsertRows:          largeInsertRows,
+		MaxLockLevel:             maxLockLevel,
+		IgnoreTransactionControl: ignoreTransactionControl,
+		PartitionedTables:        partitionedTables,
+		PublishedTables:          publishedTables,
+		TableOptions:             tableOptions,
+		SchemaObjects:            schemaObjects,
+		TreatIfExistsAsWarning:   treatIfExistsAsWarn,
+		Verbose:                  verboseFlag,
+	})
+
+	var parsed *parser.ParseResult
+	var results []*analyzer.Result
+	if migrationDir != "" {
+		files, warnings, err := orderedMigrationFiles(migrationDir, migrationOrder)
+		if err != nil {
+			return nil, err
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		parsed, results, err = analyzeFiles(a, files, mode, crossFileTransactions)
+		if err != nil {
+			return nil, err
+		}
+		filesAnalyzed = len(files)
+	} else if sinceRef != "" {
+		files, err := changedSQLFiles(sinceRef)
+		if err != nil {
+			return nil, err
+		}
+		parsed, results, err = analyzeFiles(a, files, mode, false)
+		if err != nil {
+			return nil, err
+		}
+		filesAnalyzed = len(files)
+	} else {
+		parsed, results, err = analyzeInput(cmd, args, a, mode)
+		if err != nil {
+			return nil, err
+		}
+		filesAnalyzed = countInputFiles(parsed)
+	}
+
+	// Number statements before the baseline and filter drop any, so results keep their input position
+	for i := range parsed.Statements {
+		parsed.Statements[i].Index = i
+	}
+
+	transactionSummaries = nil
+	// Statements run on their own with --transaction-mode never, so there are no blocks to summarize
+	if transactionSummaryFlag && transactionMode != transactionModeNever {
+		transactionSummaries = buildTransactions(parsed, results, migrationDir != "" && crossFileTransactions)
 	}
 
+	// Accepted findings are dropped before suggestions, output and the exit code; the baseline is
+	// applied before filtering so --baseline-update records every finding, not just the shown ones
+	parsed, results, err = applyBaseline(baselineFile, baselineUpdate, parsed, results)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter after analysis so transaction state still sees every statement
+	parsed, results = filter.apply(parsed, results)
+
 	// Create suggester if enabled
 	var s suggester.Suggester
 	if !noSuggestionFlag {
-		s = suggester.NewSuggester()
+		s = suggester.NewSuggesterWithOptions(suggester.Options{
+			RepackTool:  repackTool,
+			RepackFlags: strings.Join(strings.Fields(repackFlags), " "),
+		})
+	}
+
+	// Export suggestions as migration files if requested
+	if exportDir != "" && s != nil {
+		if err := exportSuggestions(exportDir, parsed, results, s); err != nil {
+			return nil, err
+		}
+	}
+
+	// Hooks want no output at all when nothing reaches the threshold
+	if quietIfCleanFlag && len(parsed.Errors) == 0 && isClean(results) {
+		return results, checkUnrecognized(parsed, results)
 	}
 
 	// Output results
-	return outputResults(parsed, results, s)
+	if err := outputResults(parsed, results, s); err != nil {
+		return nil, err
+	}
+
+	// Statements that failed to parse are reported above; still exit as a parse error
+	if len(parsed.Errors) > 0 {
+		return nil, fmt.Errorf("parse error: %d of %d statements failed to parse",
+			len(parsed.Errors), len(parsed.Errors)+len(parsed.Statements))
+	}
+	return results, checkUnrecognized(parsed, results)
+}
+
+// unrecognizedError lists the statements --fail-on-unrecognized fails on, as file:line (OPERATION)
+type unrecognizedError struct {
+	statements []string
+}
+
+func (e unrecognizedError) Error() string {
+	noun := "statements"
+	if len(e.statements) == 1 {
+		noun = "statement"
+	}
+	return fmt.Sprintf("%d %s not specifically analyzed: %s", len(e.statements), noun, strings.Join(e.statements, ", "))
+}
+
+// checkUnrecognized returns an unrecognizedError with --fail-on-unrecognized when a reported
+// statement only got the default INFO severity
+func checkUnrecognized(parsed *parser.ParseResult, results []*analyzer.Result) error {
+	if !failOnUnrecognizedFlag {
+		return nil
+	}
+
+	var statements []string
+	for i, result := range results {
+		if !result.Unrecognized() {
+			continue
+		}
+		location := fmt.Sprintf("line %d", statementLine(parsed, i))
+		if file := statementFile(parsed, i); file != "" {
+			location = fmt.Sprintf("%s:%d", file, statementLine(parsed, i))
+		}
+		statements = append(statements, fmt.Sprintf("%s (%s)", location, result.Operation()))
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	return unrecognizedError{statements: statements}
+}
+
+// analyzeInput reads SQL from args, --file or stdin, then parses and analyzes it
+func analyzeInput(cmd *cobra.Command, args []string, a analyzer.Analyzer, mode analyzer.TransactionMode) (*parser.ParseResult, []*analyzer.Result, error) {
+	// Get SQL input
+	sql, err := getSQLInput(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Parse SQL
+	var parsed *parser.ParseResult
+	switch inputFormat {
+	case "sql":
+		if continueOnParseError {
+			parsed, err = parser.NewParser().ParseSQLWithRecovery(sql)
+		} else {
+			parsed, err = parser.NewParser().ParseSQL(sql)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse error: %w", err)
+		}
+	case "json":
+		parsed, err = parseJSONInput(sql)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid input format %q: must be sql or json", inputFormat)
+	}
+
+	// Label piped statements; JSON input keeps any file it attributes itself
+	if stdinFilename != "" {
+		for i := range parsed.Statements {
+			if parsed.Statements[i].File == "" {
+				parsed.Statements[i].File = stdinFilename
+			}
+		}
+	}
+	relativizeFiles(parsed)
+
+	// Analyze
+	results, err := a.Analyze(parsed, mode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("analysis error: %w", err)
+	}
+	return parsed, results, nil
 }
 
 // getSQLInput retrieves SQL from command args, file, or stdin
 func getSQLInput(cmd *cobra.Command, args []string) (string, error) {
 	// Priority: file flag > command args > stdin
 	if fileFlag != "" {
-		content, err := os.ReadFile(fileFlag)
-		if err != nil {
-			return "", fmt.Errorf("reading file: %w", err)
-		}
-		return string(content), nil
+		return readFileInput(fileFlag, os.Stdin)
 	}
 
 	if len(args) > 0 {
@@ -144,13 +572,111 @@ func getSQLInput(cmd *cobra.Command, args []string) (string, error) {
 	return "", fmt.Errorf("no SQL provided")
 }
 
+// readFileInput reads SQL from a file, treating "-" as stdin and decompressing .gz files
+func readFileInput(path string, stdin io.Reader) (string, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("reading file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return "", fmt.Errorf("reading file: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	return string(content), nil
+}
+
+// JSONInputStatement is a single pre-split statement accepted by --input-format json
+type JSONInputStatement struct {
+	SQL  string `json:"sql"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// parseJSONInput parses a JSON array of statements, keeping the caller's file/line attribution
+func parseJSONInput(input string) (*parser.ParseResult, error) {
+	var statements []JSONInputStatement
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&statements); err != nil {
+		return nil, fmt.Errorf("invalid JSON input: expected an array of {\"sql\", \"file\", \"line\"} objects: %w", err)
+	}
+
+	p := parser.NewParser()
+	result := &parser.ParseResult{Statements: make([]parser.ParsedStatement, 0, len(statements))}
+	for i, stmt := range statements {
+		if strings.TrimSpace(stmt.SQL) == "" {
+			return nil, fmt.Errorf("invalid JSON input: element %d has no sql", i)
+		}
+		if stmt.Line < 0 {
+			return nil, fmt.Errorf("invalid JSON input: element %d has negative line %d", i, stmt.Line)
+		}
+		line := stmt.Line
+		if line == 0 {
+			line = 1
+		}
+
+		parsed, err := p.ParseStatement(stmt.SQL, line)
+		if err != nil {
+			if stmt.File != "" {
+				return nil, fmt.Errorf("%s: %w", reportedPath(stmt.File), err)
+			}
+			return nil, err
+		}
+		parsed.File = stmt.File
+		result.Statements = append(result.Statements, *parsed)
+	}
+
+	return result, nil
+}
+
+// outputFormatAliases maps accepted --output values to their canonical format
+var outputFormatAliases = map[string]string{
+	"text": "text",
+	"txt":  "text",
+	"json": "json",
+	"yaml": "yaml",
+	"yml":  "yaml",
+	"html": "html",
+	"htm":  "html",
+}
+
+// normalizeOutputFormat resolves aliases such as yml, rejecting unknown formats
+// instead of silently falling back to text
+func normalizeOutputFormat(value string) (string, error) {
+	if format, ok := outputFormatAliases[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid output format %q: must be one of text, json, yaml, html (aliases: txt, yml, htm)", value)
+}
+
 // outputResults handles different output formats
 func outputResults(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
+	if jsonLinesByFile {
+		return outputJSONLinesByFile(parsed, results, s)
+	}
+
 	switch outputFormat {
 	case "json":
 		return outputJSON(parsed, results, s)
 	case "yaml":
 		return outputYAML(parsed, results, s)
+	case "html":
+		return outputHTML(parsed, results, s)
 	default:
 		return outputText(parsed, results, s)
 	}
@@ -158,47 +684,217 @@ func outputResults(parsed *parser.ParseResult, results []*analyzer.Result, s sug
 
 // outputText formats results as human-readable text
 func outputText(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
-	for i, result := range results {
-		// Get statement SQL
-		stmt := ""
-		if i < len(parsed.Statements) {
-			stmt = parsed.Statements[i].SQL
-		}
+	if summaryOnlyFlag {
+		outputTextSummary(results)
+		return nil
+	}
 
-		// Print severity and statement
-		severity := getSeverityName(result.Severity)
-		fmt.Printf("[%s] %s\n", severity, stmt)
+	if onelineFlag {
+		outputOneline(parsed, results)
+		return nil
+	}
 
-		// Show suggestions for CRITICAL operations
-		if shouldShowSuggestion(result, s) {
-			showSuggestion(parsed, i, result, s)
+	groups := groupFindings(parsed, results)
+	if groupBySeverityFlag {
+		counts := countSeverities(results)
+		for _, severity := range severityNames {
+			if counts[severity] == 0 {
+				continue
+			}
+			fmt.Printf("== %s (%d) ==\n", severity, counts[severity])
+			for _, group := range groups {
+				if result := results[group.index]; getSeverityName(result.Severity) == severity {
+					outputTextResult(parsed, group, result, s)
+				}
+			}
+		}
+	} else {
+		for _, group := range groups {
+			outputTextResult(parsed, group, results[group.index], s)
 		}
 	}
 
+	for _, parseErr := range parsed.Errors {
+		fmt.Printf("[PARSE ERROR] line %d: %s\n", parseErr.LineNumber, parseErr.SQL)
+		fmt.Printf("  Error: %v\n", parseErr.Err)
+	}
+
 	// Summary
-	fmt.Printf("\nSummary: %d statements analyzed\n", len(results))
+	if len(parsed.Errors) > 0 {
+		fmt.Printf("\nSummary: %d statements analyzed, %d failed to parse\n", len(results), len(parsed.Errors))
+	} else {
+		fmt.Printf("\nSummary: %d statements analyzed\n", len(results))
+	}
+	outputTextTransactions()
+	outputTextLegend()
 	return nil
 }
 
-// shouldShowSuggestion checks if we should display a suggestion
+// outputTextResult prints a single result with its occurrences, notes and suggestion
+func outputTextResult(parsed *parser.ParseResult, group *findingGroup, result *analyzer.Result, s suggester.Suggester) {
+	i := group.index
+
+	// Get statement SQL, prefixed with its source when attributed
+	stmt := ""
+	if i < len(parsed.Statements) {
+		stmt, _ = reportedSQL(parsed.Statements[i])
+		if file := parsed.Statements[i].File; file != "" {
+			stmt = fmt.Sprintf("%s:%d: %s", file, parsed.Statements[i].LineNumber, stmt)
+		}
+	}
+
+	// Print severity and statement
+	severity := getSeverityName(result.Severity)
+	fmt.Printf("[%s] %s\n", severity, stmt)
+	outputTextOccurrences(group.lines)
+
+	if tables := buildTableLocks(result.AllLocks()); showAllLocksFlag && len(tables) > 0 {
+		locks := make([]string, len(tables))
+		for i, table := range tables {
+			locks[i] = table.Name + ":" + table.LockType
+		}
+		fmt.Printf("  Locks: %s\n", strings.Join(locks, " -> "))
+	}
+
+	for _, note := range result.Notes() {
+		fmt.Printf("  Note: %s\n", note)
+	}
+
+	// Show suggestions for operations at or above --suggest-for
+	if shouldShowSuggestion(result, s) {
+		showSuggestion(parsed, i, result, s)
+	}
+}
+
+// outputOneline prints one grep-friendly, editor-clickable line per result and parse error
+func outputOneline(parsed *parser.ParseResult, results []*analyzer.Result) {
+	for i, result := range results {
+		file, line := onelineLocation(parsed, i)
+		fmt.Printf("%s:%d: %s %s", file, line, getSeverityName(result.Severity), result.Operation())
+
+		tableLocks := result.TableLocks()
+		if showAllLocksFlag {
+			tableLocks = result.AllLocks()
+		}
+		if tables := buildTableLocks(tableLocks); len(tables) > 0 {
+			locks := make([]string, 0, len(tables))
+			for _, table := range tables {
+				locks = append(locks, table.Name+":"+table.LockType)
+			}
+			// Table locks come from a map; sort them so the line is stable unless they are in acquisition order
+			if !showAllLocksFlag {
+				sort.Strings(locks)
+			}
+			fmt.Printf(" [%s]", strings.Join(locks, ","))
+		}
+		fmt.Println()
+	}
+
+	for _, parseErr := range parsed.Errors {
+		// Errors from files are prefixed with the file, which the line already starts with
+		message := parseErr.Err.Error()
+		if parseErr.File != "" {
+			message = strings.TrimPrefix(message, parseErr.File+": ")
+		}
+		fmt.Printf("%s:%d: PARSE ERROR %s\n", onelineFile(parseErr.File), parseErr.LineNumber, message)
+	}
+}
+
+// onelineLocation returns the file and line of a statement for --oneline output
+func onelineLocation(parsed *parser.ParseResult, index int) (string, int) {
+	if index >= len(parsed.Statements) {
+		return onelineFile(""), 1
+	}
+	return onelineFile(parsed.Statements[index].File), parsed.Statements[index].LineNumber
+}
+
+// onelineFile falls back to the -f path, or <input> when SQL came from an argument or stdin
+func onelineFile(file string) string {
+	if file != "" {
+		return file
+	}
+	if fileFlag != "" && fileFlag != "-" {
+		return fileFlag
+	}
+	if stdinFilename != "" {
+		return stdinFilename
+	}
+	return "<input>"
+}
+
+// outputTextSummary prints the summary line followed by a by-severity breakdown
+func outputTextSummary(results []*analyzer.Result) {
+	counts := countSeverities(results)
+	fmt.Printf("Summary: %d statements analyzed\n", len(results))
+	for _, severity := range severityNames {
+		fmt.Printf("  %s: %d\n", severity, counts[severity])
+	}
+	outputTextTransactions()
+	outputTextLegend()
+}
+
+// outputTextLegend prints the severity legend after the summary for --legend
+func outputTextLegend() {
+	if !legendFlag {
+		return
+	}
+	fmt.Println("\nSeverities:")
+	writeLegend(os.Stdout, "  ")
+}
+
+// severityNames lists severity names from most to least severe
+var severityNames = []string{"ERROR", "CRITICAL", "WARNING", "INFO"}
+
+// severityRank returns the position of a severity name in severityNames, most severe first
+func severityRank(severity string) int {
+	for i, name := range severityNames {
+		if name == severity {
+			return i
+		}
+	}
+	return len(severityNames)
+}
+
+// countSeverities counts results per severity name
+func countSeverities(results []*analyzer.Result) map[string]int {
+	counts := make(map[string]int, len(severityNames))
+	for _, severity := range severityNames {
+		counts[severity] = 0
+	}
+	for _, result := range results {
+		counts[getSeverityName(result.Severity)]++
+	}
+	return counts
+}
+
+// shouldShowSuggestion checks if we should display a suggestion; ERROR statements fail as written,
+// so they never show one
 func shouldShowSuggestion(result *analyzer.Result, s suggester.Suggester) bool {
-	return result.Severity == analyzer.SeverityCritical &&
+	return result.Severity != analyzer.SeverityError &&
+		result.Severity >= suggestionThreshold &&
 		s != nil &&
-		s.HasSuggestion(result.Operation())
+		s.HasSuggestion(result.BaseOperation())
 }
 
-// showSuggestion displays a suggestion for a critical operation
-func showSuggestion(parsed *parser.ParseResult, index int, result *analyzer.Result, s suggester.Suggester) {
-	if index >= len(parsed.Statements) || len(parsed.Statements[index].AST.Stmts) == 0 {
-		return
+// getSuggestion extracts statement metadata and renders the suggestion for a result; PREPARE and
+// EXECUTE are extracted from the statement they prepare or run
+func getSuggestion(parsed *parser.ParseResult, index int, result *analyzer.Result, s suggester.Suggester) (*suggester.Suggestion, error) {
+	if index >= len(parsed.Statements) || parsed.Statements[index].AST == nil || len(parsed.Statements[index].AST.Stmts) == 0 {
+		return nil, suggester.ErrNoSuggestion
 	}
 
-	// Extract metadata
+	stmt := result.Statement()
+	if stmt == nil {
+		stmt = parsed.Statements[index].AST.Stmts[0].Stmt
+	}
 	extractor := metadata.NewExtractor()
-	metadata := extractor.Extract(parsed.Statements[index].AST.Stmts[0].Stmt, result.Operation())
+	metadata := extractor.Extract(stmt, result.BaseOperation())
+	return s.GetSuggestion(result.BaseOperation(), suggester.OperationMetadata(metadata))
+}
 
-	// Get and display suggestion
-	suggestion, err := s.GetSuggestion(result.Operation(), suggester.OperationMetadata(metadata))
+// showSuggestion displays a suggestion for a critical operation
+func showSuggestion(parsed *parser.ParseResult, index int, result *analyzer.Result, s suggester.Suggester) {
+	suggestion, err := getSuggestion(parsed, index, result, s)
 	if err != nil {
 		return
 	}
@@ -229,7 +925,9 @@ func showSuggestion(parsed *parser.ParseResult, index int, result *analyzer.Resu
 func outputJSON(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
 	output := buildOutput(parsed, results, s)
 	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
+	if indentFlag > 0 {
+		encoder.SetIndent("", strings.Repeat(" ", indentFlag))
+	}
 	if err := encoder.Encode(output); err != nil {
 		return fmt.Errorf("encoding JSON: %w", err)
 	}
@@ -240,7 +938,7 @@ func outputJSON(parsed *parser.ParseResult, results []*analyzer.Result, s sugges
 func outputYAML(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) error {
 	output := buildOutput(parsed, results, s)
 	encoder := yaml.NewEncoder(os.Stdout)
-	encoder.SetIndent(2)
+	encoder.SetIndent(indentFlag)
 	if err := encoder.Encode(output); err != nil {
 		return fmt.Errorf("encoding YAML: %w", err)
 	}
@@ -249,40 +947,129 @@ func outputYAML(parsed *parser.ParseResult, results []*analyzer.Result, s sugges
 
 // buildOutput creates the structured output for JSON/YAML formats
 func buildOutput(parsed *parser.ParseResult, results []*analyzer.Result, s suggester.Suggester) Output {
-	// Initialize severity counts
-	severityCounts := map[string]int{
-		"ERROR":    0,
-		"CRITICAL": 0,
-		"WARNING":  0,
-		"INFO":     0,
+	var legend map[string]string
+	if legendFlag {
+		legend = severityLegend()
 	}
 
-	// Build results
-	outputResults := make([]OutputResult, len(results))
-	for i, result := range results {
-		outputResults[i] = buildOutputResult(i, result, parsed, s, severityCounts)
+	if summaryOnlyFlag {
+		return Output{
+			Meta:         buildMeta(),
+			Legend:       legend,
+			Transactions: transactionSummaries,
+			Summary: OutputSummary{
+				TotalStatements: len(results),
+				BySeverity:      countSeverities(results),
+			},
+			Results:     []OutputResult{},
+			ParseErrors: buildParseErrors(parsed),
+		}
+	}
+
+	// Build results; each --dedupe finding is reported once with its occurrences
+	outputResults := make([]OutputResult, 0, len(results))
+	for _, group := range groupFindings(parsed, results) {
+		outputResult := buildOutputResult(group.index, results[group.index], parsed, s)
+		if dedupeFlag {
+			outputResult.Occurrences = len(group.lines)
+			outputResult.LineNumbers = group.lines
+		}
+		outputResults = append(outputResults, outputResult)
+	}
+
+	// Most severe first; each result keeps its original index
+	if groupBySeverityFlag {
+		sort.SliceStable(outputResults, func(i, j int) bool {
+			return severityRank(outputResults[i].Severity) < severityRank(outputResults[j].Severity)
+		})
 	}
 
 	return Output{
+		Meta:         buildMeta(),
+		Legend:       legend,
+		Transactions: transactionSummaries,
 		Summary: OutputSummary{
 			TotalStatements: len(results),
-			BySeverity:      severityCounts,
+			BySeverity:      countSeverities(results),
 		},
-		Results: outputResults,
+		Results:     outputResults,
+		ParseErrors: buildParseErrors(parsed),
+	}
+}
+
+// buildMeta describes the current run for JSON/YAML output
+func buildMeta() OutputMeta {
+	return OutputMeta{
+		Version:         version,
+		PgVersion:       parser.GrammarVersion(),
+		TransactionMode: transactionMode,
+		FilesAnalyzed:   filesAnalyzed,
+		GeneratedAt:     now().UTC().Format(time.RFC3339),
+	}
+}
+
+// countInputFiles counts the distinct files of the parsed statements; unlabeled input counts as one file
+func countInputFiles(parsed *parser.ParseResult) int {
+	files := make(map[string]bool)
+	for _, stmt := range parsed.Statements {
+		files[stmt.File] = true
 	}
+	if len(files) == 0 {
+		return 1
+	}
+	return len(files)
+}
+
+// reportedSQL returns the statement as reported, deparsed with --canonical-sql, and the original
+// text when it was replaced; statements that cannot be deparsed keep their original text
+func reportedSQL(stmt parser.ParsedStatement) (string, string) {
+	if !canonicalSQLFlag {
+		return stmt.SQL, ""
+	}
+	canonical, err := stmt.CanonicalSQL()
+	if err != nil {
+		return stmt.SQL, ""
+	}
+	return canonical, stmt.SQL
+}
+
+// buildParseErrors converts statements that failed to parse into output entries
+func buildParseErrors(parsed *parser.ParseResult) []OutputParseError {
+	if len(parsed.Errors) == 0 {
+		return nil
+	}
+
+	parseErrors := make([]OutputParseError, len(parsed.Errors))
+	for i, parseErr := range parsed.Errors {
+		parseErrors[i] = OutputParseError{
+			Statement:  parseErr.Statement,
+			SQL:        parseErr.SQL,
+			LineNumber: parseErr.LineNumber,
+			Message:    parseErr.Err.Error(),
+		}
+	}
+	return parseErrors
 }
 
 // buildOutputResult creates a single output result
-func buildOutputResult(index int, result *analyzer.Result, parsed *parser.ParseResult, s suggester.Suggester, severityCounts map[string]int) OutputResult {
+func buildOutputResult(index int, result *analyzer.Result, parsed *parser.ParseResult, s suggester.Suggester) OutputResult {
 	severityName := getSeverityName(result.Severity)
-	severityCounts[severityName]++
 
 	// Get SQL and line number
 	sql := ""
+	originalSQL := ""
+	normalizedSQL := ""
+	file := ""
 	lineNumber := 1
 	if index < len(parsed.Statements) {
-		sql = parsed.Statements[index].SQL
+		sql, originalSQL = reportedSQL(parsed.Statements[index])
+		file = parsed.Statements[index].File
 		lineNumber = parsed.Statements[index].LineNumber
+
+		// Omit the normalized form when pg_query cannot normalize the statement
+		if normalized, err := parsed.Statements[index].NormalizedSQL(); err == nil {
+			normalizedSQL = normalized
+		}
 	}
 
 	// Build table locks
@@ -295,20 +1082,28 @@ func buildOutputResult(index int, result *analyzer.Result, parsed *parser.ParseR
 	}
 
 	outputResult := OutputResult{
-		Index:      index,
-		SQL:        sql,
-		LineNumber: lineNumber,
-		Severity:   severityName,
-		Operation:  result.Operation(),
-		LockType:   lockType,
-		Tables:     tables,
+		Index:           statementIndex(parsed, index),
+		SQL:             sql,
+		OriginalSQL:     originalSQL,
+		NormalizedSQL:   normalizedSQL,
+		File:            file,
+		LineNumber:      lineNumber,
+		Severity:        severityName,
+		Operation:       result.Operation(),
+		LockType:        lockType,
+		Scope:           string(result.Scope()),
+		ErrorReason:     string(result.ErrorReason()),
+		RecommendedMode: string(recommendedMode(result)),
+		Tables:          tables,
+		Notes:           result.Notes(),
+	}
+	if showAllLocksFlag {
+		outputResult.AllLocks = buildTableLocks(result.AllLocks())
 	}
 
 	// Add suggestion if applicable
-	if shouldShowSuggestion(result, s) && index < len(parsed.Statements) && len(parsed.Statements[index].AST.Stmts) > 0 {
-		extractor := metadata.NewExtractor()
-		metadata := extractor.Extract(parsed.Statements[index].AST.Stmts[0].Stmt, result.Operation())
-		if suggestion, err := s.GetSuggestion(result.Operation(), suggester.OperationMetadata(metadata)); err == nil {
+	if shouldShowSuggestion(result, s) {
+		if suggestion, err := getSuggestion(parsed, index, result, s); err == nil {
 			outputResult.Suggestion = convertSuggestion(suggestion)
 		}
 	}
@@ -316,6 +1111,17 @@ func buildOutputResult(index int, result *analyzer.Result, parsed *parser.ParseR
 	return outputResult
 }
 
+// recommendedMode refines the operation's recommended mode with its suggestion definition: a step
+// that cannot run in a transaction means the statements should run without a wrapper. ERROR results
+// fail as written and keep the mode they need. Display flags such as --no-suggestion and
+// --suggest-for do not change it.
+func recommendedMode(result *analyzer.Result) analyzer.RecommendedMode {
+	if result.Severity != analyzer.SeverityError && suggester.RunsOutsideTransaction(result.BaseOperation()) {
+		return analyzer.RecommendedModeNoTransaction
+	}
+	return result.RecommendedMode()
+}
+
 // buildTableLocks parses table lock strings into structured format
 func buildTableLocks(tableLocks []string) []TableLock {
 	tables := []TableLock{}
@@ -334,13 +1140,133 @@ func buildTableLocks(tableLocks []string) []TableLock {
 
 // Helper functions
 
-func determineExitCode(err error) int {
+func determineExitCode(err error, codes exitCodeMap) int {
+	if errors.As(err, &unrecognizedError{}) {
+		if code, ok := codes["unrecognized"]; ok {
+			return code
+		}
+		return 4
+	}
 	if isParseError(err) {
+		if code, ok := codes["parse"]; ok {
+			return code
+		}
 		return 2
 	}
 	return 1
 }
 
+// exitCodeMap maps a max severity name (lowercase), "parse" or "unrecognized" to a process exit code
+type exitCodeMap map[string]int
+
+// parseExitCodeMap parses the --exit-code-map value, e.g. "critical=10,error=11,warning=0"
+func parseExitCodeMap(value string) (exitCodeMap, error) {
+	codes := exitCodeMap{}
+	if strings.TrimSpace(value) == "" {
+		return codes, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		key, rawCode, found := strings.Cut(entry, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --exit-code-map entry %q: expected key=code", entry)
+		}
+
+		switch key {
+		case "error", "critical", "warning", "info", "parse", "unrecognized":
+		default:
+			return nil, fmt.Errorf("invalid --exit-code-map key %q: must be one of error, critical, warning, info, parse, unrecognized", key)
+		}
+
+		code, err := strconv.Atoi(strings.TrimSpace(rawCode))
+		if err != nil || code < 0 || code > 255 {
+			return nil, fmt.Errorf("invalid --exit-code-map code %q for %s: must be an integer between 0 and 255", rawCode, key)
+		}
+		codes[key] = code
+	}
+
+	return codes, nil
+}
+
+// forResults returns the configured exit code for the highest severity found.
+// Unmapped severities exit 3 when they reach the --fail-on threshold, and 0 otherwise.
+func (c exitCodeMap) forResults(results []*analyzer.Result, failOn *analyzer.Severity) int {
+	highest, ok := maxSeverity(results)
+	if !ok {
+		return 0
+	}
+
+	if code, mapped := c[strings.ToLower(getSeverityName(highest))]; mapped {
+		return code
+	}
+	if failOn != nil && highest >= *failOn {
+		return 3
+	}
+	return 0
+}
+
+// maxSeverity returns the highest severity among results, or false when there are none
+func maxSeverity(results []*analyzer.Result) (analyzer.Severity, bool) {
+	if len(results) == 0 {
+		return analyzer.SeverityInfo, false
+	}
+
+	highest := results[0].Severity
+	for _, result := range results[1:] {
+		if result.Severity > highest {
+			highest = result.Severity
+		}
+	}
+	return highest, true
+}
+
+// parseFailOn parses the --fail-on severity, returning nil when it is not set
+func parseFailOn(value string) (*analyzer.Severity, error) {
+	var severity analyzer.Severity
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return nil, nil
+	case "error":
+		severity = analyzer.SeverityError
+	case "critical":
+		severity = analyzer.SeverityCritical
+	case "warning":
+		severity = analyzer.SeverityWarning
+	case "info":
+		severity = analyzer.SeverityInfo
+	default:
+		return nil, fmt.Errorf("invalid --fail-on %q: must be one of error, critical, warning, info", value)
+	}
+	return &severity, nil
+}
+
+// parseSuggestFor parses the --suggest-for severity
+func parseSuggestFor(value string) (analyzer.Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "critical":
+		return analyzer.SeverityCritical, nil
+	case "warning":
+		return analyzer.SeverityWarning, nil
+	case "info", "all":
+		return analyzer.SeverityInfo, nil
+	default:
+		return analyzer.SeverityCritical, fmt.Errorf("invalid --suggest-for %q: must be one of critical, warning, info, all", value)
+	}
+}
+
+// isClean reports whether every result is below the --quiet-if-clean threshold,
+// which is the --fail-on severity when set and WARNING otherwise
+func isClean(results []*analyzer.Result) bool {
+	threshold := analyzer.SeverityWarning
+	if failOn, err := parseFailOn(failOnFlag); err == nil && failOn != nil {
+		threshold = *failOn
+	}
+
+	highest, ok := maxSeverity(results)
+	return !ok || highest < threshold
+}
+
 func isParseError(err error) bool {
 	return err != nil && (strings.Contains(err.Error(), "parse error") ||
 		strings.Contains(err.Error(), "syntax error"))
@@ -367,11 +1293,14 @@ func convertSuggestion(suggestion *suggester.Suggestion) *OutputSuggestion {
 	}
 
 	outputSuggestion := &OutputSuggestion{
-		Steps: make([]OutputStep, 0, len(suggestion.Steps)),
+		IsPartial: suggestion.IsPartial,
+		Steps:     make([]OutputStep, 0, len(suggestion.Steps)),
 	}
 
 	for _, step := range suggestion.Steps {
 		outputStep := OutputStep{
+			ID:                  step.ID,
+			DependsOn:           step.DependsOn,
 			Description:         step.Description,
 			CanRunInTransaction: step.CanRunInTransaction,
 		}
@@ -405,8 +1334,29 @@ func printIndented(header, content string) {
 // Output structures for JSON/YAML
 
 type Output struct {
-	Summary OutputSummary  `json:"summary" yaml:"summary"`
-	Results []OutputResult `json:"results" yaml:"results"`
+	Meta         OutputMeta          `json:"meta" yaml:"meta"`
+	Legend       map[string]string   `json:"legend,omitempty" yaml:"legend,omitempty"`
+	Transactions []OutputTransaction `json:"transactions,omitempty" yaml:"transactions,omitempty"`
+	File         string              `json:"file,omitempty" yaml:"file,omitempty"`
+	Summary      OutputSummary       `json:"summary" yaml:"summary"`
+	Results      []OutputResult      `json:"results" yaml:"results"`
+	ParseErrors  []OutputParseError  `json:"parse_errors,omitempty" yaml:"parse_errors,omitempty"`
+}
+
+type OutputParseError struct {
+	Statement  int    `json:"statement" yaml:"statement"`
+	SQL        string `json:"sql" yaml:"sql"`
+	LineNumber int    `json:"line_number" yaml:"line_number"`
+	Message    string `json:"message" yaml:"message"`
+}
+
+// OutputMeta describes the run so archived reports are self-describing
+type OutputMeta struct {
+	Version         string `json:"version" yaml:"version"`
+	PgVersion       string `json:"pg_version" yaml:"pg_version"`
+	TransactionMode string `json:"transaction_mode" yaml:"transaction_mode"`
+	FilesAnalyzed   int    `json:"files_analyzed" yaml:"files_analyzed"`
+	GeneratedAt     string `json:"generated_at" yaml:"generated_at"`
 }
 
 type OutputSummary struct {
@@ -415,24 +1365,38 @@ type OutputSummary struct {
 }
 
 type OutputResult struct {
-	Index      int               `json:"index" yaml:"index"`
-	SQL        string            `json:"sql" yaml:"sql"`
-	LineNumber int               `json:"line_number" yaml:"line_number"`
-	Severity   string            `json:"severity" yaml:"severity"`
-	Operation  string            `json:"operation" yaml:"operation"`
-	LockType   string            `json:"lock_type" yaml:"lock_type"`
-	Tables     []TableLock       `json:"tables" yaml:"tables"`
-	Suggestion *OutputSuggestion `json:"suggestion,omitempty" yaml:"suggestion,omitempty"`
+	Index           int               `json:"index" yaml:"index"`
+	SQL             string            `json:"sql" yaml:"sql"`
+	OriginalSQL     string            `json:"original_sql,omitempty" yaml:"original_sql,omitempty"`
+	NormalizedSQL   string            `json:"normalized_sql,omitempty" yaml:"normalized_sql,omitempty"`
+	File            string            `json:"file,omitempty" yaml:"file,omitempty"`
+	LineNumber      int               `json:"line_number" yaml:"line_number"`
+	Severity        string            `json:"severity" yaml:"severity"`
+	Operation       string            `json:"operation" yaml:"operation"`
+	LockType        string            `json:"lock_type" yaml:"lock_type"`
+	Scope           string            `json:"scope" yaml:"scope"`
+	ErrorReason     string            `json:"error_reason,omitempty" yaml:"error_reason,omitempty"`
+	RecommendedMode string            `json:"recommended_mode" yaml:"recommended_mode"`
+	Tables          []TableLock       `json:"tables" yaml:"tables"`
+	AllLocks        []TableLock       `json:"all_locks,omitempty" yaml:"all_locks,omitempty"`
+	Notes           []string          `json:"notes,omitempty" yaml:"notes,omitempty"`
+	Suggestion      *OutputSuggestion `json:"suggestion,omitempty" yaml:"suggestion,omitempty"`
+	Occurrences     int               `json:"occurrences,omitempty" yaml:"occurrences,omitempty"`
+	LineNumbers     []int             `json:"line_numbers,omitempty" yaml:"line_numbers,omitempty"`
 }
 
 type OutputSuggestion struct {
-	Steps []OutputStep `json:"steps" yaml:"steps"`
+	// IsPartial marks an approximate or external alternative rather than a drop-in replacement
+	IsPartial bool         `json:"is_partial" yaml:"is_partial"`
+	Steps     []OutputStep `json:"steps" yaml:"steps"`
 }
 
 type OutputStep struct {
-	Description         string `json:"description" yaml:"description"`
-	CanRunInTransaction bool   `json:"can_run_in_transaction" yaml:"can_run_in_transaction"`
-	Output              string `json:"output" yaml:"output"`
+	ID                  string   `json:"id" yaml:"id"`
+	DependsOn           []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	Description         string   `json:"description" yaml:"description"`
+	CanRunInTransaction bool     `json:"can_run_in_transaction" yaml:"can_run_in_transaction"`
+	Output              string   `json:"output" yaml:"output"`
 }
 
 type TableLock struct {
@@ -2,9 +2,14 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidFlags(t *testing.T) {
@@ -63,6 +68,11 @@ func TestValidFlags(t *testing.T) {
 			args:     []string{"-o", "yaml", "SELECT 1"},
 			wantExit: 0,
 		},
+		{
+			name:     "output flag yml alias",
+			args:     []string{"-o", "yml", "SELECT 1"},
+			wantExit: 0,
+		},
 		// Transaction mode combinations
 		{
 			name:     "no-transaction flag",
@@ -155,6 +165,12 @@ func TestValidFlags(t *testing.T) {
 			wantExit:  1,
 			wantError: "no SQL provided",
 		},
+		{
+			name:      "invalid output format",
+			args:      []string{"-o", "xml", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid output format "xml": must be one of text, json, yaml`,
+		},
 		{
 			name:      "invalid short flag",
 			args:      []string{"-x", "SELECT 1"},
@@ -179,7 +195,7 @@ func TestValidFlags(t *testing.T) {
 			os.Stderr = wErr
 
 			// Run the function
-			exitCode := run(tt.args)
+			exitCode := run(withoutConfig(tt.args))
 
 			// Restore
 			_ = w.Close()
@@ -219,6 +235,24 @@ func TestRun(t *testing.T) {
 			wantExit:  1,
 			wantError: `Error: no SQL provided`,
 		},
+		{
+			name:      "invalid --transaction-mode",
+			args:      []string{"--transaction-mode", "sometimes", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid --transaction-mode "sometimes": must be always, auto or never`,
+		},
+		{
+			name:      "--no-transaction conflicts with another --transaction-mode",
+			args:      []string{"--no-transaction", "--transaction-mode", "never", "SELECT 1"},
+			wantExit:  1,
+			wantError: `--no-transaction conflicts with --transaction-mode never`,
+		},
+		{
+			name:       "--treat-if-exists-as-warning downgrades guarded DROP TABLE",
+			args:       []string{"--oneline", "--treat-if-exists-as-warning", "DROP TABLE IF EXISTS users; DROP TABLE logs"},
+			wantExit:   0,
+			wantOutput: "<input>:1: WARNING DROP TABLE (if exists) [users:AccessExclusive]\n<input>:1: CRITICAL DROP TABLE [logs:AccessExclusive]\n",
+		},
 		{
 			name:     "simple SELECT with --no-suggestion",
 			args:     []string{"--no-suggestion", "SELECT * FROM users"},
@@ -267,6 +301,18 @@ Suggestion for safe migration:
       3. Handle failures with resume capability
 
 Summary: 1 statements analyzed`,
+		},
+		{
+			name:     "EXECUTE of a prepared UPDATE without WHERE shows its suggestion",
+			args:     []string{"PREPARE p AS UPDATE users SET active = false; EXECUTE p;"},
+			wantExit: 0,
+			wantOutput: `[CRITICAL] EXECUTE p
+  Note: runs the statement prepared as p at line 1
+Suggestion for safe migration:
+  Step: Export target row IDs to file
+    Can run in transaction: Yes
+    SQL:
+      \COPY (SELECT id FROM users ORDER BY id) TO '/path/to/target_ids.csv' CSV`,
 		},
 		{
 			name:      "invalid SQL",
@@ -291,6 +337,295 @@ Summary: 1 statements analyzed`,
 
 Summary: 1 statements analyzed`,
 		},
+		{
+			name:     "expression index shows note",
+			args:     []string{"--no-suggestion", "CREATE INDEX idx ON users(lower(email))"},
+			wantExit: 0,
+			wantOutput: `[CRITICAL] CREATE INDEX idx ON users(lower(email))
+  Note: index expression calls lower(); it must be IMMUTABLE and is evaluated for every row during the build
+`,
+		},
+		{
+			name:       "partial index keeps suggestion",
+			args:       []string{"CREATE INDEX idx ON users(email) WHERE active"},
+			wantExit:   0,
+			wantOutput: "CREATE INDEX CONCURRENTLY idx ON users (email);",
+		},
+		{
+			name:     "json input text output shows attribution",
+			args:     []string{"--input-format", "json", "--no-suggestion"},
+			stdin:    `[{"sql": "TRUNCATE users", "file": "001.sql", "line": 4}]`,
+			wantExit: 0,
+			wantOutput: `[CRITICAL] 001.sql:4: TRUNCATE users
+`,
+		},
+		{
+			name:      "json input must be an array",
+			args:      []string{"--input-format", "json"},
+			stdin:     `{"sql": "SELECT 1"}`,
+			wantExit:  1,
+			wantError: "invalid JSON input",
+		},
+		{
+			name:      "json input rejects unknown fields",
+			args:      []string{"--input-format", "json"},
+			stdin:     `[{"query": "SELECT 1"}]`,
+			wantExit:  1,
+			wantError: `unknown field "query"`,
+		},
+		{
+			name:      "json input requires sql",
+			args:      []string{"--input-format", "json"},
+			stdin:     `[{"file": "a.sql", "line": 1}]`,
+			wantExit:  1,
+			wantError: "element 0 has no sql",
+		},
+		{
+			name:      "json input rejects multiple statements per element",
+			args:      []string{"--input-format", "json"},
+			stdin:     `[{"sql": "SELECT 1; SELECT 2"}]`,
+			wantExit:  1,
+			wantError: "expected exactly one statement",
+		},
+		{
+			name:      "json input parse error",
+			args:      []string{"--input-format", "json"},
+			stdin:     `[{"sql": "SELEC 1", "file": "bad.sql", "line": 9}]`,
+			wantExit:  2,
+			wantError: "bad.sql: parse error at line 9",
+		},
+		{
+			name:     "repack-tool pg_squeeze",
+			args:     []string{"--no-transaction", "--repack-tool", "pg_squeeze", "VACUUM FULL logs"},
+			wantExit: 0,
+			wantOutput: `[CRITICAL] VACUUM FULL logs
+Suggestion for safe migration:
+  Step: Use ` + "`pg_squeeze`" + ` extension instead
+    Can run in transaction: No
+    SQL:
+      SELECT squeeze.squeeze_table('public', 'logs');
+`,
+		},
+		{
+			name:      "invalid repack tool",
+			args:      []string{"--repack-tool", "pg_reorg", "VACUUM FULL logs"},
+			wantExit:  1,
+			wantError: `invalid repack tool "pg_reorg"`,
+		},
+		{
+			name:     "repack-flags",
+			args:     []string{"--no-transaction", "--repack-flags", "--no-order  -j 4 --wait-timeout 60", "CLUSTER users USING users_pkey"},
+			wantExit: 0,
+			wantOutput: `[CRITICAL] CLUSTER users USING users_pkey
+Suggestion for safe migration:
+  Step: Consider ` + "`pg_repack`" + ` extension for online reorganization
+    Can run in transaction: No
+    Command:
+      pg_repack --no-order -j 4 --wait-timeout 60 -t users -i users_pkey -d <YOUR_DATABASE>
+`,
+		},
+		{
+			name:      "repack-flags with pg_squeeze",
+			args:      []string{"--repack-tool", "pg_squeeze", "--repack-flags", "-j 4", "VACUUM FULL logs"},
+			wantExit:  1,
+			wantError: "--repack-flags only applies to --repack-tool pg_repack",
+		},
+		{
+			name:      "invalid input format",
+			args:      []string{"--input-format", "xml", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid input format "xml"`,
+		},
+		{
+			name:       "verbose notes search_path changes",
+			args:       []string{"--verbose", "SET search_path = app, public"},
+			wantExit:   0,
+			wantOutput: "  Note: later unqualified names resolve under search_path app, public; pg-lock-check reports them as written without applying the path\n",
+		},
+		{
+			name:     "check-statement-timeout notes disabled timeout",
+			args:     []string{"--no-suggestion", "--check-statement-timeout", "SET statement_timeout = 0; ALTER TABLE users ADD PRIMARY KEY (id)"},
+			wantExit: 0,
+			wantOutput: `[CRITICAL] ALTER TABLE users ADD PRIMARY KEY (id)
+  Note: PRIMARY KEY fails if existing rows contain duplicate values or NULLs in (id); check for them before running the migration
+  Note: runs with statement_timeout disabled (set at line 1); a blocked or long-running AccessExclusive lock can hang indefinitely
+`,
+		},
+		{
+			name:     "check-long-transaction notes held lock",
+			args:     []string{"--no-suggestion", "--check-long-transaction", "2", "ALTER TABLE users ADD COLUMN nickname text; SELECT 1; SELECT 2"},
+			wantExit: 0,
+			wantOutput: `[INFO] ALTER TABLE users ADD COLUMN nickname text
+  Note: AccessExclusive lock is held through 2 later statements until the end of input; run this statement in its own short transaction
+`,
+		},
+		{
+			name: "check-ddl-dml-mix notes a backfill in the DDL transaction",
+			args: []string{"--no-suggestion", "--check-ddl-dml-mix", "--no-transaction",
+				"BEGIN; ALTER TABLE users ADD COLUMN nickname text; UPDATE users SET nickname = name; COMMIT"},
+			wantExit: 0,
+			wantOutput: `[CRITICAL] UPDATE users SET nickname = name
+  Note: runs in the same transaction as the ALTER TABLE ADD COLUMN without DEFAULT at line 1, whose AccessExclusive lock is held for the whole of this full-table UPDATE without WHERE; commit the schema change first and run the data change in its own transaction
+`,
+		},
+		{
+			name:      "check-long-transaction rejects negative",
+			args:      []string{"--check-long-transaction", "-1", "SELECT 1"},
+			wantExit:  1,
+			wantError: "invalid --check-long-transaction -1",
+		},
+		{
+			name:     "large-insert-rows notes a long VALUES list",
+			args:     []string{"--no-suggestion", "--large-insert-rows", "3", "INSERT INTO users (id) VALUES (1), (2), (3)"},
+			wantExit: 0,
+			wantOutput: `[INFO] INSERT INTO users (id) VALUES (1), (2), (3)
+  Note: inserts 3 rows in one statement, holding RowExclusive on users while their WAL is written and keeping vacuum from removing dead rows older than the transaction; insert them in batches or load them with COPY
+`,
+		},
+		{
+			name:      "large-insert-rows rejects negative",
+			args:      []string{"--large-insert-rows", "-1", "SELECT 1"},
+			wantExit:  1,
+			wantError: "invalid --large-insert-rows -1",
+		},
+		{
+			name:      "indent rejects negative",
+			args:      []string{"--indent", "-1", "-o", "json", "SELECT 1"},
+			wantExit:  1,
+			wantError: "invalid --indent -1: must be 0 or greater",
+		},
+		{
+			name:      "dedupe rejects oneline",
+			args:      []string{"--dedupe", "--oneline", "SELECT 1"},
+			wantExit:  1,
+			wantError: "--dedupe cannot be combined with --oneline",
+		},
+		{
+			name:      "indent 0 is not compact YAML",
+			args:      []string{"--indent", "0", "-o", "yaml", "SELECT 1"},
+			wantExit:  1,
+			wantError: "invalid --indent 0 for YAML output: must be between 2 and 9",
+		},
+		{
+			name:     "continue-on-parse-error reports each failure",
+			args:     []string{"--no-suggestion", "--continue-on-parse-error"},
+			stdin:    "UPDATE users SET active = false;\nSELEC * FROM users;\nDROP TABLE old_users;",
+			wantExit: 2,
+			wantOutput: `[CRITICAL] UPDATE users SET active = false
+[CRITICAL] DROP TABLE old_users
+[PARSE ERROR] line 2: SELEC * FROM users
+  Error: syntax error at or near "SELEC"
+
+Summary: 2 statements analyzed, 1 failed to parse
+`,
+			wantError: "1 of 3 statements failed to parse",
+		},
+		{
+			name:     "exit-code-map critical",
+			args:     []string{"--no-suggestion", "--exit-code-map", "critical=10,error=11,warning=0", "UPDATE users SET x = 1"},
+			wantExit: 10,
+		},
+		{
+			name:     "exit-code-map uses max severity",
+			args:     []string{"--exit-code-map", "critical=10,error=11", "UPDATE users SET x = 1; VACUUM users"},
+			wantExit: 11,
+		},
+		{
+			name:     "max-lock-level violation fails on critical",
+			args:     []string{"--max-lock-level", "ShareUpdateExclusive", "--fail-on", "critical", "ALTER TABLE users ADD CONSTRAINT pk PRIMARY KEY USING INDEX idx"},
+			wantExit: 3,
+		},
+		{
+			name:     "max-lock-level weaker lock passes",
+			args:     []string{"--max-lock-level", "ShareUpdateExclusiveLock", "--fail-on", "critical", "INSERT INTO users VALUES (1)"},
+			wantExit: 0,
+		},
+		{
+			name:      "invalid max-lock-level",
+			args:      []string{"--max-lock-level", "Strong", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid --max-lock-level: unknown lock type "Strong"`,
+		},
+		{
+			name:     "exit-code-map unmapped severity exits 0",
+			args:     []string{"--exit-code-map", "critical=10", "SELECT 1"},
+			wantExit: 0,
+		},
+		{
+			name:     "exit-code-map keeps parse error default",
+			args:     []string{"--exit-code-map", "critical=10", "INVALID SQL"},
+			wantExit: 2,
+		},
+		{
+			name:     "exit-code-map remaps parse error",
+			args:     []string{"--exit-code-map", "parse=20", "INVALID SQL"},
+			wantExit: 20,
+		},
+		{
+			name:      "fail-on-unrecognized fails on a statement without its own operation",
+			args:      []string{"--no-suggestion", "--fail-on-unrecognized", "SELECT 1;\nDROP CONVERSION myconv"},
+			wantExit:  4,
+			wantError: "1 statement not specifically analyzed: line 2 (DROP)",
+		},
+		{
+			name:      "fail-on-unrecognized counts several statements",
+			args:      []string{"--no-suggestion", "--fail-on-unrecognized", "DROP CONVERSION a;\nDROP CONVERSION b"},
+			wantExit:  4,
+			wantError: "2 statements not specifically analyzed: line 1 (DROP), line 2 (DROP)",
+		},
+		{
+			name:     "exit-code-map remaps unrecognized statements",
+			args:     []string{"--no-suggestion", "--fail-on-unrecognized", "--exit-code-map", "unrecognized=30", "DROP CONVERSION myconv"},
+			wantExit: 30,
+		},
+		{
+			name:     "fail-on-unrecognized passes when every statement is recognized",
+			args:     []string{"--no-suggestion", "--fail-on-unrecognized", "SELECT 1;\nALTER TABLE users ADD COLUMN age int"},
+			wantExit: 0,
+		},
+		{
+			name:     "fail-on-unrecognized passes try advisory locks",
+			args:     []string{"--no-suggestion", "--fail-on-unrecognized", "SELECT pg_try_advisory_lock(1);\nSELECT pg_try_advisory_xact_lock_shared(1)"},
+			wantExit: 0,
+		},
+		{
+			name:     "unrecognized statements pass without fail-on-unrecognized",
+			args:     []string{"--no-suggestion", "DROP CONVERSION myconv"},
+			wantExit: 0,
+		},
+		{
+			name:     "fail-on reached exits 3",
+			args:     []string{"--no-suggestion", "--fail-on", "warning", "UPDATE users SET x = 1 WHERE id = 1"},
+			wantExit: 3,
+		},
+		{
+			name:     "fail-on not reached exits 0",
+			args:     []string{"--fail-on", "critical", "UPDATE users SET x = 1 WHERE id = 1"},
+			wantExit: 0,
+		},
+		{
+			name:     "exit-code-map overrides fail-on",
+			args:     []string{"--no-suggestion", "--fail-on", "warning", "--exit-code-map", "critical=10", "UPDATE users SET x = 1"},
+			wantExit: 10,
+		},
+		{
+			name:      "fail-on invalid severity",
+			args:      []string{"--fail-on", "fatal", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid --fail-on "fatal"`,
+		},
+		{
+			name:      "exit-code-map invalid key",
+			args:      []string{"--exit-code-map", "fatal=3", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid --exit-code-map key "fatal"`,
+		},
+		{
+			name:      "exit-code-map invalid code",
+			args:      []string{"--exit-code-map", "critical=abc", "SELECT 1"},
+			wantExit:  1,
+			wantError: `invalid --exit-code-map code "abc"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -317,7 +652,7 @@ Summary: 1 statements analyzed`,
 			}
 
 			// Run the function
-			exitCode := run(tt.args)
+			exitCode := run(withoutConfig(tt.args))
 
 			// Restore
 			_ = w.Close()
@@ -348,8 +683,168 @@ Summary: 1 statements analyzed`,
 	}
 }
 
+func TestTransactionMode(t *testing.T) {
+	sql := "CREATE INDEX CONCURRENTLY i ON t (a);\nBEGIN;\nCREATE INDEX CONCURRENTLY j ON t (b);\nCOMMIT;\nCREATE INDEX CONCURRENTLY k ON t (c);\n"
+
+	tests := []struct {
+		name string
+		args []string
+		want []string // severity per statement
+	}{
+		{
+			name: "default starts in a transaction and follows BEGIN/COMMIT",
+			want: []string{"ERROR", "INFO", "ERROR", "INFO", "WARNING"},
+		},
+		{
+			name: "always ignores BEGIN/COMMIT in one transaction",
+			args: []string{"--transaction-mode", "always"},
+			want: []string{"ERROR", "INFO", "ERROR", "INFO", "ERROR"},
+		},
+		{
+			name: "auto starts outside and follows BEGIN/COMMIT",
+			args: []string{"--transaction-mode", "auto"},
+			want: []string{"WARNING", "INFO", "ERROR", "INFO", "WARNING"},
+		},
+		{
+			name: "--no-transaction is auto",
+			args: []string{"--no-transaction"},
+			want: []string{"WARNING", "INFO", "ERROR", "INFO", "WARNING"},
+		},
+		{
+			name: "never ignores BEGIN/COMMIT",
+			args: []string{"--transaction-mode", "never"},
+			want: []string{"WARNING", "INFO", "WARNING", "INFO", "WARNING"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, exitCode := runCommand(t, append([]string{"--oneline"}, tt.args...), sql)
+			if exitCode != 0 {
+				t.Fatalf("exit code = %d, want 0", exitCode)
+			}
+
+			lines := strings.Split(strings.TrimSpace(output), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(tt.want), output)
+			}
+			for i, line := range lines {
+				if fields := strings.Fields(line); len(fields) < 2 || fields[1] != tt.want[i] {
+					t.Errorf("statement %d: got %q, want severity %s", i+1, line, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGzipFileInput(t *testing.T) {
+	sql := "SELECT * FROM users;\nUPDATE users SET active = false;\nCREATE INDEX idx ON users(email);\n"
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "migration.sql")
+	gzPath := filepath.Join(dir, "migration.sql.gz")
+
+	if err := os.WriteFile(plainPath, []byte(sql), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte(sql))
+	_ = gz.Close()
+	if err := os.WriteFile(gzPath, compressed.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write gzip fixture: %v", err)
+	}
+
+	plainOutput, plainExit := runCommand(t, []string{"-o", "json", "-f", plainPath}, "")
+	gzOutput, gzExit := runCommand(t, []string{"-o", "json", "-f", gzPath}, "")
+
+	if plainExit != 0 || gzExit != 0 {
+		t.Fatalf("Expected exit 0, got %d (plain) and %d (gzip): %s", plainExit, gzExit, gzOutput)
+	}
+	if plainOutput != gzOutput {
+		t.Errorf("Gzip output differs from plain output\nPlain: %s\nGzip: %s", plainOutput, gzOutput)
+	}
+
+	// A .gz file that is not gzip-compressed is a read error
+	if err := os.WriteFile(filepath.Join(dir, "broken.sql.gz"), []byte(sql), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	if output, exitCode := runCommand(t, []string{"-f", filepath.Join(dir, "broken.sql.gz")}, ""); exitCode != 1 || !strings.Contains(output, "reading file") {
+		t.Errorf("Expected reading file error for invalid gzip, got exit %d: %s", exitCode, output)
+	}
+}
+
+func TestTrailingSemicolon(t *testing.T) {
+	body := "SELECT 1;\nUPDATE users\n  SET active = false"
+	want := "UPDATE users\n  SET active = false"
+
+	results := func(sql string) []OutputResult {
+		t.Helper()
+		stdout, _, exitCode := runCommandOutputs(t, []string{"-o", "json", "--no-suggestion"}, sql)
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, stdout)
+		}
+		var output Output
+		if err := json.Unmarshal([]byte(stdout), &output); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v", err)
+		}
+		return output.Results
+	}
+
+	terminated := results(body + ";\n")
+	if len(terminated) != 2 || terminated[1].SQL != want || terminated[1].LineNumber != 2 {
+		t.Fatalf("Unexpected results for the terminated file: %+v", terminated)
+	}
+	for _, suffix := range []string{"", "\n", " -- no semicolon\n"} {
+		if got := results(body + suffix); !reflect.DeepEqual(got, terminated) {
+			t.Errorf("Results for a file ending in %q differ\nGot:  %+v\nWant: %+v", suffix, got, terminated)
+		}
+	}
+
+	// A baseline written from one form accepts the other
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	runCommand(t, []string{"--baseline", path, "--baseline-update"}, body+";\n")
+	if output, exitCode := runCommand(t, []string{"--baseline", path, "--fail-on", "warning"}, body); exitCode != 0 {
+		t.Errorf("Expected the baseline to accept the unterminated statement, got exit %d: %s", exitCode, output)
+	}
+}
+
+func TestLargeInsertRowsDefault(t *testing.T) {
+	sql := "INSERT INTO users (id) VALUES " + strings.Repeat("(1), ", 1999) + "(1);"
+
+	output, exitCode := runCommand(t, []string{"--no-suggestion"}, sql)
+	if exitCode != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+	}
+	if strings.Contains(output, "rows in one statement") {
+		t.Errorf("Expected no large insert note without --large-insert-rows, got %q", output)
+	}
+}
+
+func TestReadFileInputStdin(t *testing.T) {
+	content, err := readFileInput("-", strings.NewReader("SELECT 1;"))
+	if err != nil {
+		t.Fatalf("readFileInput() error = %v", err)
+	}
+	if content != "SELECT 1;" {
+		t.Errorf("Expected stdin content %q, got %q", "SELECT 1;", content)
+	}
+
+	output, exitCode := runCommand(t, []string{"-f", "-", "--no-suggestion"}, "TRUNCATE users")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+	}
+	if !strings.Contains(output, "[CRITICAL] TRUNCATE users") {
+		t.Errorf("Expected stdin statement to be analyzed, got %s", output)
+	}
+}
+
 // Test data setup
 func TestMain(m *testing.M) {
+	// Fix generated_at so repeated runs produce identical output; the zone checks it is reported in UTC
+	now = func() time.Time { return time.Date(2025, 1, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60)) }
+
 	// Create test data directory
 	_ = os.MkdirAll("testdata", 0755)
 
@@ -3,9 +3,12 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -30,6 +33,8 @@ type ExpectedResult struct {
 	operation  string
 	lockType   string
 	tables     []ExpectedTable
+
+	errorReason string // Expected error_reason, empty when omitted
 }
 
 type ExpectedTable struct {
@@ -252,6 +257,8 @@ var allTestCases = []OutputTestCase{
 				operation:  "VACUUM",
 				lockType:   "", // ERROR operations have no lock
 				tables:     []ExpectedTable{},
+
+				errorReason: "cannot-run-in-transaction",
 			},
 		},
 	},
@@ -287,6 +294,8 @@ var allTestCases = []OutputTestCase{
 				tables: []ExpectedTable{
 					{name: "users", lockType: "ShareUpdateExclusive"},
 				},
+
+				errorReason: "cannot-run-in-transaction",
 			},
 		},
 	},
@@ -572,6 +581,12 @@ func TestJSONOutputFormat(t *testing.T) {
 					t.Errorf("Result %d: expected lock_type %s, got %s", i, expected.lockType, lockType)
 				}
 
+				// Check error reason
+				errorReason, _ := result["error_reason"].(string)
+				if errorReason != expected.errorReason {
+					t.Errorf("Result %d: expected error_reason %q, got %q", i, expected.errorReason, errorReason)
+				}
+
 				// Check tables
 				tables := result["tables"].([]interface{})
 				if len(tables) != len(expected.tables) {
@@ -668,10 +683,773 @@ func TestYAMLOutputFormat(t *testing.T) {
 	}
 }
 
+// TestJSONInputFormat tests that --input-format json preserves caller attribution
+func TestJSONInputFormat(t *testing.T) {
+	input := `[
+  {"sql": "UPDATE users SET active = false", "file": "migrations/001.sql", "line": 12},
+  {"sql": "SELECT * FROM users;", "file": "migrations/002.sql", "line": 3}
+]`
+
+	output, exitCode := runCommand(t, []string{"--input-format", "json", "-o", "json", "--no-suggestion"}, input)
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+
+	if len(result.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(result.Results))
+	}
+
+	expected := []struct {
+		file      string
+		line      int
+		sql       string
+		operation string
+	}{
+		{file: "migrations/001.sql", line: 12, sql: "UPDATE users SET active = false", operation: "UPDATE without WHERE"},
+		{file: "migrations/002.sql", line: 3, sql: "SELECT * FROM users", operation: "SELECT"},
+	}
+
+	for i, want := range expected {
+		got := result.Results[i]
+		if got.File != want.file {
+			t.Errorf("Result %d: expected file %q, got %q", i, want.file, got.File)
+		}
+		if got.LineNumber != want.line {
+			t.Errorf("Result %d: expected line_number %d, got %d", i, want.line, got.LineNumber)
+		}
+		if got.SQL != want.sql {
+			t.Errorf("Result %d: expected sql %q, got %q", i, want.sql, got.SQL)
+		}
+		if got.Operation != want.operation {
+			t.Errorf("Result %d: expected operation %q, got %q", i, want.operation, got.Operation)
+		}
+	}
+}
+
+func TestOnelineOutput(t *testing.T) {
+	sql := `BEGIN;
+UPDATE users SET active = false;
+SELECT * FROM orders o JOIN users u ON u.id = o.user_id;
+CREATE INDEX idx_users_email ON users(email);
+COMMIT;`
+
+	want := `migrations/001.sql:1: INFO BEGIN
+migrations/001.sql:2: CRITICAL UPDATE without WHERE [users:RowExclusive]
+migrations/001.sql:3: INFO SELECT [orders:AccessShare,users:AccessShare]
+migrations/001.sql:4: CRITICAL CREATE INDEX [users:Share]
+migrations/001.sql:5: INFO COMMIT
+`
+
+	// Run repeatedly: table locks must not depend on map iteration order
+	for i := 0; i < 5; i++ {
+		output, exitCode := runCommand(t, []string{"--oneline", "--stdin-filename", "migrations/001.sql"}, sql)
+		if exitCode != 0 {
+			t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+		}
+		if output != want {
+			t.Fatalf("Expected output:\n%s\nGot:\n%s", want, output)
+		}
+	}
+}
+
+func TestStdinFilename(t *testing.T) {
+	stdin := "UPDATE users SET active = false;\nSELECT 1;"
+
+	t.Run("json", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"-o", "json", "--stdin-filename", "migrations/001.sql"}, stdin)
+		if exitCode != 0 {
+			t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+		}
+
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+		}
+		if len(result.Results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(result.Results))
+		}
+		for i, r := range result.Results {
+			if r.File != "migrations/001.sql" || r.LineNumber != i+1 {
+				t.Errorf("Result %d: expected migrations/001.sql:%d, got %s:%d", i, i+1, r.File, r.LineNumber)
+			}
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--no-suggestion", "--stdin-filename", "migrations/001.sql"}, stdin)
+		if exitCode != 0 {
+			t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+		}
+
+		want := "[CRITICAL] migrations/001.sql:1: UPDATE users SET active = false\n[INFO] migrations/001.sql:2: SELECT 1\n"
+		if !strings.HasPrefix(output, want) {
+			t.Errorf("Expected output starting with:\n%s\nGot:\n%s", want, output)
+		}
+	})
+
+	t.Run("rejected for SQL arguments", func(t *testing.T) {
+		_, stderr, exitCode := runCommandOutputs(t, []string{"--stdin-filename", "migrations/001.sql", "SELECT 1"}, "")
+		if exitCode != 1 {
+			t.Errorf("Expected exit code 1, got %d", exitCode)
+		}
+		if !strings.Contains(stderr, "--stdin-filename only applies to SQL read from stdin") {
+			t.Errorf("Unexpected stderr: %q", stderr)
+		}
+	})
+}
+
+func TestMultiStatementArgument(t *testing.T) {
+	output, exitCode := runCommand(t, []string{"-o", "json", "--no-suggestion", "UPDATE a SET x=1; DROP TABLE b;"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+
+	expected := []struct {
+		sql       string
+		operation string
+	}{
+		{sql: "UPDATE a SET x=1", operation: "UPDATE without WHERE"},
+		{sql: "DROP TABLE b", operation: "DROP TABLE"},
+	}
+
+	if len(result.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(result.Results))
+	}
+
+	for i, want := range expected {
+		got := result.Results[i]
+		if got.SQL != want.sql {
+			t.Errorf("Result %d: expected sql %q, got %q", i, want.sql, got.SQL)
+		}
+		if got.Operation != want.operation {
+			t.Errorf("Result %d: expected operation %q, got %q", i, want.operation, got.Operation)
+		}
+		if got.LineNumber != 1 {
+			t.Errorf("Result %d: expected line_number 1, got %d", i, got.LineNumber)
+		}
+	}
+}
+
+func TestContinueOnParseError(t *testing.T) {
+	sql := "UPDATE users SET active = false;\nSELEC * FROM users;\nDROP TABLE old_users;"
+
+	output, stderr, exitCode := runCommandOutputs(t, []string{"-o", "json", "--no-suggestion", "--continue-on-parse-error"}, sql)
+	if exitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(stderr, "1 of 3 statements failed to parse") {
+		t.Errorf("Expected parse error summary on stderr, got %q", stderr)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+
+	if len(result.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(result.Results))
+	}
+	if result.Results[0].Operation != "UPDATE without WHERE" || result.Results[1].Operation != "DROP TABLE" {
+		t.Errorf("Unexpected operations %q, %q", result.Results[0].Operation, result.Results[1].Operation)
+	}
+
+	if len(result.ParseErrors) != 1 {
+		t.Fatalf("Expected 1 parse error, got %d", len(result.ParseErrors))
+	}
+	parseErr := result.ParseErrors[0]
+	if parseErr.LineNumber != 2 || parseErr.SQL != "SELEC * FROM users" {
+		t.Errorf("Unexpected parse error %+v", parseErr)
+	}
+	if !strings.Contains(parseErr.Message, "syntax error") {
+		t.Errorf("Expected syntax error message, got %q", parseErr.Message)
+	}
+}
+
+func TestScopeField(t *testing.T) {
+	output, exitCode := runCommand(t, []string{"-o", "json", "--no-transaction", "REINDEX INDEX idx; REINDEX DATABASE mydb; REINDEX SYSTEM mydb"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+
+	expected := []string{"object", "database", "system"}
+	if len(result.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(result.Results))
+	}
+	for i, want := range expected {
+		if got := result.Results[i].Scope; got != want {
+			t.Errorf("Result %d (%s): expected scope %q, got %q", i, result.Results[i].SQL, want, got)
+		}
+	}
+}
+
+func TestHTMLOutputFormat(t *testing.T) {
+	sql := "CREATE INDEX idx_users_email ON users (email);\nUPDATE users SET active = true;\nSELECT 1;"
+
+	output, exitCode := runCommand(t, []string{"-o", "html", sql}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<td id="count-critical">2</td>`,
+		`<td id="count-info">1</td>`,
+		`<td id="count-error">0</td>`,
+		// Suggestions are collapsible, with highlighted SQL
+		"<details>",
+		`<span class="kw">CREATE</span> <span class="kw">INDEX</span> <span class="kw">CONCURRENTLY</span>`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected HTML to contain %q, got: %s", want, output)
+		}
+	}
+	if rows := strings.Count(output, `<tr class="finding">`); rows != 3 {
+		t.Errorf("Expected a row per finding (3), got %d", rows)
+	}
+
+	// SQL is escaped, never injected as markup
+	output, _ = runCommand(t, []string{"-o", "html", "SELECT '<script>alert(1)</script>'"}, "")
+	if strings.Contains(output, "<script>alert(1)") {
+		t.Errorf("Expected SQL to be escaped, got: %s", output)
+	}
+}
+
+func TestOutputFormatValidation(t *testing.T) {
+	t.Run("unknown format errors instead of printing text", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"-o", "bogus", "SELECT 1"}, "")
+		if exitCode != 1 {
+			t.Errorf("Expected exit code 1, got %d", exitCode)
+		}
+		if stdout != "" {
+			t.Errorf("Expected no output, got %q", stdout)
+		}
+		if !strings.Contains(stderr, "must be one of text, json, yaml") {
+			t.Errorf("Expected the valid formats in stderr, got %q", stderr)
+		}
+	})
+
+	t.Run("aliases match their canonical format", func(t *testing.T) {
+		for alias, format := range map[string]string{"yml": "yaml", "YAML": "yaml", "txt": "text", "Json": "json"} {
+			aliasOutput, _ := runCommand(t, []string{"-o", alias, "SELECT 1"}, "")
+			formatOutput, _ := runCommand(t, []string{"-o", format, "SELECT 1"}, "")
+			if aliasOutput != formatOutput {
+				t.Errorf("-o %s: expected the same output as -o %s\nGot:\n%s\nWant:\n%s", alias, format, aliasOutput, formatOutput)
+			}
+		}
+	})
+}
+
+func TestNormalizedSQLField(t *testing.T) {
+	output, exitCode := runCommand(t, []string{"-o", "json", "UPDATE t SET x=5 WHERE id=1; UPDATE t SET x=6 WHERE id=2"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+
+	if len(result.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(result.Results))
+	}
+	// Statements differing only in literals share a normalized form
+	for i, r := range result.Results {
+		if r.NormalizedSQL != "UPDATE t SET x=$1 WHERE id=$2" {
+			t.Errorf("Result %d: expected literal-free normalized_sql, got %q", i, r.NormalizedSQL)
+		}
+	}
+}
+
+func TestCanonicalSQLField(t *testing.T) {
+	messy := "update   users\n   set active=false\twhere  id = 1"
+	output, exitCode := runCommand(t, []string{"-o", "json", "--canonical-sql", messy}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+	if len(result.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(result.Results))
+	}
+	if got := result.Results[0].SQL; got != "UPDATE users SET active = false WHERE id = 1" {
+		t.Errorf("Expected canonical sql, got %q", got)
+	}
+	if got := result.Results[0].OriginalSQL; got != messy {
+		t.Errorf("Expected original_sql %q, got %q", messy, got)
+	}
+
+	// Without the flag the original text is reported and original_sql is omitted
+	output, _ = runCommand(t, []string{"-o", "json", messy}, "")
+	if strings.Contains(output, "original_sql") || !strings.Contains(output, `"sql": "update   users`) {
+		t.Errorf("Expected the original sql without original_sql, got: %s", output)
+	}
+
+	// Text output prints the canonical form
+	output, _ = runCommand(t, []string{"--canonical-sql", "--no-suggestion", messy}, "")
+	if !strings.Contains(output, "] UPDATE users SET active = false WHERE id = 1\n") {
+		t.Errorf("Expected canonical sql in text output, got: %s", output)
+	}
+}
+
+func TestShowAllLocks(t *testing.T) {
+	sql := "ALTER TABLE orders ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)"
+
+	output, exitCode := runCommand(t, []string{"-o", "json", "--show-all-locks", sql}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+	want := []TableLock{{Name: "orders", LockType: "ShareRowExclusive"}, {Name: "users", LockType: "RowShare"}}
+	if got := result.Results[0].AllLocks; !slices.Equal(got, want) {
+		t.Errorf("Expected all_locks %v, got %v", want, got)
+	}
+
+	// Text output lists the locks in acquisition order
+	output, _ = runCommand(t, []string{"--show-all-locks", sql}, "")
+	if !strings.Contains(output, "  Locks: orders:ShareRowExclusive -> users:RowShare\n") {
+		t.Errorf("Expected ordered locks in text output, got: %s", output)
+	}
+
+	// Without the flag all_locks is omitted
+	output, _ = runCommand(t, []string{"-o", "json", sql}, "")
+	if strings.Contains(output, "all_locks") {
+		t.Errorf("Expected no all_locks without --show-all-locks, got: %s", output)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	sql := "CREATE INDEX idx_users_email ON users (email)"
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantPrefix string
+	}{
+		{
+			name:       "JSON default",
+			args:       []string{"-o", "json", sql},
+			wantPrefix: "{\n  \"",
+		},
+		{
+			name:       "JSON four spaces",
+			args:       []string{"-o", "json", "--indent", "4", sql},
+			wantPrefix: "{\n    \"",
+		},
+		{
+			name:       "YAML four spaces",
+			args:       []string{"-o", "yaml", "--indent", "4", sql},
+			wantPrefix: "meta:\n    version: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, exitCode := runCommand(t, tt.args, "")
+			if exitCode != 0 {
+				t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+			}
+			if !strings.HasPrefix(output, tt.wantPrefix) {
+				t.Errorf("Expected output to start with %q, got: %s", tt.wantPrefix, output)
+			}
+		})
+	}
+
+	// 0 writes the whole JSON document on one line
+	output, exitCode := runCommand(t, []string{"-o", "json", "--indent", "0", sql}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+	if strings.Count(output, "\n") != 1 || !strings.HasSuffix(output, "}\n") {
+		t.Errorf("Expected compact JSON on one line, got: %s", output)
+	}
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	var sql strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&sql, "CREATE INDEX idx_%d ON table_%d (col);\n", i, i)
+	}
+	sql.WriteString("DROP TABLE users;\n")
+
+	output, exitCode := runCommand(t, []string{"-o", "json", "--dedupe", sql.String()}, "")
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("Expected 2 deduplicated results, got %d", len(result.Results))
+	}
+
+	index := result.Results[0]
+	if index.Operation != "CREATE INDEX" || index.Occurrences != 10 {
+		t.Errorf("Expected CREATE INDEX with 10 occurrences, got %s with %d", index.Operation, index.Occurrences)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(index.LineNumbers, want) {
+		t.Errorf("Expected line numbers %v, got %v", want, index.LineNumbers)
+	}
+	if drop := result.Results[1]; drop.Operation != "DROP TABLE" || drop.Occurrences != 1 {
+		t.Errorf("Expected DROP TABLE with 1 occurrence, got %s with %d", drop.Operation, drop.Occurrences)
+	}
+
+	// The summary still counts every statement
+	if result.Summary.TotalStatements != 11 || result.Summary.BySeverity["CRITICAL"] != 11 {
+		t.Errorf("Expected summary over all 11 statements, got %+v", result.Summary)
+	}
+
+	// Text output prints the finding once with where it occurs
+	output, _ = runCommand(t, []string{"--dedupe", "--no-suggestion", sql.String()}, "")
+	if strings.Count(output, "CREATE INDEX") != 1 || !strings.Contains(output, "  Occurrences: 10 (lines 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)\n") {
+		t.Errorf("Expected one CREATE INDEX with its occurrences, got: %s", output)
+	}
+
+	// Without the flag every statement is reported
+	output, _ = runCommand(t, []string{"-o", "json", sql.String()}, "")
+	if strings.Contains(output, "occurrences") {
+		t.Errorf("Expected no occurrences without --dedupe, got: %s", output)
+	}
+}
+
+func TestJSONLinesByFile(t *testing.T) {
+	input := `[
+		{"sql": "CREATE INDEX idx_users_email ON users (email)", "file": "001_users.sql"},
+		{"sql": "SELECT 1", "file": "002_orders.sql"},
+		{"sql": "UPDATE users SET active = true", "file": "001_users.sql"}
+	]`
+	output, exitCode := runCommand(t, []string{"--json-lines-by-file", "--input-format", "json"}, input)
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %d: %s", len(lines), output)
+	}
+
+	tests := []struct {
+		file       string
+		statements int
+		critical   int
+		info       int
+	}{
+		{file: "001_users.sql", statements: 2, critical: 2},
+		{file: "002_orders.sql", statements: 1, info: 1},
+	}
+	for i, tt := range tests {
+		var result Output
+		if err := json.Unmarshal([]byte(lines[i]), &result); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v\nLine: %s", i+1, err, lines[i])
+		}
+		if result.File != tt.file {
+			t.Errorf("Line %d: expected file %q, got %q", i+1, tt.file, result.File)
+		}
+		if result.Summary.TotalStatements != tt.statements || len(result.Results) != tt.statements {
+			t.Errorf("Line %d: expected %d statements, got summary %d with %d results",
+				i+1, tt.statements, result.Summary.TotalStatements, len(result.Results))
+		}
+		if result.Summary.BySeverity["CRITICAL"] != tt.critical || result.Summary.BySeverity["INFO"] != tt.info {
+			t.Errorf("Line %d: unexpected severity counts %v", i+1, result.Summary.BySeverity)
+		}
+		for _, r := range result.Results {
+			if r.File != tt.file {
+				t.Errorf("Line %d: result from %q in the %q document", i+1, r.File, tt.file)
+			}
+		}
+	}
+
+	t.Run("rejects non-JSON output", func(t *testing.T) {
+		_, stderr, exitCode := runCommandOutputs(t, []string{"--json-lines-by-file", "-o", "yaml", "SELECT 1"}, "")
+		if exitCode != 1 || !strings.Contains(stderr, "cannot be combined with --output yaml") {
+			t.Errorf("Expected exit 1 with output conflict error, got %d: %s", exitCode, stderr)
+		}
+	})
+}
+
+func TestMetaField(t *testing.T) {
+	input := `[{"sql": "SELECT 1", "file": "a.sql"}, {"sql": "SELECT 2", "file": "b.sql"}, {"sql": "SELECT 3", "file": "a.sql"}]`
+	output, exitCode := runCommand(t, []string{"-o", "yaml", "--input-format", "json", "--transaction-mode", "never"}, input)
+	if exitCode != 0 {
+		t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+	}
+
+	var result Output
+	if err := yaml.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Output is not valid YAML: %v\nOutput: %s", err, output)
+	}
+
+	meta := result.Meta
+	if meta.Version != version {
+		t.Errorf("Expected version %q, got %q", version, meta.Version)
+	}
+	if !strings.HasPrefix(meta.PgVersion, "17.") {
+		t.Errorf("Expected pg_version of the PostgreSQL 17 grammar, got %q", meta.PgVersion)
+	}
+	if meta.TransactionMode != "never" {
+		t.Errorf("Expected transaction_mode never, got %q", meta.TransactionMode)
+	}
+	if meta.FilesAnalyzed != 2 {
+		t.Errorf("Expected files_analyzed 2, got %d", meta.FilesAnalyzed)
+	}
+	if meta.GeneratedAt != "2025-01-01T00:00:00Z" {
+		t.Errorf("Expected generated_at of the test clock in UTC RFC 3339, got %q", meta.GeneratedAt)
+	}
+}
+
+func TestRecommendedModeField(t *testing.T) {
+	sql := "CREATE INDEX CONCURRENTLY idx ON users(email); UPDATE users SET active = true WHERE id = 1; CREATE INDEX idx2 ON users(name); SAVEPOINT sp1"
+	// CREATE INDEX follows its CONCURRENTLY suggestion
+	expected := []string{"no-transaction", "either", "no-transaction", "either"}
+
+	// Display flags do not change the recommendation
+	for _, args := range [][]string{{}, {"--no-suggestion"}, {"--suggest-for", "info"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			output, exitCode := runCommand(t, append([]string{"-o", "json", "--no-transaction", sql}, args...), "")
+			if exitCode != 0 {
+				t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+			}
+
+			var result Output
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+			}
+
+			if len(result.Results) != len(expected) {
+				t.Fatalf("Expected %d results, got %d", len(expected), len(result.Results))
+			}
+			for i, want := range expected {
+				if got := result.Results[i].RecommendedMode; got != want {
+					t.Errorf("Result %d (%s): expected recommended_mode %q, got %q", i, result.Results[i].SQL, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupBySeverity(t *testing.T) {
+	sql := "SELECT 1; UPDATE a SET x = 1; VACUUM a; UPDATE b SET y = 1 WHERE id = 1; DROP TABLE c"
+
+	t.Run("text", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--group-by-severity", "--no-suggestion", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+		}
+
+		want := `== ERROR (1) ==
+[ERROR] VACUUM a
+== CRITICAL (2) ==
+[CRITICAL] UPDATE a SET x = 1
+[CRITICAL] DROP TABLE c
+== WARNING (1) ==
+[WARNING] UPDATE b SET y = 1 WHERE id = 1
+== INFO (1) ==
+[INFO] SELECT 1
+
+Summary: 5 statements analyzed
+`
+		if output != want {
+			t.Errorf("Expected output:\n%s\nGot:\n%s", want, output)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--group-by-severity", "--no-suggestion", "-o", "json", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+		}
+
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+		}
+
+		expected := []struct {
+			index    int
+			severity string
+		}{
+			{2, "ERROR"},
+			{1, "CRITICAL"},
+			{4, "CRITICAL"},
+			{3, "WARNING"},
+			{0, "INFO"},
+		}
+		if len(result.Results) != len(expected) {
+			t.Fatalf("Expected %d results, got %d", len(expected), len(result.Results))
+		}
+		for i, want := range expected {
+			got := result.Results[i]
+			if got.Index != want.index || got.Severity != want.severity {
+				t.Errorf("Position %d: expected index %d (%s), got index %d (%s)", i, want.index, want.severity, got.Index, got.Severity)
+			}
+		}
+	})
+}
+
+func TestQuietIfClean(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantOutput string
+		wantExit   int
+	}{
+		{
+			name:     "clean file prints nothing",
+			args:     []string{"--quiet-if-clean", "SELECT * FROM users; INSERT INTO users (id) VALUES (1)"},
+			wantExit: 0,
+		},
+		{
+			name:     "clean JSON prints nothing",
+			args:     []string{"--quiet-if-clean", "-o", "json", "SELECT * FROM users"},
+			wantExit: 0,
+		},
+		{
+			name: "warning prints the full report",
+			args: []string{"--quiet-if-clean", "SELECT * FROM users; UPDATE users SET active = true WHERE id = 1"},
+			wantOutput: `[INFO] SELECT * FROM users
+[WARNING] UPDATE users SET active = true WHERE id = 1
+
+Summary: 2 statements analyzed
+`,
+			wantExit: 0,
+		},
+		{
+			name:     "fail-on raises the threshold",
+			args:     []string{"--quiet-if-clean", "--fail-on", "critical", "UPDATE users SET active = true WHERE id = 1"},
+			wantExit: 0,
+		},
+		{
+			name: "fail-on gates a noisy report",
+			args: []string{"--quiet-if-clean", "--fail-on", "critical", "--no-suggestion", "UPDATE users SET active = true"},
+			wantOutput: `[CRITICAL] UPDATE users SET active = true
+
+Summary: 1 statements analyzed
+`,
+			wantExit: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runCommandOutputs(t, tt.args, "")
+			if exitCode != tt.wantExit {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.wantExit, exitCode, stderr)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("Expected output:\n%q\nGot:\n%q", tt.wantOutput, stdout)
+			}
+		})
+	}
+}
+
+func TestSummaryOnlyOutput(t *testing.T) {
+	sql := "UPDATE users SET active = false; SELECT * FROM users; VACUUM users"
+
+	t.Run("text", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--summary-only", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Command failed with exit code %d: %s", exitCode, output)
+		}
+
+		want := `Summary: 3 statements analyzed
+  ERROR: 1
+  CRITICAL: 1
+  WARNING: 0
+  INFO: 1
+`
+		if output != want {
+			t.Errorf("Expected output:\n%s\nGot:\n%s", want, output)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--summary-only", "-o", "json", "--exit-code-map", "critical=10,error=11", sql}, "")
+		if exitCode != 11 {
+			t.Errorf("Expected exit code 11, got %d", exitCode)
+		}
+
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v\nOutput: %s", err, output)
+		}
+
+		if result.Results == nil || len(result.Results) != 0 {
+			t.Errorf("Expected empty results array, got %v", result.Results)
+		}
+		if result.Summary.TotalStatements != 3 {
+			t.Errorf("Expected total_statements 3, got %d", result.Summary.TotalStatements)
+		}
+		wantCounts := map[string]int{"ERROR": 1, "CRITICAL": 1, "WARNING": 0, "INFO": 1}
+		for severity, want := range wantCounts {
+			if got := result.Summary.BySeverity[severity]; got != want {
+				t.Errorf("Expected %s count %d, got %d", severity, want, got)
+			}
+		}
+	})
+}
+
 // Helper to run command and capture output
 func runCommand(t *testing.T, args []string, stdin string) (string, int) {
 	t.Helper()
 
+	stdout, stderr, exitCode := runCommandOutputs(t, args, stdin)
+
+	// If there's an error, return stderr; otherwise return stdout
+	if exitCode != 0 && stderr != "" {
+		return stderr, exitCode
+	}
+	return stdout, exitCode
+}
+
+// Helper to run command and capture stdout and stderr separately
+func runCommandOutputs(t *testing.T, args []string, stdin string) (string, string, int) {
+	t.Helper()
+	return runCommandWithConfig(t, withoutConfig(args), stdin)
+}
+
+// withoutConfig disables config discovery, so a .pg-lock-check.yaml in a directory above the
+// checkout does not change the results of tests that are not about configs; describe-lock reads
+// no config and has no --no-config flag
+func withoutConfig(args []string) []string {
+	if len(args) > 0 && args[0] == "describe-lock" {
+		return args
+	}
+	return append([]string{"--no-config"}, args...)
+}
+
+// runCommandWithConfig runs the command with config discovery and --config left to the arguments,
+// capturing stdout and stderr separately
+func runCommandWithConfig(t *testing.T, args []string, stdin string) (string, string, int) {
+	t.Helper()
+
 	// Capture stdout and stderr
 	oldStdout := os.Stdout
 	oldStderr := os.Stderr
@@ -707,9 +1485,5 @@ func runCommand(t *testing.T, args []string, stdin string) (string, int) {
 	_, _ = io.Copy(&outBuf, rOut)
 	_, _ = io.Copy(&errBuf, rErr)
 
-	// If there's an error, return stderr; otherwise return stdout
-	if exitCode != 0 && errBuf.Len() > 0 {
-		return errBuf.String(), exitCode
-	}
-	return outBuf.String(), exitCode
+	return outBuf.String(), errBuf.String(), exitCode
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// Profiles accepted by --profile
+const (
+	profileCPU = "cpu"
+	profileMem = "mem"
+)
+
+// startProfile starts the --profile profile writing to out and returns a function that finishes it.
+// With no profile requested it returns a no-op.
+func startProfile(kind, out string) (func() error, error) {
+	if kind == "" {
+		if out != "" {
+			return nil, fmt.Errorf("--profile-out requires --profile")
+		}
+		return func() error { return nil }, nil
+	}
+
+	switch kind {
+	case profileCPU, profileMem:
+	default:
+		return nil, fmt.Errorf("invalid --profile %q: must be cpu or mem", kind)
+	}
+	if out == "" {
+		return nil, fmt.Errorf("--profile requires --profile-out")
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return nil, fmt.Errorf("creating profile: %w", err)
+	}
+
+	if kind == profileCPU {
+		if err := pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("starting CPU profile: %w", err)
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		}, nil
+	}
+
+	// The allocs profile covers every allocation since the program started, not only live memory
+	return func() error {
+		runtime.GC()
+		if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("writing memory profile: %w", err)
+		}
+		return f.Close()
+	}, nil
+}
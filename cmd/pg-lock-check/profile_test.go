@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	for _, kind := range []string{profileCPU, profileMem} {
+		t.Run(kind, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), kind+".prof")
+
+			output, exitCode := runCommand(t, []string{"--profile", kind, "--profile-out", out, "--oneline", "CREATE INDEX idx ON users (id)"}, "")
+			if exitCode != 0 {
+				t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+			}
+
+			info, err := os.Stat(out)
+			if err != nil {
+				t.Fatalf("Expected profile file: %v", err)
+			}
+			if info.Size() == 0 {
+				t.Errorf("Expected non-empty %s profile", kind)
+			}
+		})
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantError string
+	}{
+		{
+			name:      "invalid kind",
+			args:      []string{"--profile", "block", "--profile-out", "out.prof", "SELECT 1"},
+			wantError: `invalid --profile "block": must be cpu or mem`,
+		},
+		{
+			name:      "missing output file",
+			args:      []string{"--profile", "cpu", "SELECT 1"},
+			wantError: "--profile requires --profile-out",
+		},
+		{
+			name:      "output file without profile",
+			args:      []string{"--profile-out", "out.prof", "SELECT 1"},
+			wantError: "--profile-out requires --profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			_, stderr, exitCode := runCommandOutputs(t, tt.args, "")
+			if exitCode != 1 || !strings.Contains(stderr, tt.wantError) {
+				t.Errorf("Expected exit 1 with %q, got %d: %s", tt.wantError, exitCode, stderr)
+			}
+			if _, err := os.Stat("out.prof"); err == nil {
+				t.Errorf("Expected no profile file on error")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// pathBase is the absolute directory reported file paths are relative to, set by runAnalysis from
+// --relative-to, or the current directory for the dir subcommand; "" reports paths as given
+var pathBase string
+
+// resolvePathBase returns the absolute directory file paths are reported relative to
+func resolvePathBase(relativeTo string, dirScan bool) (string, error) {
+	if relativeTo == "" {
+		if !dirScan {
+			return "", nil
+		}
+		relativeTo = "."
+	}
+
+	base, err := filepath.Abs(relativeTo)
+	if err != nil {
+		return "", fmt.Errorf("invalid --relative-to %s: %w", relativeTo, err)
+	}
+	info, err := os.Stat(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid --relative-to %s: %w", relativeTo, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid --relative-to %s: not a directory", relativeTo)
+	}
+	return base, nil
+}
+
+// reportedPath returns path relative to pathBase. Paths outside pathBase, and all paths when
+// no base is set, are returned unchanged, so a report never points above the base.
+func reportedPath(path string) string {
+	if pathBase == "" || path == "" {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(pathBase, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// relativizeFiles reports the files of statements and parse errors relative to pathBase
+func relativizeFiles(parsed *parser.ParseResult) {
+	for i := range parsed.Statements {
+		parsed.Statements[i].File = reportedPath(parsed.Statements[i].File)
+	}
+	for i := range parsed.Errors {
+		parsed.Errors[i].File = reportedPath(parsed.Errors[i].File)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRelativeTo(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeMigration(t, "db/migrations/0001_init.sql", "CREATE TABLE users (id int);\n")
+	absolute := filepath.Join(dir, "db", "migrations")
+
+	t.Run("dir defaults to the current directory", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--oneline", absolute}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, stderr)
+		}
+		if want := "db/migrations/0001_init.sql:1: INFO CREATE TABLE\n"; stdout != want {
+			t.Errorf("Output = %q, want %q", stdout, want)
+		}
+	})
+
+	t.Run("configured base", func(t *testing.T) {
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--oneline", "--relative-to", "db/migrations", absolute}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, stderr)
+		}
+		if want := "0001_init.sql:1: INFO CREATE TABLE\n"; stdout != want {
+			t.Errorf("Output = %q, want %q", stdout, want)
+		}
+	})
+
+	t.Run("paths outside the base are kept", func(t *testing.T) {
+		if err := os.MkdirAll(filepath.Join(dir, "db", "other"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr, exitCode := runCommandOutputs(t, []string{"dir", "--oneline", "--relative-to", "db/other", absolute}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, stderr)
+		}
+		if !strings.HasPrefix(stdout, filepath.Join(absolute, "0001_init.sql")+":1:") {
+			t.Errorf("Expected the absolute path, got %q", stdout)
+		}
+	})
+
+	t.Run("stdin filename", func(t *testing.T) {
+		stdout, _, _ := runCommandOutputs(t, []string{"--oneline", "--relative-to", "db", "--stdin-filename", filepath.Join(absolute, "0002_users.sql")}, "SELECT 1;")
+		if !strings.HasPrefix(stdout, "migrations/0002_users.sql:1:") {
+			t.Errorf("Expected the stdin filename relative to db, got %q", stdout)
+		}
+	})
+}
+
+func TestRelativeToErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.sql")
+	if err := os.WriteFile(file, []byte("SELECT 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		base      string
+		wantError string
+	}{
+		{name: "missing directory", base: filepath.Join(t.TempDir(), "missing"), wantError: "invalid --relative-to"},
+		{name: "file", base: file, wantError: "invalid --relative-to " + file + ": not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, exitCode := runCommandOutputs(t, []string{"--relative-to", tt.base, "SELECT 1"}, "")
+			if exitCode != 1 || !strings.Contains(stderr, tt.wantError) {
+				t.Errorf("Expected exit 1 with %q, got %d: %s", tt.wantError, exitCode, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// changedSQLFiles lists .sql files under the current directory that were added, copied, modified or
+// renamed since the merge base of ref and HEAD, including uncommitted and untracked files
+func changedSQLFiles(ref string) ([]string, error) {
+	if _, err := runGit("rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("--since requires a git repository: %w", err)
+	}
+
+	base, err := runGit("merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("--since %s: %w", ref, err)
+	}
+	base = strings.TrimSpace(base)
+
+	changed, err := runGit("diff", "--name-only", "-z", "--relative", "--diff-filter=ACMR", base, "--", "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("--since %s: %w", ref, err)
+	}
+	untracked, err := runGit("ls-files", "-z", "--others", "--exclude-standard", "--", "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("--since %s: %w", ref, err)
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range strings.Split(changed+untracked, "\x00") {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// runGit runs git in the current directory and returns its stdout, or stderr as the error
+func runGit(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// analyzeFiles parses and analyzes each file on its own, so transaction state does not carry over
+// from one migration to the next unless crossFile is set, and merges the statements and results in file order
+func analyzeFiles(a analyzer.Analyzer, files []string, mode analyzer.TransactionMode, crossFile bool) (*parser.ParseResult, []*analyzer.Result, error) {
+	p := parser.NewParser()
+	merged := &parser.ParseResult{}
+	var results []*analyzer.Result
+
+	for _, file := range files {
+		sql, err := readFileInput(file, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := reportedPath(file)
+
+		var parsed *parser.ParseResult
+		if continueOnParseError {
+			parsed, err = p.ParseSQLWithRecovery(sql)
+		} else {
+			parsed, err = p.ParseSQL(sql)
+		}
+		if err != nil {
+			var stmtErr parser.StatementError
+			if errors.As(err, &stmtErr) {
+				stmtErr.File = name
+				err = stmtErr
+			}
+			return nil, nil, fmt.Errorf("parse error: %s: %w", name, err)
+		}
+
+		for i := range parsed.Statements {
+			parsed.Statements[i].File = name
+		}
+		for i := range parsed.Errors {
+			parsed.Errors[i].File = name
+			parsed.Errors[i].Err = fmt.Errorf("%s: %w", name, parsed.Errors[i].Err)
+		}
+
+		merged.Statements = append(merged.Statements, parsed.Statements...)
+		merged.Errors = append(merged.Errors, parsed.Errors...)
+		if crossFile {
+			continue
+		}
+
+		fileResults, err := a.Analyze(parsed, mode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("analysis error: %s: %w", name, err)
+		}
+		results = append(results, fileResults...)
+	}
+
+	// The files run as one script, so analyze them together
+	if crossFile {
+		var err error
+		results, err = a.Analyze(merged, mode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("analysis error: %w", err)
+		}
+	}
+
+	return merged, results, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initGitRepo creates a git repository in a temporary directory and changes into it
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	t.Chdir(dir)
+	gitRun(t, "init", "-q")
+	return dir
+}
+
+// gitRun runs git with a fixed identity in the current directory
+func gitRun(t *testing.T, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// writeMigration writes a migration file relative to the current directory
+func writeMigration(t *testing.T, path, sql string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(sql), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSince(t *testing.T) {
+	initGitRepo(t)
+	writeMigration(t, "migrations/001_init.sql", "CREATE TABLE users (id int);\nDELETE FROM users;\n")
+	gitRun(t, "add", ".")
+	gitRun(t, "commit", "-q", "-m", "init")
+	gitRun(t, "tag", "base")
+
+	writeMigration(t, "migrations/002_index.sql", "CREATE INDEX idx_users_id ON users (id);\n")
+	writeMigration(t, "notes.txt", "not SQL\n")
+	gitRun(t, "add", ".")
+	gitRun(t, "commit", "-q", "-m", "add index")
+	writeMigration(t, "migrations/003_untracked.sql", "SELECT 1;\n")
+
+	output, exitCode := runCommand(t, []string{"--since", "base", "--oneline"}, "")
+	if exitCode != 0 {
+		t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+	}
+
+	want := "migrations/002_index.sql:1: CRITICAL CREATE INDEX [users:Share]\nmigrations/003_untracked.sql:1: INFO SELECT\n"
+	if output != want {
+		t.Errorf("Output = %q, want %q", output, want)
+	}
+}
+
+func TestSinceErrors(t *testing.T) {
+	t.Run("outside a git repository", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+		t.Chdir(dir)
+
+		_, stderr, exitCode := runCommandOutputs(t, []string{"--since", "main"}, "")
+		if exitCode != 1 || !strings.Contains(stderr, "--since requires a git repository") {
+			t.Errorf("Expected exit 1 with git repository error, got %d: %s", exitCode, stderr)
+		}
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		initGitRepo(t)
+		writeMigration(t, "001_init.sql", "SELECT 1;\n")
+		gitRun(t, "add", ".")
+		gitRun(t, "commit", "-q", "-m", "init")
+
+		_, stderr, exitCode := runCommandOutputs(t, []string{"--since", "no-such-ref"}, "")
+		if exitCode != 1 || !strings.Contains(stderr, "--since no-such-ref") {
+			t.Errorf("Expected exit 1 with ref error, got %d: %s", exitCode, stderr)
+		}
+	})
+
+	t.Run("combined with SQL input", func(t *testing.T) {
+		_, stderr, exitCode := runCommandOutputs(t, []string{"--since", "main", "SELECT 1"}, "")
+		if exitCode != 1 || !strings.Contains(stderr, "cannot be combined with SQL input") {
+			t.Errorf("Expected exit 1 with combination error, got %d: %s", exitCode, stderr)
+		}
+	})
+}
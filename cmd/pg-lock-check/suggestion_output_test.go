@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -637,3 +639,186 @@ func TestSuggestionOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestSuggestFor(t *testing.T) {
+	sql := "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id)"
+
+	t.Run("default shows no suggestion for WARNING", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		if !strings.Contains(output, "[WARNING]") || strings.Contains(output, "Suggestion for safe migration") {
+			t.Errorf("Expected a WARNING without suggestion, got:\n%s", output)
+		}
+	})
+
+	t.Run("warning threshold shows the suggestion", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--suggest-for", "warning", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		for _, want := range []string{
+			"Suggestion for safe migration:",
+			"ALTER TABLE orders ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID;",
+			"ALTER TABLE orders VALIDATE CONSTRAINT orders_user_id_fkey;",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+	})
+
+	t.Run("JSON output includes the suggestion", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"-o", "json", "--suggest-for", "all", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		if len(result.Results) != 1 || result.Results[0].Suggestion == nil || len(result.Results[0].Suggestion.Steps) != 2 {
+			t.Errorf("Expected a 2-step suggestion, got %+v", result.Results)
+		}
+	})
+
+	t.Run("other WARNING templates need the warning threshold", func(t *testing.T) {
+		for _, warning := range []string{
+			"ALTER TABLE accounts ENABLE ROW LEVEL SECURITY",
+			"ALTER TABLE events ATTACH PARTITION events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+		} {
+			for _, threshold := range []string{"critical", "warning"} {
+				output, _ := runCommand(t, []string{"-o", "json", "--suggest-for", threshold, warning}, "")
+				var result Output
+				if err := json.Unmarshal([]byte(output), &result); err != nil {
+					t.Fatalf("Output is not valid JSON: %v", err)
+				}
+				if len(result.Results) != 1 || result.Results[0].Severity != "WARNING" ||
+					(result.Results[0].Suggestion != nil) != (threshold == "warning") {
+					t.Errorf("%s with --suggest-for %s: expected a WARNING with a suggestion only for warning, got %+v", warning, threshold, result.Results)
+				}
+			}
+		}
+	})
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		_, stderr, exitCode := runCommandOutputs(t, []string{"--suggest-for", "sometimes", sql}, "")
+		if exitCode != 1 || !strings.Contains(stderr, `invalid --suggest-for "sometimes"`) {
+			t.Errorf("Expected exit 1 with threshold error, got %d: %s", exitCode, stderr)
+		}
+	})
+}
+
+func TestOnlyCriticalSuggestions(t *testing.T) {
+	sql := "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id);\nCREATE INDEX idx_orders_user_id ON orders (user_id);"
+
+	t.Run("hides WARNING suggestions shown by --suggest-for", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"-o", "json", "--suggest-for", "warning", "--only-critical-suggestions", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		if len(result.Results) != 2 {
+			t.Fatalf("Expected 2 results, got %+v", result.Results)
+		}
+		if result.Results[0].Severity != "WARNING" || result.Results[0].Suggestion != nil {
+			t.Errorf("Expected a WARNING without suggestion, got %+v", result.Results[0])
+		}
+		if result.Results[1].Severity != "CRITICAL" || result.Results[1].Suggestion == nil {
+			t.Errorf("Expected a CRITICAL with suggestion, got %+v", result.Results[1])
+		}
+	})
+
+	t.Run("--no-suggestion wins", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--only-critical-suggestions", "--no-suggestion", sql}, "")
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		if !strings.Contains(output, "[CRITICAL]") || strings.Contains(output, "Suggestion for safe migration") {
+			t.Errorf("Expected a CRITICAL without suggestion, got:\n%s", output)
+		}
+	})
+}
+
+func TestSuggestionIsPartial(t *testing.T) {
+	tests := []struct {
+		name        string
+		sql         string
+		wantPartial bool
+	}{
+		{"CLUSTER suggests pg_repack", "CLUSTER users USING idx_users_id", true},
+		{"CREATE INDEX suggests CONCURRENTLY", "CREATE INDEX idx_users_email ON users (email)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, exitCode := runCommand(t, []string{"-o", "json", tt.sql}, "")
+			if exitCode != 0 {
+				t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+			}
+			var result Output
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("Output is not valid JSON: %v", err)
+			}
+			if len(result.Results) != 1 || result.Results[0].Suggestion == nil {
+				t.Fatalf("Expected a result with a suggestion, got %+v", result.Results)
+			}
+			if result.Results[0].Suggestion.IsPartial != tt.wantPartial {
+				t.Errorf("Expected is_partial %v, got %v", tt.wantPartial, result.Results[0].Suggestion.IsPartial)
+			}
+			if !strings.Contains(output, `"is_partial":`) {
+				t.Errorf("Expected is_partial in the JSON output, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestQuotedReindexRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		wantTable string
+		wantSQL   string
+	}{
+		{
+			name:      "schema-qualified",
+			sql:       "REINDEX INDEX app.idx_users_email",
+			wantTable: "app.idx_users_email",
+			wantSQL:   "REINDEX INDEX CONCURRENTLY app.idx_users_email;",
+		},
+		{
+			name:      "quoted with a space",
+			sql:       `REINDEX INDEX "app"."My Index"`,
+			wantTable: `app."My Index"`,
+			wantSQL:   `REINDEX INDEX CONCURRENTLY app."My Index";`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, exitCode := runCommand(t, []string{"-o", "json", tt.sql}, "")
+			if exitCode != 0 {
+				t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+			}
+			var result Output
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("Output is not valid JSON: %v", err)
+			}
+			if len(result.Results) != 1 || result.Results[0].Suggestion == nil {
+				t.Fatalf("Expected one result with a suggestion, got %+v", result.Results)
+			}
+
+			res := result.Results[0]
+			if len(res.Tables) != 1 || res.Tables[0].Name != tt.wantTable {
+				t.Errorf("Expected the lock on %s, got %+v", tt.wantTable, res.Tables)
+			}
+			if got := strings.TrimSpace(res.Suggestion.Steps[0].Output); got != tt.wantSQL {
+				t.Errorf("Suggestion = %q, want %q", got, tt.wantSQL)
+			}
+		})
+	}
+}
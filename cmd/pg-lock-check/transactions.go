@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nnaka2992/pg-lock-check/internal/analyzer"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// OutputTransaction is the --transaction-summary entry of one explicit transaction block
+type OutputTransaction struct {
+	File      string `json:"file,omitempty" yaml:"file,omitempty"`
+	StartLine int    `json:"start_line" yaml:"start_line"`
+	EndLine   int    `json:"end_line" yaml:"end_line"`
+	// End is COMMIT or ROLLBACK, or "end of input" for a block that is never closed
+	End      string      `json:"end" yaml:"end"`
+	LockType string      `json:"lock_type,omitempty" yaml:"lock_type,omitempty"`
+	Tables   []TableLock `json:"tables" yaml:"tables"`
+}
+
+// buildTransactions summarizes the transaction blocks of all statements, before any filtering.
+// Files analyzed one by one have their own transactions, as a block left open ends with its file.
+func buildTransactions(parsed *parser.ParseResult, results []*analyzer.Result, crossFile bool) []OutputTransaction {
+	transactions := []OutputTransaction{}
+	for start := 0; start < len(results); {
+		end := start + 1
+		for end < len(results) && (crossFile || statementFile(parsed, end) == statementFile(parsed, start)) {
+			end++
+		}
+
+		for _, transaction := range analyzer.Transactions(results[start:end]) {
+			output := OutputTransaction{
+				File:      statementFile(parsed, start+transaction.Start),
+				StartLine: statementLine(parsed, start+transaction.Start),
+				EndLine:   statementLine(parsed, start+transaction.End),
+				End:       transaction.EndOperation,
+				LockType:  string(transaction.LockType),
+				Tables:    transactionTableLocks(transaction),
+			}
+			if output.End == "" {
+				output.End = "end of input"
+			}
+			transactions = append(transactions, output)
+		}
+		start = end
+	}
+	return transactions
+}
+
+// transactionTableLocks lists the tables a transaction block locks, strongest lock first
+func transactionTableLocks(transaction analyzer.Transaction) []TableLock {
+	tables := make([]TableLock, 0, len(transaction.Tables))
+	for _, table := range transaction.Tables {
+		tables = append(tables, TableLock{Name: table, LockType: string(transaction.TableLockTypes[table])})
+	}
+	return tables
+}
+
+// statementFile returns the file of the statement behind a result, or "" for unlabeled input
+func statementFile(parsed *parser.ParseResult, index int) string {
+	if index < len(parsed.Statements) {
+		return parsed.Statements[index].File
+	}
+	return ""
+}
+
+// statementIndex returns the input position of the statement behind a result, which differs
+// from its position in the results once the baseline or filter dropped earlier statements
+func statementIndex(parsed *parser.ParseResult, index int) int {
+	if index < len(parsed.Statements) {
+		return parsed.Statements[index].Index
+	}
+	return index
+}
+
+// statementLine returns the line of the statement behind a result
+func statementLine(parsed *parser.ParseResult, index int) int {
+	if index < len(parsed.Statements) {
+		return parsed.Statements[index].LineNumber
+	}
+	return 1
+}
+
+// outputTextTransactions prints a block per transaction for --transaction-summary: a header with
+// its lines and the strongest lock, then the strongest lock held on each table
+func outputTextTransactions() {
+	if !transactionSummaryFlag {
+		return
+	}
+	if len(transactionSummaries) == 0 {
+		fmt.Println("\nTransactions: none")
+		return
+	}
+
+	for _, transaction := range transactionSummaries {
+		lines := fmt.Sprintf("%d-%d", transaction.StartLine, transaction.EndLine)
+		location := "at lines " + lines
+		if transaction.StartLine == transaction.EndLine {
+			lines = fmt.Sprint(transaction.StartLine)
+			location = "at line " + lines
+		}
+		if transaction.File != "" {
+			location = "at " + transaction.File + ":" + lines
+		}
+		lockType := transaction.LockType
+		if lockType == "" {
+			lockType = "no table locks"
+		}
+		fmt.Printf("\n== Transaction %s (until %s): %s ==\n", location, transaction.End, lockType)
+		for _, table := range transaction.Tables {
+			fmt.Printf("  %s: %s\n", table.Name, table.LockType)
+		}
+	}
+}
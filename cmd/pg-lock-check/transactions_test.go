@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransactionSummary(t *testing.T) {
+	sql := "SELECT 1;\nBEGIN;\nUPDATE users SET active = true WHERE id = 1;\nALTER TABLE users ADD COLUMN nickname text;\n" +
+		"INSERT INTO orders (id) VALUES (1);\nCOMMIT;\nBEGIN;\nSELECT * FROM accounts;\n"
+
+	t.Run("json", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"-o", "json", "--no-transaction", "--transaction-summary"}, sql)
+		if exitCode != 0 {
+			t.Fatalf("Expected exit 0, got %d: %s", exitCode, output)
+		}
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+
+		want := []OutputTransaction{
+			{
+				StartLine: 2,
+				EndLine:   6,
+				End:       "COMMIT",
+				LockType:  "AccessExclusive",
+				Tables:    []TableLock{{Name: "users", LockType: "AccessExclusive"}, {Name: "orders", LockType: "RowExclusive"}},
+			},
+			{
+				StartLine: 7,
+				EndLine:   8,
+				End:       "end of input",
+				LockType:  "AccessShare",
+				Tables:    []TableLock{{Name: "accounts", LockType: "AccessShare"}},
+			},
+		}
+		if !reflect.DeepEqual(result.Transactions, want) {
+			t.Errorf("Transactions = %+v, want %+v", result.Transactions, want)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"--no-transaction", "--no-suggestion", "--transaction-summary"}, sql)
+		want := "\n== Transaction at lines 2-6 (until COMMIT): AccessExclusive ==\n  users: AccessExclusive\n  orders: RowExclusive\n" +
+			"\n== Transaction at lines 7-8 (until end of input): AccessShare ==\n  accounts: AccessShare\n"
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected the transaction blocks %q, got:\n%s", want, output)
+		}
+	})
+
+	t.Run("table name containing a colon", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"-o", "json", "--no-transaction", "--transaction-summary"}, "BEGIN;\nLOCK TABLE \"a:b\";\nCOMMIT;")
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		want := []TableLock{{Name: `"a:b"`, LockType: "AccessExclusive"}}
+		if len(result.Transactions) != 1 || !reflect.DeepEqual(result.Transactions[0].Tables, want) {
+			t.Errorf("Transactions = %+v, want one block locking %+v", result.Transactions, want)
+		}
+	})
+
+	t.Run("filtered results keep the whole block", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"-o", "json", "--no-transaction", "--include-operations", "UPDATE*", "--transaction-summary"}, sql)
+		var result Output
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Output is not valid JSON: %v", err)
+		}
+		if len(result.Results) != 1 || len(result.Transactions) != 2 || result.Transactions[0].LockType != "AccessExclusive" {
+			t.Errorf("Expected one result and both transactions, got %+v", result)
+		}
+	})
+
+	t.Run("without --transaction-summary", func(t *testing.T) {
+		output, _ := runCommand(t, []string{"-o", "json", "--no-transaction"}, sql)
+		if strings.Contains(output, `"transactions"`) {
+			t.Errorf("Expected no transactions without --transaction-summary, got %s", output)
+		}
+	})
+
+	t.Run("blocks end with their file", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "001_open.sql"), []byte("BEGIN;\nTRUNCATE logs;\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "002_next.sql"), []byte("SELECT * FROM users;\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		output, _ := runCommand(t, []string{"dir", dir, "--no-suggestion", "--transaction-summary"}, "")
+		want := "== Transaction at " + filepath.Join(dir, "001_open.sql") + ":1-2 (until end of input): AccessExclusive ==\n  logs: AccessExclusive\n"
+		if !strings.Contains(output, want) || strings.Contains(output, "users: AccessShare") {
+			t.Errorf("Expected the block to end with 001_open.sql, got:\n%s", output)
+		}
+	})
+
+	t.Run("oneline", func(t *testing.T) {
+		output, exitCode := runCommand(t, []string{"--oneline", "--transaction-summary", "SELECT 1"}, "")
+		if exitCode != 1 || !strings.Contains(output, "--transaction-summary cannot be combined with --oneline") {
+			t.Errorf("Expected an error for --oneline, got exit %d: %s", exitCode, output)
+		}
+	})
+}
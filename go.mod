@@ -5,11 +5,9 @@ go 1.24.1
 require (
 	github.com/pganalyze/pg_query_go/v6 v6.1.0
 	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
+	google.golang.org/protobuf v1.31.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
-	google.golang.org/protobuf v1.31.0 // indirect
-)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
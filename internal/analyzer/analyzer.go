@@ -2,6 +2,8 @@ package analyzer
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/nnaka2992/pg-lock-check/internal/parser"
@@ -17,17 +19,85 @@ type Analyzer interface {
 	Analyze(parsed *parser.ParseResult, mode TransactionMode) ([]*Result, error)
 }
 
+// Options enables optional, advisory checks that span multiple statements
+type Options struct {
+	// CheckStatementTimeout notes strong-lock statements that run with statement_timeout
+	// disabled or set very large by an earlier SET
+	CheckStatementTimeout bool
+	// LongTransactionThreshold notes AccessExclusive statements inside a transaction that are
+	// followed by at least this many statements before the transaction ends; 0 disables the check
+	LongTransactionThreshold int
+	// CheckDDLDMLMix notes transactions that combine an AccessExclusive schema change with a
+	// full-table UPDATE, DELETE, MERGE or INSERT ... SELECT
+	CheckDDLDMLMix bool
+	// IgnoreTransactionControl analyzes every statement in the mode passed to Analyze, without
+	// following BEGIN/COMMIT/ROLLBACK, as for tools that wrap the whole input in one transaction
+	// (InTransaction) or run each statement on its own (NoTransaction)
+	IgnoreTransactionControl bool
+	// PartitionedTables names tables known to be partitioned, in addition to those created
+	// with PARTITION BY earlier in the input
+	PartitionedTables []string
+	// TreatIfExistsAsWarning reports DROP TABLE IF EXISTS as WARNING instead of CRITICAL,
+	// for teardown scripts where the drop is intended
+	TreatIfExistsAsWarning bool
+	// LargeInsertRows notes INSERT ... VALUES statements with at least this many rows; 0 disables the check
+	LargeInsertRows int
+	// PublishedTables names tables known to be in a logical replication publication, in addition
+	// to those added by CREATE PUBLICATION or ALTER PUBLICATION earlier in the input
+	PublishedTables []string
+	// TableOptions holds the current storage parameters of tables, as in pg_class.reloptions; a listed
+	// table has exactly these parameters set. Tables created earlier in the input are tracked as well.
+	TableOptions map[string]map[string]string
+	// SchemaObjects counts the objects of each kind in a schema (e.g. "tables": 12), to list what
+	// DROP SCHEMA ... CASCADE removes
+	SchemaObjects map[string]map[string]int
+	// MaxLockLevel is the strongest lock a statement may take under the team's policy; statements
+	// taking a stronger lock on any table are noted and raised to at least CRITICAL. Empty disables the check.
+	MaxLockLevel LockType
+	// Verbose adds notes that only set expectations, such as how a SET search_path affects later
+	// statements, without pointing at a risk
+	Verbose bool
+}
+
 // analyzer is the main implementation of the Analyzer interface
 type analyzer struct {
 	registry         *operationRegistry
+	options          Options
 	transactionDepth int // Track nesting level of transactions
+	// Partitioned tables from the options and from CREATE TABLE ... PARTITION BY seen so far
+	partitionedTables map[string]bool
+	// Published tables from the options and from publications seen so far; allTablesPublished
+	// is set by CREATE PUBLICATION ... FOR ALL TABLES
+	publishedTables    map[string]bool
+	allTablesPublished bool
+	// Storage parameters of tables from the options and from CREATE/ALTER TABLE seen so far
+	tableOptions map[string]map[string]string
+	// Routines created so far whose body runs statements that cannot run inside them
+	routines map[string][]string
+	// Expected row counts from pg-lock-check:rows comments seen so far
+	tableRows map[string]tableRows
+	// Statements prepared so far, by name
+	prepared map[string]preparedStatement
 }
 
 // New creates a new analyzer instance
 func New() Analyzer {
-	return &analyzer{
-		registry: newOperationRegistry(),
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a new analyzer instance with optional checks enabled
+func NewWithOptions(options Options) Analyzer {
+	a := &analyzer{
+		registry:  newOperationRegistry(),
+		options:   options,
+		routines:  make(map[string][]string),
+		tableRows: make(map[string]tableRows),
+		prepared:  make(map[string]preparedStatement),
 	}
+	a.resetPartitionedTables()
+	a.resetPublishedTables()
+	a.resetTableOptions()
+	return a
 }
 
 // AnalyzeStatement analyzes a single parsed statement
@@ -37,21 +107,30 @@ func (a *analyzer) AnalyzeStatement(stmt parser.ParsedStatement, mode Transactio
 			Severity:  SeverityInfo,
 			operation: "UNKNOWN",
 			lockType:  AccessShare,
+			scope:     ScopeObject,
+
+			recommendedMode: RecommendedModeEither,
+			unrecognized:    true,
 		}, nil
 	}
 
-	// Get the first statement node from the AST
-	stmtNode := stmt.AST.Stmts[0].Stmt
+	// Get the first statement node from the AST; PREPARE and EXECUTE of a statement prepared
+	// earlier are analyzed as the prepared statement
+	stmtNode, sql, preparedQualifier, preparedNote := a.preparedTarget(stmt.AST.Stmts[0].Stmt, stmt.SQL)
 
 	// Analyze the AST node to determine operation type and details
 	opInfo := a.analyzeNode(stmtNode, mode)
 	if opInfo == nil {
 		return nil, fmt.Errorf("unsupported SQL operation")
 	}
+	if preparedQualifier != "" {
+		opInfo.qualifiers = append(opInfo.qualifiers, preparedQualifier)
+		opInfo.notes = append(opInfo.notes, preparedNote)
+	}
 
-	// Special handling for MERGE to detect WHERE conditions
-	if opInfo.operation == "MERGE without WHERE" {
-		upperSQL := strings.ToUpper(stmt.SQL)
+	// Special handling for MERGE to detect WHERE conditions; an unconditional DELETE branch is not narrowed by them
+	if opInfo.operation == "MERGE without WHERE" && !opInfo.unfilteredDelete {
+		upperSQL := strings.ToUpper(sql)
 		// MERGE is considered "with WHERE" if:
 		// 1. It has additional conditions in WHEN clause (AND after MATCHED)
 		// 2. It uses a subquery/CTE in USING clause (targeted merge)
@@ -62,12 +141,27 @@ func (a *analyzer) AnalyzeStatement(stmt parser.ParsedStatement, mode Transactio
 	}
 
 	// Special handling for DETACH PARTITION CONCURRENTLY
-	if opInfo.operation == "ALTER TABLE DETACH PARTITION" && strings.Contains(strings.ToUpper(stmt.SQL), "CONCURRENTLY") {
+	if opInfo.operation == "ALTER TABLE DETACH PARTITION" && strings.Contains(strings.ToUpper(sql), "CONCURRENTLY") {
 		opInfo.operation = "ALTER TABLE DETACH PARTITION CONCURRENTLY"
 	}
 
 	// Get severity and lock information from the registry
 	severity, lockType := a.registry.getSeverityAndLock(opInfo.operation, mode)
+	if lockLevel(opInfo.statementLock) > lockLevel(lockType) {
+		lockType = opInfo.statementLock
+	}
+
+	// Node analyzers set errorReason for statements PostgreSQL rejects whatever the registry says:
+	// routine bodies in any mode, and CONCURRENTLY on partitioned tables or system catalogs only
+	// outside a transaction block, where the registry does not already report ERROR
+	if opInfo.errorReason != ErrorReasonNone {
+		severity = SeverityError
+	}
+
+	// Guarded drops are only downgraded on request; the "if exists" qualifier marks them either way
+	if a.options.TreatIfExistsAsWarning && opInfo.operation == "DROP TABLE" && slices.Contains(opInfo.qualifiers, "if exists") && severity == SeverityCritical {
+		severity = SeverityWarning
+	}
 
 	// Extract table information with context-aware locks
 	tableLocksMap := extractTablesWithContext(stmtNode)
@@ -105,15 +199,68 @@ func (a *analyzer) AnalyzeStatement(stmt parser.ParsedStatement, mode Transactio
 		tableLocks = append(tableLocks, formatTableLock(table, lockType))
 	}
 
+	scope := classifyScope(opInfo, len(tableLocks) > 0)
+	notes := opInfo.notes
+	if severity == SeverityCritical {
+		if note := a.smallTableNote(opInfo.operation, tableLocksMap, lockType); note != "" {
+			notes = append(slices.Clip(notes), note)
+			severity = SeverityWarning
+		}
+	}
+	if note := a.maxLockLevelNote(tableLocksMap, scope, lockType); note != "" {
+		notes = append(slices.Clip(notes), note)
+		severity = max(severity, SeverityCritical)
+	}
+
+	// Registry ERRORs are all operations that cannot run inside a transaction block
+	errorReason := opInfo.errorReason
+	if severity == SeverityError && errorReason == ErrorReasonNone {
+		errorReason = ErrorReasonCannotRunInTransaction
+	}
+
 	return &Result{
-		Severity:   severity,
-		operation:  opInfo.operation,
-		lockType:   lockType,
-		tableLocks: tableLocks,
-		message:    opInfo.message,
+		Severity:    severity,
+		operation:   opInfo.operation,
+		lockType:    lockType,
+		qualifiers:  opInfo.qualifiers,
+		scope:       scope,
+		tableLocks:  tableLocks,
+		lockTypes:   tableLocksMap,
+		allLocks:    acquisitionOrder(tableLocksMap, lockType),
+		notes:       notes,
+		errorReason: errorReason,
+
+		recommendedMode: a.registry.recommendedMode(opInfo.operation),
+		unrecognized:    opInfo.unrecognized,
+
+		statement: stmtNode,
 	}, nil
 }
 
+// maxLockLevelNote notes a statement whose strongest lock on an existing table exceeds
+// Options.MaxLockLevel. Only table locks count, so creating or renaming schemas, types and other
+// objects that lock no table passes any ceiling. Database-wide operations such as VACUUM FULL or
+// REINDEX DATABASE name no table but lock every one, so their statement lock counts as well.
+func (a *analyzer) maxLockLevelNote(tableLocks map[string]LockType, scope Scope, lockType LockType) string {
+	if a.options.MaxLockLevel == "" {
+		return ""
+	}
+
+	var strongest LockType
+	if scope == ScopeDatabase {
+		strongest = lockType
+	}
+	for _, lock := range tableLocks {
+		if lockLevel(lock) > lockLevel(strongest) {
+			strongest = lock
+		}
+	}
+	if lockLevel(strongest) <= lockLevel(a.options.MaxLockLevel) {
+		return ""
+	}
+	return fmt.Sprintf("%s lock is stronger than the %s maximum allowed by policy", strongest, a.options.MaxLockLevel)
+}
+
 // Analyze analyzes all statements in a parsed result
 func (a *analyzer) Analyze(parsed *parser.ParseResult, mode TransactionMode) ([]*Result, error) {
 	results := make([]*Result, 0, len(parsed.Statements))
@@ -126,6 +273,16 @@ func (a *analyzer) Analyze(parsed *parser.ParseResult, mode TransactionMode) ([]
 		a.transactionDepth = 1
 	}
 
+	// Only partitioned and published tables and storage parameters from the options carry over between inputs
+	a.resetPartitionedTables()
+	a.resetPublishedTables()
+	a.resetTableOptions()
+	a.routines = make(map[string][]string)
+	a.tableRows = make(map[string]tableRows)
+	a.prepared = make(map[string]preparedStatement)
+
+	state := &transactionState{}
+
 	for _, stmt := range parsed.Statements {
 		// Determine the effective mode based on transaction depth
 		effectiveMode := NoTransaction
@@ -133,17 +290,47 @@ func (a *analyzer) Analyze(parsed *parser.ParseResult, mode TransactionMode) ([]
 			effectiveMode = InTransaction
 		}
 
+		// Row counts given in the statement's comments apply to it and later statements
+		directiveNotes := a.recordTableRows(stmt)
+
 		result, err := a.AnalyzeStatement(stmt, effectiveMode)
 		if err != nil {
 			return nil, err
 		}
+		result.notes = append(result.notes, directiveNotes...)
+
+		// Apply checks that depend on earlier statements; when transaction control is ignored,
+		// BEGIN/COMMIT/ROLLBACK neither start nor end the transaction the checks track
+		if !a.options.IgnoreTransactionControl || !isTransactionControl(result.BaseOperation()) {
+			a.applyTransactionChecks(state, stmt, result, effectiveMode)
+		}
 
 		// Update transaction depth based on the operation
-		a.updateTransactionDepth(result.Operation())
+		if !a.options.IgnoreTransactionControl {
+			a.updateTransactionDepth(result.Operation())
+		}
+
+		// Later CREATE INDEX statements on this table recurse into its partitions
+		a.recordPartitionedTable(stmt)
+
+		// Later ALTER TABLE statements on these tables are noted for subscribers
+		a.recordPublishedTables(stmt)
+
+		// Later ALTER TABLE SET/RESET statements are compared with these storage parameters
+		a.recordTableOptions(stmt)
+
+		// Later CALLs of this procedure fail at the same statements as its body
+		a.recordRoutine(stmt)
+
+		// Later EXECUTEs of this name run the prepared statement
+		a.recordPrepared(stmt)
 
 		results = append(results, result)
 	}
 
+	// Locks taken in a transaction that is never closed are held until the end of input
+	state.endTransaction("the end of input")
+
 	return results, nil
 }
 
@@ -151,17 +338,31 @@ func (a *analyzer) Analyze(parsed *parser.ParseResult, mode TransactionMode) ([]
 type operationInfo struct {
 	operation string
 	tableLock LockType
-	message   string
+	// Qualifiers refine the operation label without changing its registry lookup
+	qualifiers []string
+	notes      []string
+	// Scope overrides the operation-based scope classification
+	scope Scope
 	// Additional table locks for multi-table operations
 	additionalTableLocks map[string]LockType
+	// ErrorReason makes the operation ERROR regardless of the registry, e.g. for a routine body that cannot run
+	errorReason ErrorReason
+	// StatementLock raises the registry lock of the operation, e.g. to the strongest lock of any
+	// action of a multi-action ALTER TABLE
+	statementLock LockType
+	// UnfilteredDelete marks a MERGE whose unconditional DELETE branch keeps it "without WHERE"
+	unfilteredDelete bool
+	// Unrecognized marks a generic fallback for a statement not classified specifically, e.g. DROP CONVERSION
+	unrecognized bool
 }
 
 // analyzeNode analyzes an AST node to determine the operation type
 func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *operationInfo {
 	if node == nil {
 		return &operationInfo{
-			operation: "UNKNOWN",
-			tableLock: AccessShare,
+			operation:    "UNKNOWN",
+			tableLock:    AccessShare,
+			unrecognized: true,
 		}
 	}
 
@@ -183,7 +384,7 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 	case *pg_query.Node_CreateStmt:
 		return a.analyzeCreate(n.CreateStmt)
 	case *pg_query.Node_AlterTableStmt:
-		return a.analyzeAlterTable(n.AlterTableStmt)
+		return a.analyzeAlterTable(n.AlterTableStmt, mode)
 	case *pg_query.Node_AlterObjectSchemaStmt:
 		return a.analyzeAlterObjectSchema(n.AlterObjectSchemaStmt)
 	case *pg_query.Node_RenameStmt:
@@ -195,9 +396,9 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 
 	// DDL Operations - Indexes
 	case *pg_query.Node_IndexStmt:
-		return a.analyzeIndex(n.IndexStmt)
+		return a.analyzeIndex(n.IndexStmt, mode)
 	case *pg_query.Node_ReindexStmt:
-		return a.analyzeReindex(n.ReindexStmt)
+		return a.analyzeReindex(n.ReindexStmt, mode)
 
 	// DDL Operations - Views
 	case *pg_query.Node_ViewStmt:
@@ -304,6 +505,24 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 	case *pg_query.Node_LoadStmt:
 		return a.analyzeLoad(n.LoadStmt)
 
+	// Asynchronous Notifications
+	case *pg_query.Node_ListenStmt:
+		return &operationInfo{
+			operation: "LISTEN",
+			tableLock: AccessShare,
+		}
+	case *pg_query.Node_NotifyStmt:
+		return &operationInfo{
+			operation: "NOTIFY",
+			tableLock: AccessShare,
+			notes:     []string{"the notification and its payload are delivered to listeners only when the transaction commits"},
+		}
+	case *pg_query.Node_UnlistenStmt:
+		return &operationInfo{
+			operation: "UNLISTEN",
+			tableLock: AccessShare,
+		}
+
 	// Replication
 	case *pg_query.Node_CreateSubscriptionStmt:
 		return a.analyzeCreateSubscription(n.CreateSubscriptionStmt)
@@ -317,6 +536,11 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 	// Comments
 	case *pg_query.Node_CommentStmt:
 		return a.analyzeComment(n.CommentStmt)
+	case *pg_query.Node_SecLabelStmt:
+		return &operationInfo{
+			operation: "SECURITY LABEL",
+			tableLock: AccessShare,
+		}
 
 	// Additional DDL Operations
 	case *pg_query.Node_CreateEnumStmt:
@@ -325,21 +549,17 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 			tableLock: AccessExclusive,
 		}
 	case *pg_query.Node_AlterDomainStmt:
-		return &operationInfo{
-			operation: "ALTER DOMAIN",
-			tableLock: AccessExclusive,
-		}
+		return a.analyzeAlterDomain(n.AlterDomainStmt)
 	case *pg_query.Node_CreateFunctionStmt:
-		// Check if it's a procedure
-		if n.CreateFunctionStmt.IsProcedure {
-			return &operationInfo{
-				operation: "CREATE PROCEDURE",
-				tableLock: AccessExclusive,
-			}
-		}
+		return a.analyzeCreateFunction(n.CreateFunctionStmt)
+	case *pg_query.Node_CallStmt:
+		return a.analyzeCall(n.CallStmt)
+	case *pg_query.Node_ExecuteStmt:
+		return a.analyzeExecute(n.ExecuteStmt)
+	case *pg_query.Node_DeallocateStmt:
 		return &operationInfo{
-			operation: "CREATE FUNCTION",
-			tableLock: AccessExclusive,
+			operation: "DEALLOCATE",
+			tableLock: AccessShare,
 		}
 	case *pg_query.Node_DefineStmt:
 		return a.analyzeDefine(n.DefineStmt)
@@ -358,6 +578,16 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 			operation: "CREATE CAST",
 			tableLock: AccessExclusive,
 		}
+	case *pg_query.Node_CreateAmStmt:
+		return &operationInfo{
+			operation: "CREATE ACCESS METHOD",
+			tableLock: AccessShare,
+		}
+	case *pg_query.Node_CreateTransformStmt:
+		return &operationInfo{
+			operation: "CREATE TRANSFORM",
+			tableLock: AccessShare,
+		}
 	case *pg_query.Node_CreateFdwStmt:
 		return &operationInfo{
 			operation: "CREATE FOREIGN DATA WRAPPER",
@@ -403,26 +633,132 @@ func (a *analyzer) analyzeNode(node *pg_query.Node, mode TransactionMode) *opera
 	}
 }
 
+// isTransactionControl reports whether an operation starts or ends a transaction
+func isTransactionControl(operation string) bool {
+	return startsTransaction(operation) || endsTransaction(operation)
+}
+
+// startsTransaction reports whether an operation opens a transaction block
+func startsTransaction(operation string) bool {
+	return operation == "BEGIN" || operation == "START TRANSACTION"
+}
+
+// endsTransaction reports whether an operation ends a transaction block
+func endsTransaction(operation string) bool {
+	return operation == "COMMIT" || operation == "ROLLBACK"
+}
+
 // updateTransactionDepth updates the transaction depth based on the operation
 func (a *analyzer) updateTransactionDepth(operation string) {
-	switch operation {
-	case "BEGIN", "START TRANSACTION":
+	switch {
+	case startsTransaction(operation):
 		// In PostgreSQL, nested BEGIN doesn't actually create nested transactions
 		// but we'll track it to maintain consistency
 		if a.transactionDepth == 0 {
 			a.transactionDepth = 1
 		}
-	case "COMMIT", "ROLLBACK":
+	case endsTransaction(operation):
+		// ROLLBACK TO SAVEPOINT doesn't end the transaction
 		if a.transactionDepth > 0 {
 			a.transactionDepth--
 		}
-	case "ROLLBACK TO SAVEPOINT":
-		// ROLLBACK TO SAVEPOINT doesn't end the transaction
-		// Transaction depth remains the same
 	}
 }
 
+// resetPartitionedTables forgets partitioned tables other than those named in the options
+func (a *analyzer) resetPartitionedTables() {
+	a.partitionedTables = make(map[string]bool, len(a.options.PartitionedTables))
+	for _, table := range a.options.PartitionedTables {
+		a.partitionedTables[table] = true
+	}
+}
+
+// recordPartitionedTable remembers a table created with PARTITION BY
+func (a *analyzer) recordPartitionedTable(stmt parser.ParsedStatement) {
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+	create := stmt.AST.Stmts[0].Stmt.GetCreateStmt()
+	if create == nil || create.Partspec == nil || create.Relation == nil {
+		return
+	}
+	a.partitionedTables[getQualifiedTableName(create.Relation)] = true
+}
+
+// resetPublishedTables forgets published tables other than those named in the options
+func (a *analyzer) resetPublishedTables() {
+	a.publishedTables = make(map[string]bool, len(a.options.PublishedTables))
+	for _, table := range a.options.PublishedTables {
+		a.publishedTables[table] = true
+	}
+	a.allTablesPublished = false
+}
+
+// recordPublishedTables remembers tables added to a publication by CREATE PUBLICATION or
+// ALTER PUBLICATION ... ADD/SET TABLE
+func (a *analyzer) recordPublishedTables(stmt parser.ParsedStatement) {
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+
+	var pubobjects []*pg_query.Node
+	switch n := stmt.AST.Stmts[0].Stmt.Node.(type) {
+	case *pg_query.Node_CreatePublicationStmt:
+		if n.CreatePublicationStmt.ForAllTables {
+			a.allTablesPublished = true
+		}
+		pubobjects = n.CreatePublicationStmt.Pubobjects
+	case *pg_query.Node_AlterPublicationStmt:
+		if n.AlterPublicationStmt.Action == pg_query.AlterPublicationAction_AP_DropObjects {
+			// The table may still be in another publication
+			return
+		}
+		pubobjects = n.AlterPublicationStmt.Pubobjects
+	default:
+		return
+	}
+
+	for _, pubobj := range pubobjects {
+		spec := pubobj.GetPublicationObjSpec()
+		if spec == nil || spec.Pubtable == nil || spec.Pubtable.Relation == nil {
+			continue
+		}
+		a.publishedTables[getQualifiedTableName(spec.Pubtable.Relation)] = true
+	}
+}
+
+// isPublished reports whether a table is known to be in a logical replication publication
+func (a *analyzer) isPublished(relation *pg_query.RangeVar) bool {
+	return relation != nil && (a.allTablesPublished || a.publishedTables[getQualifiedTableName(relation)])
+}
+
 // formatTableLock formats a table lock for display
 func formatTableLock(tableName string, lockType LockType) string {
 	return tableName + ": " + string(lockType)
 }
+
+// acquisitionOrder formats table locks in the order PostgreSQL takes them: the tables under the
+// operation's own lock first, as the target is locked before the tables it references, then the
+// referenced tables from the strongest lock down, by name within the same lock
+func acquisitionOrder(tableLocks map[string]LockType, primary LockType) []string {
+	tables := make([]string, 0, len(tableLocks))
+	for table := range tableLocks {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		lockI, lockJ := tableLocks[tables[i]], tableLocks[tables[j]]
+		if (lockI == primary) != (lockJ == primary) {
+			return lockI == primary
+		}
+		if lockLevel(lockI) != lockLevel(lockJ) {
+			return lockLevel(lockI) > lockLevel(lockJ)
+		}
+		return tables[i] < tables[j]
+	})
+
+	locks := make([]string, len(tables))
+	for i, table := range tables {
+		locks[i] = formatTableLock(table, tableLocks[table])
+	}
+	return locks
+}
@@ -1,6 +1,15 @@
 package analyzer
 
 import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -95,6 +104,35 @@ func TestAnalyzer_DML_Basic(t *testing.T) {
 			expectedOp:       "MERGE with WHERE",
 			expectedLocks:    map[string]string{"target": "RowExclusive", "source": "AccessShare"},
 		},
+		{
+			name:             "MERGE with unconditional DELETE branch",
+			sql:              "MERGE INTO target USING source ON target.id = source.id WHEN MATCHED AND target.active THEN UPDATE SET value = source.value WHEN MATCHED THEN DELETE",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "MERGE without WHERE (delete)",
+			expectedLocks:    map[string]string{"target": "RowExclusive", "source": "AccessShare"},
+		},
+		{
+			name:             "MERGE with conditional DELETE branch",
+			sql:              "MERGE INTO target USING source ON target.id = source.id WHEN MATCHED AND source.deleted THEN DELETE",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "MERGE with WHERE (delete)",
+		},
+		{
+			name:             "MERGE with filtered source and matched DELETE",
+			sql:              "MERGE INTO target USING (SELECT * FROM source WHERE id < 100) s ON target.id = s.id WHEN MATCHED THEN DELETE",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "MERGE with WHERE (delete)",
+		},
+		{
+			name:             "MERGE with filtered source and NOT MATCHED BY SOURCE DELETE",
+			sql:              "MERGE INTO target USING (SELECT * FROM source WHERE id < 100) s ON target.id = s.id WHEN NOT MATCHED BY SOURCE THEN DELETE",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "MERGE without WHERE (delete)",
+		},
 
 		// INSERT variations
 		{
@@ -121,6 +159,46 @@ func TestAnalyzer_DML_Basic(t *testing.T) {
 			expectedOp:       "INSERT SELECT",
 			expectedLocks:    map[string]string{"archived_users": "RowExclusive", "users": "AccessShare"},
 		},
+		{
+			name:             "INSERT SELECT from joined tables",
+			sql:              "INSERT INTO archived_orders SELECT o.* FROM (VALUES (1)) v(id) JOIN orders o ON o.id = v.id",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "INSERT SELECT",
+			expectedLocks:    map[string]string{"archived_orders": "RowExclusive", "orders": "AccessShare"},
+		},
+		{
+			name:             "INSERT SELECT from UNION with a table",
+			sql:              "INSERT INTO archived_users SELECT 1, 'seed' UNION ALL SELECT id, name FROM users",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "INSERT SELECT",
+			expectedLocks:    map[string]string{"archived_users": "RowExclusive", "users": "AccessShare"},
+		},
+		{
+			name:             "INSERT SELECT from VALUES",
+			sql:              "INSERT INTO users (id, name) SELECT * FROM (VALUES (1, 'John'), (2, 'Jane')) v(id, name)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "INSERT",
+			expectedLocks:    map[string]string{"users": "RowExclusive"},
+		},
+		{
+			name:             "INSERT SELECT from constant subquery",
+			sql:              "INSERT INTO users (id, name) SELECT s.id, s.name FROM (SELECT 1 AS id, 'John' AS name) s",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "INSERT",
+			expectedLocks:    map[string]string{"users": "RowExclusive"},
+		},
+		{
+			name:             "INSERT SELECT constants without FROM",
+			sql:              "INSERT INTO users (id, name) SELECT 1, 'John'",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "INSERT",
+			expectedLocks:    map[string]string{"users": "RowExclusive"},
+		},
 		{
 			name:             "INSERT RETURNING",
 			sql:              "INSERT INTO users (name, email) VALUES ('John', 'john@example.com') RETURNING id",
@@ -147,6 +225,22 @@ func TestAnalyzer_DML_Basic(t *testing.T) {
 			expectedOp:       "COPY TO",
 			expectedLocks:    map[string]string{"users": "AccessShare"},
 		},
+		{
+			name:             "COPY FROM PROGRAM",
+			sql:              "COPY users FROM PROGRAM 'curl -s https://example.com/users.csv' CSV",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "COPY FROM PROGRAM",
+			expectedLocks:    map[string]string{"users": "RowExclusive"},
+		},
+		{
+			name:             "COPY TO PROGRAM",
+			sql:              "COPY users TO PROGRAM 'gzip > /tmp/users.csv.gz'",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "COPY TO PROGRAM",
+			expectedLocks:    map[string]string{"users": "AccessShare"},
+		},
 	}
 
 	runAnalyzerTests(t, tests)
@@ -249,6 +343,46 @@ func TestAnalyzer_DDL_Tables(t *testing.T) {
 			expectedOp:       "CREATE TABLE",
 			expectedLocks:    map[string]string{},
 		},
+		{
+			name:             "CREATE TABLE LIKE",
+			sql:              "CREATE TABLE t (LIKE src INCLUDING ALL)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "CREATE TABLE",
+			expectedLocks:    map[string]string{"src": "AccessShare"},
+		},
+		{
+			name:             "CREATE TABLE LIKE with extra columns",
+			sql:              "CREATE TABLE t (LIKE app.src, extra INT)",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "CREATE TABLE",
+			expectedLocks:    map[string]string{"app.src": "AccessShare"},
+		},
+		{
+			name:             "CREATE TABLE PARTITION BY",
+			sql:              "CREATE TABLE measurements (id INT, logdate DATE) PARTITION BY RANGE (logdate)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "CREATE TABLE (partitioned)",
+			expectedLocks:    map[string]string{},
+		},
+		{
+			name:             "CREATE TABLE PARTITION OF",
+			sql:              "CREATE TABLE measurements_2024 PARTITION OF measurements FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "CREATE TABLE PARTITION OF",
+			expectedLocks:    map[string]string{"measurements": "AccessExclusive"},
+		},
+		{
+			name:             "CREATE TABLE PARTITION OF - no transaction",
+			sql:              "CREATE TABLE measurements_default PARTITION OF measurements DEFAULT",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "CREATE TABLE PARTITION OF",
+			expectedLocks:    map[string]string{"measurements": "AccessExclusive"},
+		},
 		{
 			name:             "CREATE TABLE AS",
 			sql:              "CREATE TABLE archived_users AS SELECT * FROM users WHERE created_at < '2020-01-01'",
@@ -272,6 +406,34 @@ func TestAnalyzer_DDL_Tables(t *testing.T) {
 			expectedOp:       "SELECT INTO",
 			expectedLocks:    map[string]string{"users": "AccessShare"},
 		},
+		{
+			name:             "SELECT pg_advisory_lock",
+			sql:              "SELECT pg_advisory_lock(123)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "SELECT pg_advisory_lock",
+		},
+		{
+			name:             "SELECT pg_try_advisory_lock",
+			sql:              "SELECT pg_try_advisory_lock(123)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT pg_try_advisory_lock",
+		},
+		{
+			name:             "SELECT pg_try_advisory_xact_lock_shared",
+			sql:              "SELECT pg_try_advisory_xact_lock_shared(123)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT pg_try_advisory_lock (transaction, shared)",
+		},
+		{
+			name:             "SELECT pg_catalog.pg_advisory_xact_lock",
+			sql:              "SELECT pg_catalog.pg_advisory_xact_lock(123)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "SELECT pg_advisory_lock (transaction)",
+		},
 
 		// DROP operations
 		{
@@ -287,7 +449,23 @@ func TestAnalyzer_DDL_Tables(t *testing.T) {
 			sql:              "DROP TABLE users CASCADE",
 			mode:             InTransaction,
 			expectedSeverity: SeverityCritical,
-			expectedOp:       "DROP TABLE",
+			expectedOp:       "DROP TABLE (cascade)",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "DROP TABLE IF EXISTS",
+			sql:              "DROP TABLE IF EXISTS users",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "DROP TABLE (if exists)",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "DROP TABLE IF EXISTS CASCADE",
+			sql:              "DROP TABLE IF EXISTS users CASCADE",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "DROP TABLE (if exists, cascade)",
 			expectedLocks:    map[string]string{"users": "AccessExclusive"},
 		},
 
@@ -308,6 +486,22 @@ func TestAnalyzer_DDL_Tables(t *testing.T) {
 			expectedOp:       "TRUNCATE",
 			expectedLocks:    map[string]string{"users": "AccessExclusive", "sessions": "AccessExclusive", "logs": "AccessExclusive"},
 		},
+		{
+			name:             "TRUNCATE RESTART IDENTITY",
+			sql:              "TRUNCATE users RESTART IDENTITY",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "TRUNCATE (restart identity)",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "TRUNCATE CONTINUE IDENTITY",
+			sql:              "TRUNCATE users CONTINUE IDENTITY",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "TRUNCATE",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
 	}
 
 	runAnalyzerTests(t, tests)
@@ -347,6 +541,14 @@ func TestAnalyzer_DDL_AlterTable(t *testing.T) {
 			expectedOp:       "ALTER TABLE ADD COLUMN with volatile DEFAULT",
 			expectedLocks:    map[string]string{"users": "AccessExclusive"},
 		},
+		{
+			name:             "ALTER TABLE ADD COLUMN with nextval DEFAULT",
+			sql:              "ALTER TABLE users ADD COLUMN n int DEFAULT nextval('s')",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "ALTER TABLE ADD COLUMN with volatile DEFAULT",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
 		{
 			name:             "ALTER TABLE DROP COLUMN",
 			sql:              "ALTER TABLE users DROP COLUMN obsolete_field",
@@ -455,6 +657,30 @@ func TestAnalyzer_DDL_AlterTable(t *testing.T) {
 			expectedOp:       "ALTER TABLE ADD PRIMARY KEY",
 			expectedLocks:    map[string]string{"users": "AccessExclusive"},
 		},
+		{
+			name:             "ALTER TABLE ADD PRIMARY KEY USING INDEX",
+			sql:              "ALTER TABLE users ADD CONSTRAINT pk PRIMARY KEY USING INDEX idx",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER TABLE ADD PRIMARY KEY USING INDEX",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "ALTER TABLE ADD CONSTRAINT UNIQUE",
+			sql:              "ALTER TABLE users ADD CONSTRAINT u UNIQUE (email)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER TABLE ADD CONSTRAINT UNIQUE",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "ALTER TABLE ADD CONSTRAINT UNIQUE USING INDEX",
+			sql:              "ALTER TABLE users ADD CONSTRAINT u UNIQUE USING INDEX idx",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER TABLE ADD CONSTRAINT UNIQUE USING INDEX",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
 		{
 			name:             "ALTER TABLE ADD FOREIGN KEY",
 			sql:              "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users(id)",
@@ -463,6 +689,54 @@ func TestAnalyzer_DDL_AlterTable(t *testing.T) {
 			expectedOp:       "ALTER TABLE ADD FOREIGN KEY",
 			expectedLocks:    map[string]string{"orders": "ShareRowExclusive", "users": "RowShare"},
 		},
+		{
+			name:             "ALTER TABLE ADD FOREIGN KEY NOT VALID",
+			sql:              "ALTER TABLE orders ADD CONSTRAINT fk FOREIGN KEY (a) REFERENCES other(id) NOT VALID",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER TABLE ADD FOREIGN KEY NOT VALID",
+			expectedLocks:    map[string]string{"orders": "ShareRowExclusive", "other": "RowShare"},
+		},
+		{
+			name:             "ALTER TABLE ADD COLUMN with inline REFERENCES",
+			sql:              "ALTER TABLE orders ADD COLUMN owner_id int REFERENCES users(id)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER TABLE ADD COLUMN with FOREIGN KEY",
+			expectedLocks:    map[string]string{"orders": "AccessExclusive", "users": "RowShare"},
+		},
+		{
+			name:             "ALTER TABLE ADD COLUMN with inline PRIMARY KEY",
+			sql:              "ALTER TABLE orders ADD COLUMN id bigserial PRIMARY KEY",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "ALTER TABLE ADD COLUMN with PRIMARY KEY",
+			expectedLocks:    map[string]string{"orders": "AccessExclusive"},
+		},
+		{
+			name:             "ALTER TABLE ADD COLUMN with inline UNIQUE",
+			sql:              "ALTER TABLE users ADD COLUMN email text UNIQUE",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "ALTER TABLE ADD COLUMN with UNIQUE",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "ALTER TABLE ADD COLUMN with volatile DEFAULT and inline REFERENCES",
+			sql:              "ALTER TABLE orders ADD COLUMN owner_id uuid DEFAULT gen_random_uuid() REFERENCES users(id)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "ALTER TABLE ADD COLUMN with volatile DEFAULT",
+			expectedLocks:    map[string]string{"orders": "AccessExclusive", "users": "RowShare"},
+		},
+		{
+			name:             "ALTER TABLE ADD CHECK NOT VALID",
+			sql:              "ALTER TABLE orders ADD CONSTRAINT positive CHECK (total > 0) NOT VALID",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER TABLE ADD CONSTRAINT NOT VALID",
+			expectedLocks:    map[string]string{"orders": "ShareRowExclusive"},
+		},
 		{
 			name:             "ALTER TABLE ADD CHECK CONSTRAINT",
 			sql:              "ALTER TABLE users ADD CONSTRAINT age_check CHECK (age >= 0)",
@@ -747,6 +1021,38 @@ func TestAnalyzer_DDL_Indexes(t *testing.T) {
 			expectedOp:       "CREATE UNIQUE INDEX",
 			expectedLocks:    map[string]string{"users": "Share"},
 		},
+		{
+			name:             "CREATE INDEX partial",
+			sql:              "CREATE INDEX idx_active_users ON users(email) WHERE deleted_at IS NULL",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "CREATE INDEX (partial)",
+			expectedLocks:    map[string]string{"users": "Share"},
+		},
+		{
+			name:             "CREATE INDEX expression",
+			sql:              "CREATE INDEX idx_users_lower_email ON users(lower(email))",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "CREATE INDEX (expression)",
+			expectedLocks:    map[string]string{"users": "Share"},
+		},
+		{
+			name:             "CREATE INDEX INCLUDE",
+			sql:              "CREATE INDEX idx_users_email ON users(email) INCLUDE (name)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "CREATE INDEX (covering)",
+			expectedLocks:    map[string]string{"users": "Share"},
+		},
+		{
+			name:             "CREATE INDEX CONCURRENTLY partial expression",
+			sql:              "CREATE INDEX CONCURRENTLY idx ON users(lower(email)) WHERE active",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "CREATE INDEX CONCURRENTLY (partial, expression)",
+			expectedLocks:    map[string]string{"users": "ShareUpdateExclusive"},
+		},
 		{
 			name:             "CREATE INDEX CONCURRENTLY - transaction",
 			sql:              "CREATE INDEX CONCURRENTLY idx_users_email ON users(email)",
@@ -844,6 +1150,51 @@ func TestAnalyzer_DDL_Indexes(t *testing.T) {
 			expectedOp:       "REINDEX CONCURRENTLY",
 			expectedLocks:    map[string]string{"idx_users_email": "ShareUpdateExclusive"},
 		},
+		{
+			name:             "REINDEX VERBOSE, CONCURRENTLY TABLE",
+			sql:              "REINDEX (VERBOSE, CONCURRENTLY) TABLE users",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "REINDEX CONCURRENTLY",
+			expectedLocks:    map[string]string{"users": "ShareUpdateExclusive"},
+		},
+		{
+			name:             "REINDEX VERBOSE TABLE",
+			sql:              "REINDEX (VERBOSE) TABLE users",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "REINDEX TABLE",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "REINDEX CONCURRENTLY false",
+			sql:              "REINDEX (CONCURRENTLY false, VERBOSE) INDEX idx_users_email",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "REINDEX",
+			expectedLocks:    map[string]string{"idx_users_email": "AccessExclusive"},
+		},
+		{
+			name:             "REINDEX CONCURRENTLY DATABASE - transaction",
+			sql:              "REINDEX (CONCURRENTLY) DATABASE mydb",
+			mode:             InTransaction,
+			expectedSeverity: SeverityError,
+			expectedOp:       "REINDEX DATABASE CONCURRENTLY",
+		},
+		{
+			name:             "REINDEX DATABASE CONCURRENTLY - no transaction",
+			sql:              "REINDEX DATABASE CONCURRENTLY mydb",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "REINDEX DATABASE CONCURRENTLY",
+		},
+		{
+			name:             "REINDEX VERBOSE, CONCURRENTLY SCHEMA",
+			sql:              "REINDEX (VERBOSE, CONCURRENTLY) SCHEMA public",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "REINDEX SCHEMA CONCURRENTLY",
+		},
 
 		// ALTER INDEX
 		{
@@ -867,6 +1218,29 @@ func TestAnalyzer_DDL_Indexes(t *testing.T) {
 	runAnalyzerTests(t, tests)
 }
 
+func TestAnalyzer_CatalogOnlyLocks(t *testing.T) {
+	for _, sql := range []string{
+		"ALTER SCHEMA app RENAME TO app_v2",
+		"COMMENT ON TABLE users IS 'User accounts'",
+		"SECURITY LABEL FOR selinux ON TABLE users IS 'system_u:object_r:sepgsql_table_t:s0'",
+	} {
+		t.Run(sql, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := New().AnalyzeStatement(parsed.Statements[0], InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+			if result.LockType() != AccessShare {
+				t.Errorf("Expected lock %s, got %s", AccessShare, result.LockType())
+			}
+		})
+	}
+}
+
 func TestAnalyzer_DDL_SchemaDatabaseObjects(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -884,6 +1258,13 @@ func TestAnalyzer_DDL_SchemaDatabaseObjects(t *testing.T) {
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "CREATE SCHEMA",
 		},
+		{
+			name:             "ALTER SCHEMA RENAME",
+			sql:              "ALTER SCHEMA app RENAME TO app_v2",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "ALTER SCHEMA RENAME TO",
+		},
 		{
 			name:             "DROP SCHEMA",
 			sql:              "DROP SCHEMA archive",
@@ -914,6 +1295,13 @@ func TestAnalyzer_DDL_SchemaDatabaseObjects(t *testing.T) {
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "CREATE DATABASE",
 		},
+		{
+			name:             "CREATE DATABASE TEMPLATE - transaction",
+			sql:              "CREATE DATABASE testdb TEMPLATE app_template",
+			mode:             InTransaction,
+			expectedSeverity: SeverityError,
+			expectedOp:       "CREATE DATABASE",
+		},
 		{
 			name:             "DROP DATABASE - transaction",
 			sql:              "DROP DATABASE testdb",
@@ -1002,6 +1390,14 @@ func TestAnalyzer_DDL_ViewsAndMaterializedViews(t *testing.T) {
 			expectedOp:       "CREATE VIEW",
 			expectedLocks:    map[string]string{"users": "AccessShare"},
 		},
+		{
+			name:             "CREATE OR REPLACE VIEW",
+			sql:              "CREATE OR REPLACE VIEW active_users AS SELECT u.id, o.total FROM users u JOIN orders o ON o.user_id = u.id",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "CREATE OR REPLACE VIEW",
+			expectedLocks:    map[string]string{"active_users": "AccessExclusive", "users": "AccessShare", "orders": "AccessShare"},
+		},
 		{
 			name:             "DROP VIEW",
 			sql:              "DROP VIEW active_users",
@@ -1150,32 +1546,60 @@ func TestAnalyzer_DDL_OtherObjects(t *testing.T) {
 		},
 		{
 			name:             "ALTER DOMAIN",
-			sql:              "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255)",
+			sql:              "ALTER DOMAIN email SET DEFAULT 'unknown'",
 			mode:             InTransaction,
 			expectedSeverity: SeverityWarning,
 			expectedOp:       "ALTER DOMAIN",
 		},
-
-		// Extensions
 		{
-			name:             "CREATE EXTENSION",
-			sql:              "CREATE EXTENSION IF NOT EXISTS pg_stat_statements",
+			name:             "ALTER DOMAIN ADD CONSTRAINT",
+			sql:              "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255)",
 			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "CREATE EXTENSION",
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER DOMAIN ADD CONSTRAINT",
 		},
 		{
-			name:             "DROP EXTENSION",
-			sql:              "DROP EXTENSION pg_stat_statements",
+			name:             "ALTER DOMAIN ADD CONSTRAINT NOT VALID",
+			sql:              "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255) NOT VALID",
 			mode:             InTransaction,
 			expectedSeverity: SeverityWarning,
-			expectedOp:       "DROP EXTENSION",
+			expectedOp:       "ALTER DOMAIN ADD CONSTRAINT NOT VALID",
 		},
-
-		// Functions and procedures
 		{
-			name:             "CREATE FUNCTION",
-			sql:              "CREATE FUNCTION add_numbers(a INT, b INT) RETURNS INT AS $$ SELECT a + b $$ LANGUAGE SQL",
+			name:             "ALTER DOMAIN VALIDATE CONSTRAINT",
+			sql:              "ALTER DOMAIN email VALIDATE CONSTRAINT email_length",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER DOMAIN VALIDATE CONSTRAINT",
+		},
+		{
+			name:             "ALTER DOMAIN DROP CONSTRAINT",
+			sql:              "ALTER DOMAIN email DROP CONSTRAINT email_length",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "ALTER DOMAIN DROP CONSTRAINT",
+		},
+
+		// Extensions
+		{
+			name:             "CREATE EXTENSION",
+			sql:              "CREATE EXTENSION IF NOT EXISTS pg_stat_statements",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "CREATE EXTENSION",
+		},
+		{
+			name:             "DROP EXTENSION",
+			sql:              "DROP EXTENSION pg_stat_statements",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "DROP EXTENSION",
+		},
+
+		// Functions and procedures
+		{
+			name:             "CREATE FUNCTION",
+			sql:              "CREATE FUNCTION add_numbers(a INT, b INT) RETURNS INT AS $$ SELECT a + b $$ LANGUAGE SQL",
 			mode:             InTransaction,
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "CREATE FUNCTION",
@@ -1333,6 +1757,34 @@ func TestAnalyzer_DDL_OtherObjects(t *testing.T) {
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "DROP CAST",
 		},
+		{
+			name:             "CREATE ACCESS METHOD",
+			sql:              "CREATE ACCESS METHOD heap2 TYPE TABLE HANDLER heap_tableam_handler",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "CREATE ACCESS METHOD",
+		},
+		{
+			name:             "DROP ACCESS METHOD",
+			sql:              "DROP ACCESS METHOD heap2",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "DROP ACCESS METHOD",
+		},
+		{
+			name:             "CREATE TRANSFORM",
+			sql:              "CREATE TRANSFORM FOR hstore LANGUAGE plpython3u (FROM SQL WITH FUNCTION hstore_to_plpython(internal), TO SQL WITH FUNCTION plpython_to_hstore(internal))",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "CREATE TRANSFORM",
+		},
+		{
+			name:             "DROP TRANSFORM",
+			sql:              "DROP TRANSFORM FOR hstore LANGUAGE plpython3u",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "DROP TRANSFORM",
+		},
 		{
 			name:             "CREATE COLLATION",
 			sql:              "CREATE COLLATION french (LOCALE = 'fr_FR.utf8')",
@@ -1352,6 +1804,50 @@ func TestAnalyzer_DDL_OtherObjects(t *testing.T) {
 	runAnalyzerTests(t, tests)
 }
 
+func TestAnalyzer_DomainConstraintLocks(t *testing.T) {
+	tests := []struct {
+		name         string
+		sql          string
+		expectedLock LockType
+	}{
+		{
+			name:         "ADD CONSTRAINT scans tables using the domain",
+			sql:          "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255)",
+			expectedLock: Share,
+		},
+		{
+			name:         "ADD CONSTRAINT NOT VALID skips the scan",
+			sql:          "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255) NOT VALID",
+			expectedLock: AccessShare,
+		},
+		{
+			name:         "VALIDATE CONSTRAINT scans tables using the domain",
+			sql:          "ALTER DOMAIN email VALIDATE CONSTRAINT email_length",
+			expectedLock: Share,
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := New().Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if got := results[0].LockType(); got != tt.expectedLock {
+				t.Errorf("Expected lock %s, got %s", tt.expectedLock, got)
+			}
+		})
+	}
+}
+
 func TestAnalyzer_DDL_TriggersRulesAndPolicies(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -1419,6 +1915,67 @@ func TestAnalyzer_DDL_TriggersRulesAndPolicies(t *testing.T) {
 	runAnalyzerTests(t, tests)
 }
 
+func TestAnalyzer_SchemaRenameDoesNotLockTables(t *testing.T) {
+	parsed, err := parser.NewParser().ParseSQL("ALTER SCHEMA app RENAME TO app_v2")
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+
+	result, err := New().AnalyzeStatement(parsed.Statements[0], InTransaction)
+	if err != nil {
+		t.Fatalf("Failed to analyze: %v", err)
+	}
+
+	if result.Severity != SeverityInfo {
+		t.Errorf("Expected severity INFO, got %s", result.Severity)
+	}
+	if len(result.TableLocks()) != 0 {
+		t.Errorf("Expected no table locks, got %v", result.TableLocks())
+	}
+}
+
+func TestAnalyzer_AllLocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+	}{
+		{
+			name:     "ADD FOREIGN KEY locks the child before the parent",
+			sql:      "ALTER TABLE orders ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)",
+			expected: []string{"orders: ShareRowExclusive", "users: RowShare"},
+		},
+		{
+			name:     "UPDATE locks the target before the tables it reads",
+			sql:      "UPDATE accounts SET balance = t.amount FROM transfers t, audit a WHERE accounts.id = t.account_id",
+			expected: []string{"accounts: RowExclusive", "audit: AccessShare", "transfers: AccessShare"},
+		},
+		{
+			name:     "no tables",
+			sql:      "SELECT 1",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := New().AnalyzeStatement(parsed.Statements[0], InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if !reflect.DeepEqual(result.AllLocks(), tt.expected) {
+				t.Errorf("AllLocks() = %v, want %v", result.AllLocks(), tt.expected)
+			}
+		})
+	}
+}
+
 // ===== 3. MAINTENANCE OPERATIONS =====
 
 func TestAnalyzer_MaintenanceOperations(t *testing.T) {
@@ -1491,6 +2048,46 @@ func TestAnalyzer_MaintenanceOperations(t *testing.T) {
 			expectedOp:       "VACUUM ANALYZE",
 			expectedLocks:    map[string]string{"users": "ShareUpdateExclusive"},
 		},
+		{
+			name:             "VACUUM (FULL, ANALYZE) - no transaction",
+			sql:              "VACUUM (FULL, ANALYZE) users",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "VACUUM FULL (analyze)",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "VACUUM (SKIP_LOCKED) - no transaction",
+			sql:              "VACUUM (SKIP_LOCKED) users",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "VACUUM (skip locked)",
+			expectedLocks:    map[string]string{"users": "ShareUpdateExclusive"},
+		},
+		{
+			name:             "VACUUM (FREEZE, ANALYZE false) - no transaction",
+			sql:              "VACUUM (FREEZE, ANALYZE false) users",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "VACUUM FREEZE",
+			expectedLocks:    map[string]string{"users": "ShareUpdateExclusive"},
+		},
+		{
+			name:             "VACUUM (ONLY_DATABASE_STATS) - transaction",
+			sql:              "VACUUM (ONLY_DATABASE_STATS)",
+			mode:             InTransaction,
+			expectedSeverity: SeverityError,
+			expectedOp:       "VACUUM ONLY_DATABASE_STATS",
+			expectedLocks:    map[string]string{},
+		},
+		{
+			name:             "VACUUM (ONLY_DATABASE_STATS) - no transaction",
+			sql:              "VACUUM (ONLY_DATABASE_STATS)",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "VACUUM ONLY_DATABASE_STATS",
+			expectedLocks:    map[string]string{},
+		},
 
 		// ANALYZE
 		{
@@ -1511,11 +2108,241 @@ func TestAnalyzer_MaintenanceOperations(t *testing.T) {
 			expectedOp:       "CLUSTER",
 			expectedLocks:    map[string]string{"users": "AccessExclusive"},
 		},
+		{
+			name:             "CLUSTER on the previous index",
+			sql:              "CLUSTER users",
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "CLUSTER (previous index)",
+			expectedLocks:    map[string]string{"users": "AccessExclusive"},
+		},
+		{
+			name:             "CLUSTER without table - transaction",
+			sql:              "CLUSTER",
+			mode:             InTransaction,
+			expectedSeverity: SeverityError,
+			expectedOp:       "CLUSTER without table",
+		},
+		{
+			name:             "CLUSTER without table - no transaction",
+			sql:              "CLUSTER",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "CLUSTER without table",
+		},
 	}
 
 	runAnalyzerTests(t, tests)
 }
 
+func TestAnalyzer_Scope(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedScope Scope
+	}{
+		{"REINDEX INDEX", "REINDEX INDEX idx_users_email", InTransaction, ScopeObject},
+		{"REINDEX TABLE", "REINDEX TABLE users", InTransaction, ScopeTable},
+		{"REINDEX SCHEMA", "REINDEX SCHEMA public", InTransaction, ScopeSchema},
+		{"REINDEX DATABASE", "REINDEX DATABASE mydb", NoTransaction, ScopeDatabase},
+		{"REINDEX SYSTEM", "REINDEX SYSTEM mydb", NoTransaction, ScopeSystem},
+		{"REINDEX CONCURRENTLY DATABASE", "REINDEX (CONCURRENTLY) DATABASE mydb", NoTransaction, ScopeDatabase},
+		{"REINDEX CONCURRENTLY SCHEMA", "REINDEX (CONCURRENTLY) SCHEMA public", NoTransaction, ScopeSchema},
+		{"VACUUM without tables", "VACUUM", NoTransaction, ScopeDatabase},
+		{"VACUUM table", "VACUUM users", NoTransaction, ScopeTable},
+		{"ANALYZE without tables", "ANALYZE", InTransaction, ScopeDatabase},
+		{"CLUSTER without table", "CLUSTER", NoTransaction, ScopeDatabase},
+		{"UPDATE", "UPDATE users SET active = false", InTransaction, ScopeTable},
+		{"CREATE FUNCTION", "CREATE FUNCTION f() RETURNS int AS 'SELECT 1' LANGUAGE sql", InTransaction, ScopeObject},
+	}
+
+	a := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := a.AnalyzeStatement(parsed.Statements[0], tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if result.Scope() != tt.expectedScope {
+				t.Errorf("Expected scope %q, got %q", tt.expectedScope, result.Scope())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_RecommendedMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		sql          string
+		mode         TransactionMode
+		expectedMode RecommendedMode
+	}{
+		{"CREATE INDEX CONCURRENTLY", "CREATE INDEX CONCURRENTLY idx ON users(email)", InTransaction, RecommendedModeNoTransaction},
+		{"CREATE INDEX CONCURRENTLY without transaction", "CREATE INDEX CONCURRENTLY idx ON users(email)", NoTransaction, RecommendedModeNoTransaction},
+		{"REINDEX CONCURRENTLY", "REINDEX (CONCURRENTLY) INDEX idx", InTransaction, RecommendedModeNoTransaction},
+		{"VACUUM", "VACUUM users", NoTransaction, RecommendedModeNoTransaction},
+		{"SAVEPOINT", "SAVEPOINT sp1", NoTransaction, RecommendedModeEither},
+		{"UPDATE with WHERE", "UPDATE users SET active = true WHERE id = 1", InTransaction, RecommendedModeEither},
+		{"INSERT", "INSERT INTO users (id) VALUES (1)", NoTransaction, RecommendedModeEither},
+		{"SELECT", "SELECT * FROM users", InTransaction, RecommendedModeEither},
+	}
+
+	a := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := a.AnalyzeStatement(parsed.Statements[0], tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if result.RecommendedMode() != tt.expectedMode {
+				t.Errorf("Expected recommended mode %q, got %q", tt.expectedMode, result.RecommendedMode())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Unrecognized(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected bool
+	}{
+		{"DROP of an object type without its own operation", "DROP CONVERSION myconv", true},
+		{"rename of an object type without its own operation", "ALTER CONVERSION myconv RENAME TO yourconv", true},
+		{"ALTER TABLE ADD COLUMN", "ALTER TABLE users ADD COLUMN age int", false},
+		{"SELECT", "SELECT * FROM users", false},
+		{"RESET ALL", "RESET ALL", false},
+		{"ALTER TABLE RENAME CONSTRAINT", "ALTER TABLE t RENAME CONSTRAINT a TO b", false},
+		{"SELECT pg_try_advisory_lock", "SELECT pg_try_advisory_lock(1)", false},
+		{"ALTER SEQUENCE RENAME TO", "ALTER SEQUENCE s RENAME TO s2", false},
+		{"LOCK TABLE", "LOCK TABLE users", false},
+		{"PREPARE TRANSACTION", "PREPARE TRANSACTION 'tx'", true},
+	}
+
+	a := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := a.AnalyzeStatement(parsed.Statements[0], InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if result.Unrecognized() != tt.expected {
+				t.Errorf("Expected Unrecognized() %v for %s, got %v", tt.expected, result.Operation(), result.Unrecognized())
+			}
+		})
+	}
+}
+
+// TestRegistry_KnowsEveryOperation checks that every operation label the analyzer assigns, other
+// than the generic fallbacks marked unrecognized, has a registry entry of its own
+func TestRegistry_KnowsEveryOperation(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || file == "registry.go" {
+			continue
+		}
+		f, err := goparser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		ast.Inspect(f, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.CompositeLit:
+				var operation string
+				unrecognized := false
+				for _, elt := range n.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					key, ok := kv.Key.(*ast.Ident)
+					if !ok {
+						continue
+					}
+					switch key.Name {
+					case "operation":
+						operation = stringLiteral(kv.Value)
+					case "unrecognized":
+						value, _ := kv.Value.(*ast.Ident)
+						unrecognized = value != nil && value.Name == "true"
+					}
+				}
+				if operation != "" && !unrecognized {
+					operations[operation] = true
+				}
+			case *ast.AssignStmt:
+				// Appending to a label, as in operation += " PROGRAM", is not a label of its own
+				if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+					return true
+				}
+				for i, lhs := range n.Lhs {
+					name := ""
+					switch l := lhs.(type) {
+					case *ast.Ident:
+						name = l.Name
+					case *ast.SelectorExpr:
+						name = l.Sel.Name
+					}
+					if name == "operation" && i < len(n.Rhs) {
+						if operation := stringLiteral(n.Rhs[i]); operation != "" {
+							operations[operation] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	if len(operations) == 0 {
+		t.Fatal("Found no operation labels")
+	}
+	r := newOperationRegistry()
+	for operation := range operations {
+		if !r.known(operation) {
+			t.Errorf("Operation %q has no registry entry", operation)
+		}
+	}
+}
+
+// stringLiteral returns the value of a string literal expression, or "" for any other expression
+func stringLiteral(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
 // ===== 4. EXPLICIT LOCKING =====
 
 func TestAnalyzer_ExplicitLocking(t *testing.T) {
@@ -1641,6 +2468,15 @@ func TestAnalyzer_OwnershipAndPermissions(t *testing.T) {
 			mode:             InTransaction,
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "GRANT",
+			expectedLocks:    map[string]string{"users": "AccessShare"},
+		},
+		{
+			name:             "GRANT SELECT on table",
+			sql:              "GRANT SELECT ON users TO r",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "GRANT",
+			expectedLocks:    map[string]string{"users": "AccessShare"},
 		},
 		{
 			name:             "REVOKE on table",
@@ -1648,6 +2484,15 @@ func TestAnalyzer_OwnershipAndPermissions(t *testing.T) {
 			mode:             InTransaction,
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "REVOKE",
+			expectedLocks:    map[string]string{"users": "AccessShare"},
+		},
+		{
+			name:             "REVOKE on several tables",
+			sql:              "REVOKE SELECT ON public.users, orders FROM r",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "REVOKE",
+			expectedLocks:    map[string]string{"public.users": "AccessShare", "orders": "AccessShare"},
 		},
 		{
 			name:             "REASSIGN OWNED",
@@ -1736,28 +2581,70 @@ func TestAnalyzer_SystemAndSessionOperations(t *testing.T) {
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "LOAD",
 		},
-
-		// Session settings
 		{
-			name:             "SET session variable",
-			sql:              "SET work_mem = '256MB'",
+			name:             "LISTEN - transaction",
+			sql:              "LISTEN ch",
 			mode:             InTransaction,
 			expectedSeverity: SeverityInfo,
-			expectedOp:       "SET",
+			expectedOp:       "LISTEN",
 		},
 		{
-			name:             "SET LOCAL",
-			sql:              "SET LOCAL work_mem = '256MB'",
-			mode:             InTransaction,
+			name:             "LISTEN - no transaction",
+			sql:              "LISTEN ch",
+			mode:             NoTransaction,
 			expectedSeverity: SeverityInfo,
-			expectedOp:       "SET LOCAL",
+			expectedOp:       "LISTEN",
 		},
 		{
-			name:             "RESET",
-			sql:              "RESET work_mem",
+			name:             "NOTIFY - transaction",
+			sql:              "NOTIFY ch, 'msg'",
 			mode:             InTransaction,
 			expectedSeverity: SeverityInfo,
-			expectedOp:       "RESET",
+			expectedOp:       "NOTIFY",
+		},
+		{
+			name:             "NOTIFY - no transaction",
+			sql:              "NOTIFY ch, 'msg'",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "NOTIFY",
+		},
+		{
+			name:             "UNLISTEN - transaction",
+			sql:              "UNLISTEN *",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "UNLISTEN",
+		},
+		{
+			name:             "UNLISTEN - no transaction",
+			sql:              "UNLISTEN *",
+			mode:             NoTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "UNLISTEN",
+		},
+
+		// Session settings
+		{
+			name:             "SET session variable",
+			sql:              "SET work_mem = '256MB'",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SET",
+		},
+		{
+			name:             "SET LOCAL",
+			sql:              "SET LOCAL work_mem = '256MB'",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SET LOCAL",
+		},
+		{
+			name:             "RESET",
+			sql:              "RESET work_mem",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "RESET",
 		},
 
 		// Comments
@@ -1768,6 +2655,13 @@ func TestAnalyzer_SystemAndSessionOperations(t *testing.T) {
 			expectedSeverity: SeverityInfo,
 			expectedOp:       "COMMENT ON",
 		},
+		{
+			name:             "SECURITY LABEL",
+			sql:              "SECURITY LABEL FOR selinux ON TABLE users IS 'system_u:object_r:sepgsql_table_t:s0'",
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SECURITY LABEL",
+		},
 	}
 
 	runAnalyzerTests(t, tests)
@@ -2547,19 +3441,72 @@ func TestAnalyzer_MultipleStatements(t *testing.T) {
 	}
 }
 
-// ===== HELPER FUNCTIONS =====
+// ===== 12. TRANSACTION CHECKS =====
 
-func runAnalyzerTests(t *testing.T, tests []struct {
-	name             string
-	sql              string
-	mode             TransactionMode
-	expectedSeverity Severity
-	expectedOp       string
-	expectedLocks    map[string]string
-}) {
-	a := New()
-	p := parser.NewParser()
+func TestAnalyzer_ErrorReasons(t *testing.T) {
+	tests := []struct {
+		name           string
+		sql            string
+		mode           TransactionMode
+		expectedReason []ErrorReason
+	}{
+		{
+			name:           "CREATE INDEX CONCURRENTLY in transaction",
+			sql:            "CREATE INDEX CONCURRENTLY idx ON users(email)",
+			mode:           InTransaction,
+			expectedReason: []ErrorReason{ErrorReasonCannotRunInTransaction},
+		},
+		{
+			name:           "CREATE INDEX CONCURRENTLY inside BEGIN",
+			sql:            "BEGIN; CREATE INDEX CONCURRENTLY idx ON users(email); COMMIT;",
+			mode:           NoTransaction,
+			expectedReason: []ErrorReason{ErrorReasonNone, ErrorReasonCannotRunInTransaction, ErrorReasonNone},
+		},
+		{
+			name:           "CREATE DATABASE inside BEGIN",
+			sql:            "BEGIN; CREATE DATABASE app; COMMIT;",
+			mode:           NoTransaction,
+			expectedReason: []ErrorReason{ErrorReasonNone, ErrorReasonCannotRunInTransaction, ErrorReasonNone},
+		},
+		{
+			name:           "VACUUM in transaction",
+			sql:            "VACUUM users",
+			mode:           InTransaction,
+			expectedReason: []ErrorReason{ErrorReasonCannotRunInTransaction},
+		},
+		{
+			name:           "ALTER SYSTEM in transaction",
+			sql:            "ALTER SYSTEM SET work_mem = '64MB'",
+			mode:           InTransaction,
+			expectedReason: []ErrorReason{ErrorReasonCannotRunInTransaction},
+		},
+		{
+			name:           "SAVEPOINT outside transaction",
+			sql:            "SAVEPOINT sp1",
+			mode:           NoTransaction,
+			expectedReason: []ErrorReason{ErrorReasonNone},
+		},
+		{
+			name:           "ROLLBACK TO SAVEPOINT outside transaction",
+			sql:            "ROLLBACK TO SAVEPOINT sp1",
+			mode:           NoTransaction,
+			expectedReason: []ErrorReason{ErrorReasonNone},
+		},
+		{
+			name:           "SAVEPOINT inside BEGIN",
+			sql:            "BEGIN; SAVEPOINT sp1; RELEASE SAVEPOINT sp1; COMMIT;",
+			mode:           NoTransaction,
+			expectedReason: []ErrorReason{ErrorReasonNone, ErrorReasonNone, ErrorReasonNone, ErrorReasonNone},
+		},
+		{
+			name:           "non-error result",
+			sql:            "TRUNCATE users",
+			mode:           InTransaction,
+			expectedReason: []ErrorReason{ErrorReasonNone},
+		},
+	}
 
+	p := parser.NewParser()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parsed, err := p.ParseSQL(tt.sql)
@@ -2567,218 +3514,2436 @@ func runAnalyzerTests(t *testing.T, tests []struct {
 				t.Fatalf("Failed to parse SQL: %v", err)
 			}
 
-			result, err := a.AnalyzeStatement(parsed.Statements[0], tt.mode)
+			results, err := New().Analyze(parsed, tt.mode)
 			if err != nil {
 				t.Fatalf("Failed to analyze: %v", err)
 			}
 
-			if result.Severity != tt.expectedSeverity {
-				t.Errorf("Expected severity %s, got %s", tt.expectedSeverity, result.Severity)
-			}
-
-			if tt.expectedOp != "" && result.Operation() != tt.expectedOp {
-				t.Errorf("Expected operation %s, got %s", tt.expectedOp, result.Operation())
+			if len(results) != len(tt.expectedReason) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expectedReason), len(results))
 			}
 
-			if tt.expectedLocks != nil {
-				for table, expectedLock := range tt.expectedLocks {
-					found := false
-					for _, tl := range result.TableLocks() {
-						if strings.Contains(tl, table) && strings.Contains(tl, expectedLock) {
-							found = true
-							break
-						}
-					}
-					if !found {
-						t.Errorf("Expected lock %s on table %s, not found in %v",
-							expectedLock, table, result.TableLocks())
-					}
+			for i, expected := range tt.expectedReason {
+				if results[i].ErrorReason() != expected {
+					t.Errorf("Statement %d: expected error reason %q, got %q", i, expected, results[i].ErrorReason())
+				}
+				if (expected != ErrorReasonNone) != (results[i].Severity == SeverityError) {
+					t.Errorf("Statement %d: error reason %q does not match severity %s", i, expected, results[i].Severity)
 				}
 			}
 		})
 	}
 }
 
-// ===== QUOTED IDENTIFIERS TEST =====
-
-func TestAnalyzer_QuotedIdentifiers(t *testing.T) {
+func TestAnalyzer_StatementTimeoutCheck(t *testing.T) {
 	tests := []struct {
-		name             string
-		sql              string
-		mode             TransactionMode
-		expectedSeverity Severity
-		expectedOp       string
-		expectedLocks    map[string]string
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string // substring per statement, "" means no statement_timeout note
 	}{
-		// === Basic quoted vs unquoted identifiers ===
 		{
-			name:             "simple quoted lowercase table",
-			sql:              `SELECT * FROM "users"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{"users": "AccessShare"},
+			name:          "disabled timeout before ADD PRIMARY KEY",
+			sql:           "SET statement_timeout = 0;\nALTER TABLE users ADD PRIMARY KEY (id);",
+			mode:          InTransaction,
+			expectedNotes: []string{"", "runs with statement_timeout disabled (set at line 1)"},
 		},
 		{
-			name:             "simple unquoted lowercase table",
-			sql:              `SELECT * FROM users`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{"users": "AccessShare"},
+			name:          "SET LOCAL very large timeout",
+			sql:           "BEGIN;\nSET LOCAL statement_timeout = '1h';\nALTER TABLE users ADD PRIMARY KEY (id);\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "very large statement_timeout of 3600000ms (set at line 2)", ""},
 		},
 		{
-			name:             "quoted uppercase table",
-			sql:              `SELECT * FROM "Users"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{`"Users"`: "AccessShare"},
+			name:          "SET LOCAL ends with the transaction",
+			sql:           "BEGIN;\nSET LOCAL statement_timeout = 0;\nCOMMIT;\nALTER TABLE users ADD PRIMARY KEY (id);",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
 		},
 		{
-			name:             "quoted mixed case table",
-			sql:              `UPDATE "UserAccounts" SET active = true WHERE id = 1`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityWarning,
-			expectedOp:       "UPDATE with WHERE",
-			expectedLocks:    map[string]string{`"UserAccounts"`: "RowExclusive"},
+			name:          "bounded timeout",
+			sql:           "SET statement_timeout = '30s';\nALTER TABLE users ADD PRIMARY KEY (id);",
+			mode:          InTransaction,
+			expectedNotes: []string{"", ""},
+		},
+		{
+			name:          "RESET clears the timeout",
+			sql:           "SET statement_timeout = 0;\nRESET statement_timeout;\nALTER TABLE users ADD PRIMARY KEY (id);",
+			mode:          InTransaction,
+			expectedNotes: []string{"", "", ""},
+		},
+		{
+			name:          "weak lock is not noted",
+			sql:           "SET statement_timeout = 0;\nUPDATE users SET active = true WHERE id = 1;",
+			mode:          InTransaction,
+			expectedNotes: []string{"", ""},
 		},
+	}
 
-		// === Special characters that require quoting ===
+	// ADD PRIMARY KEY carries notes of its own, so only statement_timeout notes are compared
+	timeoutNote := func(note string) bool {
+		return strings.Contains(note, "statement_timeout")
+	}
+	runTransactionCheckTests(t, Options{CheckStatementTimeout: true}, tests, timeoutNote)
+
+	// The check is opt-in
+	p := parser.NewParser()
+	parsed, err := p.ParseSQL("SET statement_timeout = 0; ALTER TABLE users ADD PRIMARY KEY (id);")
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+	results, err := New().Analyze(parsed, InTransaction)
+	if err != nil {
+		t.Fatalf("Failed to analyze: %v", err)
+	}
+	if notes := slices.DeleteFunc(results[1].Notes(), func(note string) bool { return !timeoutNote(note) }); len(notes) != 0 {
+		t.Errorf("Expected no statement_timeout note without the option, got %v", notes)
+	}
+}
+
+func TestAnalyzer_LongTransactionCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string // substring per statement, "" means no notes
+	}{
 		{
-			name:             "table with hyphen",
-			sql:              `SELECT * FROM "user-accounts"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{`"user-accounts"`: "AccessShare"},
+			name:          "early ADD COLUMN held until COMMIT",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN nickname text;\nUPDATE users SET nickname = name WHERE id < 100;\nUPDATE users SET nickname = name WHERE id < 200;\nUPDATE users SET nickname = name WHERE id < 300;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "AccessExclusive lock is held through 3 later statements until COMMIT at line 6", "", "", "", ""},
 		},
 		{
-			name:             "table with space",
-			sql:              `UPDATE "user accounts" SET status = 'active'`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityCritical,
-			expectedOp:       "UPDATE without WHERE",
-			expectedLocks:    map[string]string{`"user accounts"`: "RowExclusive"},
+			name:          "below threshold",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN nickname text;\nSELECT 1;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
 		},
 		{
-			name:             "table with dot notation",
-			sql:              `DELETE FROM "my.table" WHERE id > 100`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityWarning,
-			expectedOp:       "DELETE with WHERE",
-			expectedLocks:    map[string]string{`"my.table"`: "RowExclusive"},
+			name:          "implicit transaction held until end of input",
+			sql:           "ALTER TABLE users ADD COLUMN nickname text;\nSELECT 1;\nSELECT 2;\nSELECT 3;",
+			mode:          InTransaction,
+			expectedNotes: []string{"held through 3 later statements until the end of input", "", "", ""},
 		},
 		{
-			name:             "table starting with number",
-			sql:              `INSERT INTO "123_logs" (message) VALUES ('test')`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "INSERT",
-			expectedLocks:    map[string]string{`"123_logs"`: "RowExclusive"},
+			name:          "outside a transaction the lock is released",
+			sql:           "ALTER TABLE users ADD COLUMN nickname text;\nSELECT 1;\nSELECT 2;\nSELECT 3;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
 		},
 		{
-			name:             "table with special symbols",
-			sql:              `SELECT * FROM "user$data"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{`"user$data"`: "AccessShare"},
+			name:          "ROLLBACK also ends the hold",
+			sql:           "BEGIN;\nDROP TABLE old_users;\nSELECT 1;\nSELECT 2;\nSELECT 3;\nROLLBACK;\nSELECT 4;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "held through 3 later statements until ROLLBACK at line 6", "", "", "", "", ""},
 		},
+	}
 
-		// === Schema qualified identifiers ===
+	runTransactionCheckTests(t, Options{LongTransactionThreshold: 3}, tests, nil)
+}
+
+func TestAnalyzer_DDLDMLMixCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string // substring per statement, "" means no notes
+	}{
 		{
-			name:             "quoted schema and table",
-			sql:              `SELECT * FROM "public"."users"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{"public.users": "AccessShare"},
+			name:          "ALTER TABLE then UPDATE without WHERE",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN nickname text;\nUPDATE users SET nickname = name;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "runs in the same transaction as the ALTER TABLE ADD COLUMN without DEFAULT at line 2, whose AccessExclusive lock is held for the whole of this full-table UPDATE without WHERE", ""},
 		},
 		{
-			name:             "unquoted schema, quoted table",
-			sql:              `SELECT * FROM public."Users"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{`public."Users"`: "AccessShare"},
+			name:          "backfill then SET NOT NULL",
+			sql:           "BEGIN;\nUPDATE users SET nickname = name;\nALTER TABLE users ALTER COLUMN nickname SET NOT NULL;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "takes AccessExclusive in the same transaction as the UPDATE without WHERE at line 2", ""},
 		},
 		{
-			name:             "quoted schema, unquoted table",
-			sql:              `UPDATE "MySchema".users SET active = false`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityCritical,
-			expectedOp:       "UPDATE without WHERE",
-			expectedLocks:    map[string]string{`"MySchema".users`: "RowExclusive"},
+			name:          "INSERT SELECT after DROP TABLE",
+			sql:           "BEGIN;\nDROP TABLE users_old;\nINSERT INTO users_archive SELECT * FROM users;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "as the DROP TABLE at line 2", ""},
 		},
-
-		// === PostgreSQL reserved words as identifiers ===
 		{
-			name:             "reserved word USER",
-			sql:              `SELECT * FROM "user"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "SELECT",
-			expectedLocks:    map[string]string{`"user"`: "AccessShare"},
+			name:          "targeted UPDATE is not a mix",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN nickname text;\nUPDATE users SET nickname = name WHERE id = 1;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
 		},
 		{
-			name:             "reserved word ORDER",
-			sql:              `UPDATE "order" SET status = 'shipped' WHERE id = 1`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityWarning,
-			expectedOp:       "UPDATE with WHERE",
-			expectedLocks:    map[string]string{`"order"`: "RowExclusive"},
+			name:          "separate transactions",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN nickname text;\nCOMMIT;\nBEGIN;\nUPDATE users SET nickname = name;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", "", "", ""},
 		},
 		{
-			name:             "reserved word GROUP",
-			sql:              `INSERT INTO "group" (name) VALUES ('admins')`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "INSERT",
-			expectedLocks:    map[string]string{`"group"`: "RowExclusive"},
+			name:          "outside a transaction",
+			sql:           "ALTER TABLE users ADD COLUMN nickname text;\nUPDATE users SET nickname = name;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", ""},
 		},
 		{
-			name:             "reserved word TABLE",
-			sql:              `DELETE FROM "table" WHERE created < NOW() - INTERVAL '1 year'`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityWarning,
-			expectedOp:       "DELETE with WHERE",
-			expectedLocks:    map[string]string{`"table"`: "RowExclusive"},
+			name:          "implicit transaction",
+			sql:           "ALTER TABLE users ADD COLUMN nickname text;\nDELETE FROM sessions;",
+			mode:          InTransaction,
+			expectedNotes: []string{"", "as the ALTER TABLE ADD COLUMN without DEFAULT at line 1"},
 		},
 		{
-			name:             "reserved word SELECT as table",
-			sql:              `CREATE INDEX idx_select ON "select" (id)`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityCritical,
-			expectedOp:       "CREATE INDEX",
-			expectedLocks:    map[string]string{`"select"`: "Share"},
+			name:          "explicit LOCK TABLE is not a schema change",
+			sql:           "BEGIN;\nLOCK TABLE users IN ACCESS EXCLUSIVE MODE;\nUPDATE users SET nickname = name;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
 		},
+	}
+
+	runTransactionCheckTests(t, Options{CheckDDLDMLMix: true}, tests, nil)
+}
+
+func TestTransactions(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []Transaction
+	}{
 		{
-			name:             "reserved word WHERE as table",
-			sql:              `DROP TABLE "where"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityCritical,
-			expectedOp:       "DROP TABLE",
-			expectedLocks:    map[string]string{`"where"`: "AccessExclusive"},
+			name: "strongest lock per table across the block",
+			sql: "SELECT 1;\nBEGIN;\nUPDATE users SET active = true WHERE id = 1;\nALTER TABLE users ADD COLUMN nickname text;\n" +
+				"INSERT INTO orders (id) VALUES (1);\nSELECT * FROM accounts;\nCOMMIT;\nUPDATE users SET active = false;",
+			want: []Transaction{{
+				Start:        1,
+				End:          6,
+				EndOperation: "COMMIT",
+				LockType:     AccessExclusive,
+				Tables:       []string{"users", "orders", "accounts"},
+				TableLockTypes: map[string]LockType{
+					"users":    AccessExclusive,
+					"orders":   RowExclusive,
+					"accounts": AccessShare,
+				},
+			}},
+		},
+		{
+			name: "one entry per block",
+			sql:  "BEGIN;\nCREATE INDEX idx ON users (email);\nROLLBACK;\nSTART TRANSACTION;\nSELECT * FROM users;\nCOMMIT;",
+			want: []Transaction{
+				{Start: 0, End: 2, EndOperation: "ROLLBACK", LockType: Share, Tables: []string{"users"}, TableLockTypes: map[string]LockType{"users": Share}},
+				{Start: 3, End: 5, EndOperation: "COMMIT", LockType: AccessShare, Tables: []string{"users"}, TableLockTypes: map[string]LockType{"users": AccessShare}},
+			},
 		},
 		{
-			name:             "reserved word FROM as column",
-			sql:              `ALTER TABLE messages ADD COLUMN "from" text`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityInfo,
-			expectedOp:       "ALTER TABLE ADD COLUMN without DEFAULT",
-			expectedLocks:    map[string]string{"messages": "AccessExclusive"},
+			name: "open at the end of input, ERROR statements take no locks",
+			sql:  "BEGIN;\nCREATE INDEX CONCURRENTLY idx ON users (email);\nDELETE FROM sessions WHERE id = 1;",
+			want: []Transaction{{Start: 0, End: 2, LockType: RowExclusive, Tables: []string{"sessions"}, TableLockTypes: map[string]LockType{"sessions": RowExclusive}}},
 		},
 		{
-			name:             "reserved word CHECK as table",
-			sql:              `TRUNCATE "check"`,
-			mode:             InTransaction,
-			expectedSeverity: SeverityCritical,
-			expectedOp:       "TRUNCATE",
-			expectedLocks:    map[string]string{`"check"`: "AccessExclusive"},
+			name: "quoted table name containing a colon",
+			sql:  "BEGIN;\nUPDATE \"audit: events\" SET archived = true WHERE id = 1;\nCOMMIT;",
+			want: []Transaction{{Start: 0, End: 2, EndOperation: "COMMIT", LockType: RowExclusive, Tables: []string{`"audit: events"`}, TableLockTypes: map[string]LockType{`"audit: events"`: RowExclusive}}},
 		},
-
-		// === DDL operations with quoted identifiers ===
+		{
+			name: "no explicit transaction",
+			sql:  "ALTER TABLE users ADD COLUMN nickname text;\nCOMMIT;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+			results, err := New().Analyze(parsed, NoTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if got := Transactions(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transactions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_TableRowsDirective(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		options  Options
+		severity []Severity
+		notes    []string // substring per statement, "" means no notes
+	}{
+		{
+			name:     "small table is downgraded, large and unknown tables are kept",
+			sql:      "-- pg-lock-check:rows users=500, orders=2_000_000\nCREATE INDEX idx ON users (email);\nCREATE INDEX idx ON orders (id);\nCREATE INDEX idx ON accounts (id);",
+			severity: []Severity{SeverityWarning, SeverityCritical, SeverityCritical},
+			notes:    []string{"pg-lock-check:rows gives users: 500 rows (line 1), fewer than 10000, so the Share lock is held only briefly; reported as WARNING instead of CRITICAL", "", ""},
+		},
+		{
+			name:     "hints apply from the annotated statement on",
+			sql:      "UPDATE users SET active = false;\n/* pg-lock-check:rows users=10 */\nUPDATE users SET active = true;",
+			severity: []Severity{SeverityCritical, SeverityWarning},
+			notes:    []string{"", "users: 10 rows (line 2)"},
+		},
+		{
+			name:     "qualified and quoted names match the directive",
+			sql:      "-- pg-lock-check:rows users=10, Accounts=20, audit.events=30\nCREATE INDEX idx ON public.users (email);\nCREATE INDEX idx ON \"Accounts\" (id);\nCREATE INDEX idx ON audit.events (at);\nCREATE INDEX idx ON events (at);",
+			severity: []Severity{SeverityWarning, SeverityWarning, SeverityWarning, SeverityCritical},
+			notes:    []string{"public.users: 10 rows (line 1)", `"Accounts": 20 rows (line 1)`, "audit.events: 30 rows (line 1)", ""},
+		},
+		{
+			name:     "later hints replace earlier ones",
+			sql:      "-- pg-lock-check:rows users=10\nSELECT 1;\n-- pg-lock-check:rows users=50000\nCREATE INDEX idx ON users (email);",
+			severity: []Severity{SeverityInfo, SeverityCritical},
+			notes:    []string{"", ""},
+		},
+		{
+			name:     "operations that destroy data are kept",
+			sql:      "-- pg-lock-check:rows users=10\nDELETE FROM users;\nDROP TABLE users;",
+			severity: []Severity{SeverityCritical, SeverityCritical},
+			notes:    []string{"", ""},
+		},
+		{
+			name:     "max lock level still raises",
+			sql:      "-- pg-lock-check:rows users=10\nALTER TABLE users SET TABLESPACE fast;",
+			options:  Options{MaxLockLevel: ShareUpdateExclusive},
+			severity: []Severity{SeverityCritical},
+			notes:    []string{"stronger than the ShareUpdateExclusive maximum allowed by policy"},
+		},
+		{
+			name:     "unreadable entries are noted",
+			sql:      "-- pg-lock-check:rows users=many\nSELECT 1;",
+			severity: []Severity{SeverityInfo},
+			notes:    []string{`ignored pg-lock-check:rows entry "users=many" at line 1: expected table=rows`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+			results, err := NewWithOptions(tt.options).Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+			if len(results) != len(tt.severity) {
+				t.Fatalf("Expected %d results, got %d", len(tt.severity), len(results))
+			}
+
+			for i, result := range results {
+				if result.Severity != tt.severity[i] {
+					t.Errorf("Statement %d: expected %s, got %s", i, tt.severity[i], result.Severity)
+				}
+				notes := strings.Join(result.Notes(), "\n")
+				if tt.notes[i] == "" && notes != "" {
+					t.Errorf("Statement %d: expected no notes, got %q", i, notes)
+				}
+				if !strings.Contains(notes, tt.notes[i]) {
+					t.Errorf("Statement %d: expected a note containing %q, got %q", i, tt.notes[i], notes)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_LargeInsertCheck(t *testing.T) {
+	// insertValues builds an INSERT with the given number of VALUES rows
+	insertValues := func(rows int) string {
+		tuples := make([]string, rows)
+		for i := range tuples {
+			tuples[i] = fmt.Sprintf("(%d, 'user%d')", i, i)
+		}
+		return "INSERT INTO users (id, name) VALUES " + strings.Join(tuples, ", ")
+	}
+
+	tests := []struct {
+		name         string
+		sql          string
+		options      Options
+		expectedNote string
+	}{
+		{
+			name:         "at the threshold",
+			sql:          insertValues(1000),
+			options:      Options{LargeInsertRows: 1000},
+			expectedNote: "inserts 1000 rows in one statement, holding RowExclusive on users",
+		},
+		{
+			name:    "below the threshold",
+			sql:     insertValues(999),
+			options: Options{LargeInsertRows: 1000},
+		},
+		{
+			name:    "check disabled",
+			sql:     insertValues(5000),
+			options: Options{},
+		},
+		{
+			name:    "INSERT SELECT has no VALUES list",
+			sql:     "INSERT INTO users_archive SELECT * FROM users",
+			options: Options{LargeInsertRows: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := NewWithOptions(tt.options).AnalyzeStatement(parsed.Statements[0], InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			notes := strings.Join(result.Notes(), "\n")
+			if tt.expectedNote == "" {
+				if notes != "" {
+					t.Errorf("Expected no notes, got %v", result.Notes())
+				}
+				return
+			}
+			// The note surfaces the row count without raising the severity
+			if result.Severity != SeverityInfo {
+				t.Errorf("Expected severity INFO, got %s", result.Severity)
+			}
+			if !strings.Contains(notes, tt.expectedNote) || !strings.Contains(notes, "insert them in batches or load them with COPY") {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_RepeatedAlterCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string // substring per statement, "" means no notes
+	}{
+		{
+			name:          "second ALTER on the same table in a transaction",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN age int;\nALTER TABLE users ALTER COLUMN age TYPE bigint;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "users is already locked AccessExclusive by the ALTER TABLE at line 2 in this transaction", ""},
+		},
+		{
+			name:          "different tables",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN age int;\nALTER TABLE orders ADD COLUMN total int;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
+		},
+		{
+			name:          "COMMIT resets the tracking",
+			sql:           "BEGIN;\nALTER TABLE users ADD COLUMN age int;\nCOMMIT;\nBEGIN;\nALTER TABLE users ADD COLUMN nickname text;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", "", "", ""},
+		},
+		{
+			name:          "outside a transaction each ALTER commits on its own",
+			sql:           "ALTER TABLE users ADD COLUMN age int;\nALTER TABLE users ADD COLUMN nickname text;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", ""},
+		},
+	}
+
+	runTransactionCheckTests(t, Options{}, tests, nil)
+
+	// With transaction control ignored in a transaction, the whole input is one transaction
+	runTransactionCheckTests(t, Options{IgnoreTransactionControl: true}, []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string
+	}{
+		{
+			name:          "COMMIT does not end the single transaction",
+			sql:           "ALTER TABLE users ADD COLUMN age int;\nCOMMIT;\nBEGIN;\nALTER TABLE users ADD COLUMN nickname text;",
+			mode:          InTransaction,
+			expectedNotes: []string{"", "", "", "users is already locked AccessExclusive by the ALTER TABLE at line 1 in this transaction"},
+		},
+	}, nil)
+}
+
+func TestAnalyzer_ConcurrentIndexDMLCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string
+	}{
+		{
+			name:          "full-table UPDATE right after the build",
+			sql:           "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\nUPDATE users SET active = true;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "follows the CREATE INDEX CONCURRENTLY on users at line 1, so every row this full-table UPDATE without WHERE changes is also written to the new index"},
+		},
+		{
+			name:          "INSERT SELECT into the indexed table",
+			sql:           "CREATE UNIQUE INDEX CONCURRENTLY idx_users_email ON users (email);\nINSERT INTO users SELECT * FROM staging;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "follows the CREATE INDEX CONCURRENTLY on users at line 1"},
+		},
+		{
+			name:          "quoted table name containing a colon",
+			sql:           "CREATE INDEX CONCURRENTLY idx_events ON \"audit: events\" (created_at);\nUPDATE \"audit: events\" SET archived = true;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "follows the CREATE INDEX CONCURRENTLY on \"audit: events\" at line 1"},
+		},
+		{
+			name:          "schema-qualified in only one statement",
+			sql:           "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\nUPDATE public.users SET active = true;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "follows the CREATE INDEX CONCURRENTLY on users at line 1"},
+		},
+		{
+			name:          "same name in another schema",
+			sql:           "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\nUPDATE archive.users SET active = true;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", ""},
+		},
+		{
+			name:          "another table",
+			sql:           "CREATE INDEX CONCURRENTLY idx_orders_user_id ON orders (user_id);\nUPDATE users SET active = true;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", ""},
+		},
+		{
+			name:          "targeted UPDATE",
+			sql:           "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\nUPDATE users SET active = true WHERE id = 1;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", ""},
+		},
+		{
+			name:          "not immediately after the build",
+			sql:           "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\nSELECT 1;\nUPDATE users SET active = true;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", ""},
+		},
+	}
+
+	runTransactionCheckTests(t, Options{}, tests, nil)
+}
+
+func TestAnalyzer_IsolationCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		mode          TransactionMode
+		expectedNotes []string // substring per statement, "" means no notes
+	}{
+		{
+			name:          "SET TRANSACTION SERIALIZABLE before DDL",
+			sql:           "BEGIN;\nSET TRANSACTION ISOLATION LEVEL SERIALIZABLE;\nALTER TABLE users ADD COLUMN age int;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "runs under SERIALIZABLE isolation (set at line 2)", ""},
+		},
+		{
+			name:          "BEGIN ISOLATION LEVEL REPEATABLE READ",
+			sql:           "BEGIN ISOLATION LEVEL REPEATABLE READ;\nCREATE INDEX idx_users_email ON users (email);\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "runs under REPEATABLE READ isolation (set at line 1)", ""},
+		},
+		{
+			name:          "COMMIT ends the isolation level",
+			sql:           "BEGIN;\nSET TRANSACTION ISOLATION LEVEL SERIALIZABLE;\nCOMMIT;\nBEGIN;\nALTER TABLE users ADD COLUMN age int;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", "", "", ""},
+		},
+		{
+			name:          "session characteristics apply to later transactions",
+			sql:           "SET SESSION CHARACTERISTICS AS TRANSACTION ISOLATION LEVEL SERIALIZABLE;\nBEGIN;\nCOMMIT;\nALTER TABLE users ADD COLUMN age int;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", "runs under SERIALIZABLE isolation (set at line 1)"},
+		},
+		{
+			name:          "READ COMMITTED",
+			sql:           "BEGIN;\nSET TRANSACTION ISOLATION LEVEL READ COMMITTED;\nALTER TABLE users ADD COLUMN age int;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
+		},
+		{
+			name:          "weak locks are not noted",
+			sql:           "BEGIN;\nSET TRANSACTION ISOLATION LEVEL SERIALIZABLE;\nUPDATE users SET active = true WHERE id = 1;\nCOMMIT;",
+			mode:          NoTransaction,
+			expectedNotes: []string{"", "", "", ""},
+		},
+	}
+
+	runTransactionCheckTests(t, Options{}, tests, nil)
+}
+
+// ===== HELPER FUNCTIONS =====
+
+// runTransactionCheckTests analyzes multi-statement SQL with options and checks notes per statement;
+// a non-nil keep limits the comparison to the notes it accepts
+func runTransactionCheckTests(t *testing.T, options Options, tests []struct {
+	name          string
+	sql           string
+	mode          TransactionMode
+	expectedNotes []string
+}, keep func(note string) bool) {
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(options).Analyze(parsed, tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if len(results) != len(tt.expectedNotes) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expectedNotes), len(results))
+			}
+
+			for i, expectedNote := range tt.expectedNotes {
+				notes := results[i].Notes()
+				if keep != nil {
+					notes = slices.DeleteFunc(slices.Clone(notes), func(note string) bool { return !keep(note) })
+				}
+				if expectedNote == "" {
+					if len(notes) != 0 {
+						t.Errorf("Statement %d: expected no notes, got %v", i, notes)
+					}
+					continue
+				}
+
+				found := false
+				for _, note := range notes {
+					if strings.Contains(note, expectedNote) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("Statement %d: expected note containing %q, got %v", i, expectedNote, notes)
+				}
+			}
+		})
+	}
+}
+
+func runAnalyzerTests(t *testing.T, tests []struct {
+	name             string
+	sql              string
+	mode             TransactionMode
+	expectedSeverity Severity
+	expectedOp       string
+	expectedLocks    map[string]string
+}) {
+	a := New()
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := a.AnalyzeStatement(parsed.Statements[0], tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if result.Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %s, got %s", tt.expectedSeverity, result.Severity)
+			}
+
+			if tt.expectedOp != "" && result.Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %s, got %s", tt.expectedOp, result.Operation())
+			}
+
+			if tt.expectedLocks != nil {
+				for table, expectedLock := range tt.expectedLocks {
+					found := false
+					for _, tl := range result.TableLocks() {
+						if strings.Contains(tl, table) && strings.Contains(tl, expectedLock) {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Errorf("Expected lock %s on table %s, not found in %v",
+							expectedLock, table, result.TableLocks())
+					}
+				}
+			}
+		})
+	}
+}
+
+// runNoteTests checks the operation label and that a note containing expectedNote is reported.
+// An empty expectedNote asserts that no notes are reported.
+func runNoteTests(t *testing.T, options Options, tests []struct {
+	name         string
+	sql          string
+	mode         TransactionMode
+	expectedOp   string
+	expectedNote string
+}) {
+	a := NewWithOptions(options)
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := a.AnalyzeStatement(parsed.Statements[0], tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if tt.expectedOp != "" && result.Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %s, got %s", tt.expectedOp, result.Operation())
+			}
+
+			if tt.expectedNote == "" {
+				if len(result.Notes()) != 0 {
+					t.Errorf("Expected no notes, got %v", result.Notes())
+				}
+				return
+			}
+
+			for _, note := range result.Notes() {
+				if strings.Contains(note, tt.expectedNote) {
+					return
+				}
+			}
+			t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+		})
+	}
+}
+
+// ===== NOTES TEST =====
+
+func TestAnalyzer_Notes(t *testing.T) {
+	tests := []struct {
+		name         string
+		sql          string
+		mode         TransactionMode
+		expectedOp   string
+		expectedNote string
+	}{
+		{
+			name:         "CLUSTER without USING reuses the previous index",
+			sql:          "CLUSTER users",
+			mode:         InTransaction,
+			expectedOp:   "CLUSTER (previous index)",
+			expectedNote: "reclusters users on the index it was last clustered on",
+		},
+		{
+			name:         "CLUSTER without table sweeps the database",
+			sql:          "CLUSTER",
+			mode:         NoTransaction,
+			expectedOp:   "CLUSTER without table",
+			expectedNote: "reclusters every table in the database that was clustered before",
+		},
+		{
+			name:         "ADD COLUMN DEFAULT nextval consumes a value per row",
+			sql:          "ALTER TABLE users ADD COLUMN n int DEFAULT nextval('s')",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with volatile DEFAULT",
+			expectedNote: "the default calls nextval() for every existing row while the table is rewritten, consuming one value of sequence s per row",
+		},
+		{
+			name:         "ADD COLUMN DEFAULT nextval with a regclass cast",
+			sql:          "ALTER TABLE users ADD COLUMN n bigint DEFAULT pg_catalog.nextval('app.order_seq'::regclass)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with volatile DEFAULT",
+			expectedNote: "consuming one value of sequence app.order_seq per row",
+		},
+		{
+			name:         "TRUNCATE keeps sequences by default",
+			sql:          "TRUNCATE users",
+			mode:         InTransaction,
+			expectedOp:   "TRUNCATE",
+			expectedNote: "CONTINUE IDENTITY (the default) keeps the sequences owned by columns of the truncated tables",
+		},
+		{
+			name:         "TRUNCATE CONTINUE IDENTITY keeps sequences",
+			sql:          "TRUNCATE users CONTINUE IDENTITY",
+			mode:         InTransaction,
+			expectedOp:   "TRUNCATE",
+			expectedNote: "CONTINUE IDENTITY (the default) keeps the sequences",
+		},
+		{
+			name:         "TRUNCATE RESTART IDENTITY resets sequences",
+			sql:          "TRUNCATE users RESTART IDENTITY CASCADE",
+			mode:         NoTransaction,
+			expectedOp:   "TRUNCATE (restart identity)",
+			expectedNote: "RESTART IDENTITY resets the sequences owned by columns of the truncated tables, so new rows get ids from the start again",
+		},
+		{
+			name:         "NOTIFY is delivered on commit",
+			sql:          "NOTIFY ch, 'msg'",
+			mode:         InTransaction,
+			expectedOp:   "NOTIFY",
+			expectedNote: "delivered to listeners only when the transaction commits",
+		},
+		{
+			name:         "ADD PRIMARY KEY USING INDEX attaches briefly",
+			sql:          "ALTER TABLE users ADD CONSTRAINT pk PRIMARY KEY USING INDEX idx",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD PRIMARY KEY USING INDEX",
+			expectedNote: "held only briefly",
+		},
+		{
+			name:         "ADD CONSTRAINT UNIQUE USING INDEX attaches briefly",
+			sql:          "ALTER TABLE users ADD CONSTRAINT u UNIQUE USING INDEX idx",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD CONSTRAINT UNIQUE USING INDEX",
+			expectedNote: "held only briefly",
+		},
+		{
+			name:         "ADD COLUMN NOT NULL without DEFAULT needs an empty table",
+			sql:          "ALTER TABLE users ADD COLUMN status text NOT NULL",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedNote: "NOT NULL column status has no DEFAULT, so this fails unless the table is empty",
+		},
+		{
+			name:         "ADD COLUMN NOT NULL with DEFAULT fills existing rows",
+			sql:          "ALTER TABLE users ADD COLUMN status text NOT NULL DEFAULT 'new'",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with constant DEFAULT",
+			expectedNote: "",
+		},
+		{
+			name:         "ADD COLUMN NOT NULL DEFAULT NULL is no DEFAULT",
+			sql:          "ALTER TABLE users ADD COLUMN status text NOT NULL DEFAULT NULL::text",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with constant DEFAULT",
+			expectedNote: "NOT NULL column status has no DEFAULT, so this fails unless the table is empty",
+		},
+		{
+			name:         "ADD COLUMN bigserial NOT NULL has an implicit DEFAULT",
+			sql:          "ALTER TABLE users ADD COLUMN seq bigserial NOT NULL",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedNote: "",
+		},
+		{
+			name:         "ADD PRIMARY KEY fails on duplicates or NULLs",
+			sql:          "ALTER TABLE users ADD PRIMARY KEY (tenant_id, id)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD PRIMARY KEY",
+			expectedNote: "PRIMARY KEY fails if existing rows contain duplicate values or NULLs in (tenant_id, id)",
+		},
+		{
+			name:         "ADD CONSTRAINT UNIQUE fails on duplicates",
+			sql:          "ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD CONSTRAINT UNIQUE",
+			expectedNote: "UNIQUE fails if existing rows contain duplicate values in (email)",
+		},
+		{
+			name:         "CREATE DATABASE TEMPLATE needs no connections to the template",
+			sql:          "CREATE DATABASE x TEMPLATE y",
+			mode:         NoTransaction,
+			expectedOp:   "CREATE DATABASE",
+			expectedNote: "copying template y fails if any other session is connected to it",
+		},
+		{
+			name:         "VACUUM SKIP_LOCKED does not wait for locks",
+			sql:          "VACUUM (SKIP_LOCKED) users",
+			mode:         NoTransaction,
+			expectedOp:   "VACUUM (skip locked)",
+			expectedNote: "instead of waiting, so it never queues behind other sessions",
+		},
+		{
+			name:         "VACUUM FULL SKIP_LOCKED still rewrites under AccessExclusive",
+			sql:          "VACUUM (FULL, SKIP_LOCKED, ANALYZE) users",
+			mode:         NoTransaction,
+			expectedOp:   "VACUUM FULL (analyze, skip locked)",
+			expectedNote: "still held AccessExclusive for the whole rewrite",
+		},
+		{
+			name:         "VACUUM INDEX_CLEANUP off",
+			sql:          "VACUUM (INDEX_CLEANUP off, BUFFER_USAGE_LIMIT '256MB') users",
+			mode:         NoTransaction,
+			expectedOp:   "VACUUM",
+			expectedNote: "INDEX_CLEANUP off skips index vacuuming",
+		},
+		{
+			name:         "COPY FROM PROGRAM runs a server command",
+			sql:          "COPY users FROM PROGRAM 'curl -s https://example.com/users.csv' CSV",
+			mode:         InTransaction,
+			expectedOp:   "COPY FROM PROGRAM",
+			expectedNote: "runs the shell command 'curl -s https://example.com/users.csv' on the database server",
+		},
+		{
+			name:         "COPY TO PROGRAM requires superuser",
+			sql:          "COPY (SELECT * FROM users) TO PROGRAM 'gzip > /tmp/users.csv.gz'",
+			mode:         InTransaction,
+			expectedOp:   "COPY TO PROGRAM",
+			expectedNote: "requires superuser or pg_execute_server_program",
+		},
+		{
+			name:         "REINDEX CONCURRENTLY DATABASE skips catalogs",
+			sql:          "REINDEX (VERBOSE, CONCURRENTLY) DATABASE mydb",
+			mode:         NoTransaction,
+			expectedOp:   "REINDEX DATABASE CONCURRENTLY",
+			expectedNote: "system catalogs cannot be reindexed concurrently and are skipped",
+		},
+		{
+			name:         "REINDEX CONCURRENTLY SYSTEM is rejected",
+			sql:          "REINDEX (CONCURRENTLY) SYSTEM mydb",
+			mode:         NoTransaction,
+			expectedOp:   "REINDEX SYSTEM",
+			expectedNote: "PostgreSQL rejects this statement",
+		},
+		{
+			name:         "REINDEX CONCURRENTLY on a catalog table is rejected",
+			sql:          "REINDEX (CONCURRENTLY) TABLE pg_catalog.pg_class",
+			mode:         NoTransaction,
+			expectedOp:   "REINDEX CONCURRENTLY",
+			expectedNote: "PostgreSQL rejects this statement",
+		},
+		{
+			name:         "REINDEX VERBOSE has no notes",
+			sql:          "REINDEX (VERBOSE) TABLE users",
+			mode:         InTransaction,
+			expectedOp:   "REINDEX TABLE",
+			expectedNote: "",
+		},
+		{
+			name:         "CREATE TABLE LIKE INCLUDING ALL",
+			sql:          "CREATE TABLE t (LIKE src INCLUDING ALL)",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TABLE",
+			expectedNote: "copies column definitions from src including all properties",
+		},
+		{
+			name:         "CREATE TABLE LIKE with selected options",
+			sql:          "CREATE TABLE t (LIKE src INCLUDING DEFAULTS INCLUDING INDEXES)",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TABLE",
+			expectedNote: "copies column definitions from src including defaults, indexes;",
+		},
+		{
+			name:         "ALTER COLUMN TYPE warns about dependent views",
+			sql:          "ALTER TABLE users ALTER COLUMN age TYPE BIGINT",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ALTER COLUMN TYPE",
+			expectedNote: "fails if a view or rule depends on this column",
+		},
+		{
+			name:         "DROP COLUMN warns about dependent views",
+			sql:          "ALTER TABLE users DROP COLUMN age",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE DROP COLUMN",
+			expectedNote: "fails if a view or rule depends on this column",
+		},
+		{
+			name:         "DROP COLUMN CASCADE drops dependent views",
+			sql:          "ALTER TABLE users DROP COLUMN age CASCADE",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE DROP COLUMN",
+			expectedNote: "CASCADE also drops views, rules and constraints that depend on this column",
+		},
+		{
+			name:         "CREATE OR REPLACE VIEW can only append columns",
+			sql:          "CREATE OR REPLACE VIEW active_users AS SELECT id, email FROM users WHERE active",
+			mode:         InTransaction,
+			expectedOp:   "CREATE OR REPLACE VIEW",
+			expectedNote: "can only append new output columns",
+		},
+		{
+			name:         "plain index has no notes",
+			sql:          "CREATE INDEX idx ON users(email)",
+			mode:         InTransaction,
+			expectedOp:   "CREATE INDEX",
+			expectedNote: "",
+		},
+		{
+			name:         "expression index must be immutable",
+			sql:          "CREATE INDEX idx ON users(lower(email))",
+			mode:         InTransaction,
+			expectedOp:   "CREATE INDEX (expression)",
+			expectedNote: "lower(); it must be IMMUTABLE",
+		},
+		{
+			name:         "expression index with volatile function",
+			sql:          "CREATE INDEX idx ON events((created_at < now()))",
+			mode:         InTransaction,
+			expectedOp:   "CREATE INDEX (expression)",
+			expectedNote: "calls now(), which is not IMMUTABLE",
+		},
+		{
+			name:         "expression index with schema-qualified volatile function",
+			sql:          "CREATE INDEX idx ON events((pg_catalog.random() < 0.5))",
+			mode:         InTransaction,
+			expectedOp:   "CREATE INDEX (expression)",
+			expectedNote: "calls random(), which is not IMMUTABLE",
+		},
+		{
+			name:         "function name containing a volatile name is not volatile",
+			sql:          "CREATE INDEX idx ON events(random_bucket(id))",
+			mode:         InTransaction,
+			expectedOp:   "CREATE INDEX (expression)",
+			expectedNote: "random_bucket(); it must be IMMUTABLE",
+		},
+		{
+			name:         "partition of suggests attach",
+			sql:          "CREATE TABLE m_2024 PARTITION OF m FOR VALUES FROM (2024) TO (2025)",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TABLE PARTITION OF",
+			expectedNote: "use ALTER TABLE m ATTACH PARTITION",
+		},
+		{
+			name:         "partitioned parent has no notes",
+			sql:          "CREATE TABLE m (id INT) PARTITION BY LIST (id)",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TABLE (partitioned)",
+			expectedNote: "",
+		},
+		{
+			name:         "column-scoped analyze lists columns",
+			sql:          "ANALYZE users (email, name)",
+			mode:         InTransaction,
+			expectedOp:   "ANALYZE",
+			expectedNote: "limited to columns email, name of users",
+		},
+		{
+			name:         "database-wide analyze",
+			sql:          "ANALYZE",
+			mode:         NoTransaction,
+			expectedOp:   "ANALYZE",
+			expectedNote: "analyzes every table in the current database",
+		},
+		{
+			name:         "table analyze has no notes",
+			sql:          "ANALYZE users",
+			mode:         InTransaction,
+			expectedOp:   "ANALYZE",
+			expectedNote: "",
+		},
+		{
+			name:         "drop type cascade",
+			sql:          "DROP TYPE mood CASCADE",
+			mode:         InTransaction,
+			expectedOp:   "DROP TYPE (cascade)",
+			expectedNote: "CASCADE also drops every dependent object",
+		},
+		{
+			name:         "drop table cascade",
+			sql:          "DROP TABLE t CASCADE",
+			mode:         InTransaction,
+			expectedOp:   "DROP TABLE (cascade)",
+			expectedNote: "CASCADE also drops every dependent object",
+		},
+		{
+			name:         "row-level trigger",
+			sql:          "CREATE TRIGGER audit AFTER INSERT OR UPDATE ON users FOR EACH ROW EXECUTE FUNCTION audit()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "AFTER INSERT OR UPDATE FOR EACH ROW trigger runs once per affected row, adding per-row overhead to every such write on users",
+		},
+		{
+			name:         "statement-level trigger",
+			sql:          "CREATE TRIGGER refresh BEFORE DELETE ON users FOR EACH STATEMENT EXECUTE FUNCTION refresh()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "BEFORE DELETE FOR EACH STATEMENT trigger runs once per statement however many rows it affects",
+		},
+		{
+			name:         "trigger default is statement-level",
+			sql:          "CREATE TRIGGER refresh AFTER TRUNCATE ON users EXECUTE FUNCTION refresh()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "AFTER TRUNCATE FOR EACH STATEMENT trigger",
+		},
+		{
+			name:         "INSTEAD OF trigger",
+			sql:          "CREATE TRIGGER write_through INSTEAD OF UPDATE ON active_users FOR EACH ROW EXECUTE FUNCTION write_through()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "INSTEAD OF UPDATE FOR EACH ROW trigger",
+		},
+		{
+			name:         "constraint trigger",
+			sql:          "CREATE CONSTRAINT TRIGGER check_balance AFTER UPDATE ON accounts FOR EACH ROW EXECUTE FUNCTION check_balance()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "constraint trigger (AFTER UPDATE FOR EACH ROW) runs once per affected row at the end of each statement",
+		},
+		{
+			name:         "deferred constraint trigger",
+			sql:          "CREATE CONSTRAINT TRIGGER check_balance AFTER UPDATE ON accounts DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION check_balance()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "is INITIALLY DEFERRED: every row event is queued until COMMIT",
+		},
+		{
+			name:         "trigger creation lock",
+			sql:          "CREATE TRIGGER audit AFTER INSERT ON app.users FOR EACH ROW EXECUTE FUNCTION audit()",
+			mode:         InTransaction,
+			expectedOp:   "CREATE TRIGGER",
+			expectedNote: "creating the trigger itself takes ShareRowExclusive on app.users",
+		},
+		{
+			name:         "drop table if exists",
+			sql:          "DROP TABLE IF EXISTS t",
+			mode:         InTransaction,
+			expectedOp:   "DROP TABLE (if exists)",
+			expectedNote: "IF EXISTS makes the drop idempotent",
+		},
+		{
+			name:         "drop schema cascade keeps its operation",
+			sql:          "DROP SCHEMA app CASCADE",
+			mode:         InTransaction,
+			expectedOp:   "DROP SCHEMA CASCADE",
+			expectedNote: "CASCADE drops every object in schema app",
+		},
+		{
+			name:         "drop type restrict has no notes",
+			sql:          "DROP TYPE mood",
+			mode:         InTransaction,
+			expectedOp:   "DROP TYPE",
+			expectedNote: "",
+		},
+		{
+			name:         "domain constraint revalidates dependent tables",
+			sql:          "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER DOMAIN ADD CONSTRAINT",
+			expectedNote: "add the constraint NOT VALID and validate it separately",
+		},
+		{
+			name:         "foreign key NOT VALID skips existing rows",
+			sql:          "ALTER TABLE orders ADD CONSTRAINT fk FOREIGN KEY (a) REFERENCES other(id) NOT VALID",
+			mode:         NoTransaction,
+			expectedOp:   "ALTER TABLE ADD FOREIGN KEY NOT VALID",
+			expectedNote: "run ALTER TABLE ... VALIDATE CONSTRAINT afterwards",
+		},
+		{
+			name:         "inline REFERENCES in ADD COLUMN",
+			sql:          "ALTER TABLE orders ADD COLUMN owner_id int REFERENCES users(id)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with FOREIGN KEY",
+			expectedNote: "inline REFERENCES users is validated while the AccessExclusive lock is held",
+		},
+		{
+			name:         "ADD IDENTITY starts its sequence at 1",
+			sql:          "ALTER TABLE users ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ALTER COLUMN ADD IDENTITY",
+			expectedNote: "moved past MAX(id) with ALTER COLUMN id RESTART WITH",
+		},
+		{
+			name:         "DROP IDENTITY may break inserts",
+			sql:          "ALTER TABLE users ALTER COLUMN id DROP IDENTITY IF EXISTS",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ALTER COLUMN DROP IDENTITY",
+			expectedNote: "INSERTs that omit id will fail its NOT NULL constraint",
+		},
+		{
+			name:         "RESTART identity",
+			sql:          "ALTER TABLE users ALTER COLUMN id RESTART WITH 100",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ALTER COLUMN SET IDENTITY",
+			expectedNote: "RESTART changes the next value of the identity sequence of id",
+		},
+		{
+			name:         "SET GENERATED ALWAYS",
+			sql:          "ALTER TABLE users ALTER COLUMN id SET GENERATED ALWAYS",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ALTER COLUMN SET IDENTITY",
+			expectedNote: "unless they use OVERRIDING SYSTEM VALUE",
+		},
+		{
+			name:         "SET GENERATED BY DEFAULT has no note",
+			sql:          "ALTER TABLE users ALTER COLUMN id SET GENERATED BY DEFAULT",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ALTER COLUMN SET IDENTITY",
+			expectedNote: "",
+		},
+		{
+			name:         "inline PRIMARY KEY in ADD COLUMN",
+			sql:          "ALTER TABLE orders ADD COLUMN id bigint PRIMARY KEY",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with PRIMARY KEY",
+			expectedNote: "inline PRIMARY KEY builds a unique index on id while the AccessExclusive lock is held",
+		},
+		{
+			name:         "inline UNIQUE with REFERENCES in ADD COLUMN",
+			sql:          "ALTER TABLE orders ADD COLUMN owner_id int UNIQUE REFERENCES users(id)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ADD COLUMN with UNIQUE",
+			expectedNote: "CREATE UNIQUE INDEX CONCURRENTLY and ALTER TABLE ... ADD CONSTRAINT ... UNIQUE USING INDEX",
+		},
+		{
+			name:         "pg_advisory_lock waits and is held by the session",
+			sql:          "SELECT pg_advisory_lock(123)",
+			mode:         InTransaction,
+			expectedOp:   "SELECT pg_advisory_lock",
+			expectedNote: "pg_advisory_lock() waits until no other session holds the advisory lock",
+		},
+		{
+			name:         "pg_try_advisory_lock does not wait",
+			sql:          "SELECT pg_try_advisory_lock(123)",
+			mode:         InTransaction,
+			expectedOp:   "SELECT pg_try_advisory_lock",
+			expectedNote: "pg_try_advisory_lock() returns false instead of waiting",
+		},
+		{
+			name:         "pg_advisory_xact_lock is released at transaction end",
+			sql:          "SELECT pg_advisory_xact_lock(hashtext('deploy'))",
+			mode:         InTransaction,
+			expectedOp:   "SELECT pg_advisory_lock (transaction)",
+			expectedNote: "released at the end of the transaction",
+		},
+		{
+			name:         "pg_advisory_unlock is a plain SELECT",
+			sql:          "SELECT pg_advisory_unlock(123)",
+			mode:         InTransaction,
+			expectedOp:   "SELECT",
+			expectedNote: "",
+		},
+		{
+			name:         "domain constraint NOT VALID skips existing values",
+			sql:          "ALTER DOMAIN email ADD CONSTRAINT email_length CHECK (LENGTH(VALUE) <= 255) NOT VALID",
+			mode:         InTransaction,
+			expectedOp:   "ALTER DOMAIN ADD CONSTRAINT NOT VALID",
+			expectedNote: "existing values are not checked",
+		},
+		{
+			name:         "domain validate scans dependent tables",
+			sql:          "ALTER DOMAIN email VALIDATE CONSTRAINT email_length",
+			mode:         InTransaction,
+			expectedOp:   "ALTER DOMAIN VALIDATE CONSTRAINT",
+			expectedNote: "holding a Share lock on each table with a column of this type",
+		},
+		{
+			name:         "domain drop constraint has no notes",
+			sql:          "ALTER DOMAIN email DROP CONSTRAINT email_length",
+			mode:         InTransaction,
+			expectedOp:   "ALTER DOMAIN DROP CONSTRAINT",
+			expectedNote: "",
+		},
+		{
+			name:         "schema rename references",
+			sql:          "ALTER SCHEMA app RENAME TO app_v2",
+			mode:         InTransaction,
+			expectedOp:   "ALTER SCHEMA RENAME TO",
+			expectedNote: "locks only the schema, not its tables",
+		},
+		{
+			name:         "disabling autovacuum",
+			sql:          "ALTER TABLE users SET (autovacuum_enabled = false)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE SET",
+			expectedNote: "disables autovacuum on this table",
+		},
+		{
+			name:         "disabling autovacuum on TOAST",
+			sql:          "ALTER TABLE users SET (toast.autovacuum_enabled = off)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE SET",
+			expectedNote: "disables autovacuum on this table's TOAST relation",
+		},
+		{
+			name:         "autovacuum tuning",
+			sql:          "ALTER TABLE users SET (autovacuum_vacuum_scale_factor = 0.01)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE SET",
+			expectedNote: "changes autovacuum tuning (autovacuum_vacuum_scale_factor)",
+		},
+		{
+			name:         "enabling autovacuum has no note",
+			sql:          "ALTER TABLE users SET (autovacuum_enabled = true)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE SET",
+			expectedNote: "",
+		},
+		{
+			name:         "fillfactor has no note",
+			sql:          "ALTER TABLE users SET (fillfactor = 70)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE SET",
+			expectedNote: "",
+		},
+		{
+			name:         "resetting autovacuum",
+			sql:          "ALTER TABLE users RESET (autovacuum_enabled)",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE RESET",
+			expectedNote: "restores the default autovacuum setting",
+		},
+		{
+			name:         "ENABLE ROW LEVEL SECURITY warns about missing policies",
+			sql:          "ALTER TABLE accounts ENABLE ROW LEVEL SECURITY",
+			mode:         InTransaction,
+			expectedOp:   "ALTER TABLE ENABLE ROW LEVEL SECURITY",
+			expectedNote: "denies all access to every role but the owner; create the policies first",
+		},
+		{
+			name:         "MERGE WHEN MATCHED THEN DELETE",
+			sql:          "MERGE INTO users USING staging ON users.id = staging.id WHEN MATCHED THEN DELETE",
+			mode:         InTransaction,
+			expectedOp:   "MERGE without WHERE (delete)",
+			expectedNote: "WHEN MATCHED THEN DELETE removes every row of users matching the source, as impactful as DELETE without WHERE",
+		},
+		{
+			name:         "MERGE WHEN NOT MATCHED BY SOURCE THEN DELETE",
+			sql:          "MERGE INTO users USING staging ON users.id = staging.id WHEN NOT MATCHED BY SOURCE THEN DELETE",
+			mode:         InTransaction,
+			expectedOp:   "MERGE without WHERE (delete)",
+			expectedNote: "removes every row of users missing from the source",
+		},
+		{
+			name:         "other settings have no note",
+			sql:          "SET work_mem = '256MB'",
+			mode:         InTransaction,
+			expectedOp:   "SET",
+			expectedNote: "",
+		},
+	}
+
+	runNoteTests(t, Options{}, tests)
+}
+
+func TestAnalyzer_SearchPathNotes(t *testing.T) {
+	tests := []struct {
+		name         string
+		sql          string
+		mode         TransactionMode
+		expectedOp   string
+		expectedNote string
+	}{
+		{
+			name:         "SET search_path",
+			sql:          "SET search_path = app, public",
+			mode:         InTransaction,
+			expectedOp:   "SET",
+			expectedNote: "later unqualified names resolve under search_path app, public; pg-lock-check reports them as written",
+		},
+		{
+			name:         "SET LOCAL search_path",
+			sql:          `SET LOCAL search_path TO "$user", public`,
+			mode:         InTransaction,
+			expectedOp:   "SET LOCAL",
+			expectedNote: "search_path $user, public",
+		},
+		{
+			name:         "SET search_path to DEFAULT",
+			sql:          "SET search_path = DEFAULT",
+			mode:         InTransaction,
+			expectedOp:   "RESET",
+			expectedNote: "later unqualified names resolve under the default search_path;",
+		},
+		{
+			name:         "RESET search_path",
+			sql:          "RESET search_path",
+			mode:         InTransaction,
+			expectedOp:   "RESET",
+			expectedNote: "resolve under the default search_path",
+		},
+		{
+			name:         "empty search_path",
+			sql:          "SET search_path = ''",
+			mode:         InTransaction,
+			expectedOp:   "SET",
+			expectedNote: "later unqualified names resolve under an empty search_path;",
+		},
+	}
+
+	runNoteTests(t, Options{Verbose: true}, tests)
+
+	// The note only shows in verbose output
+	runNoteTests(t, Options{}, []struct {
+		name         string
+		sql          string
+		mode         TransactionMode
+		expectedOp   string
+		expectedNote string
+	}{
+		{
+			name:         "SET search_path without Verbose",
+			sql:          "SET search_path = app, public",
+			mode:         InTransaction,
+			expectedOp:   "SET",
+			expectedNote: "",
+		},
+	})
+}
+
+func TestAnalyzer_MixedAlterTableLocks(t *testing.T) {
+	tests := []struct {
+		name             string
+		sql              string
+		expectedOp       string
+		expectedSeverity Severity
+		expectedLock     LockType
+		expectedLocks    []string
+	}{
+		{
+			name:             "strongest lock after a weaker subcommand",
+			sql:              "ALTER TABLE users ALTER COLUMN email SET STATISTICS 1000, ALTER COLUMN id TYPE bigint",
+			expectedOp:       "ALTER TABLE ALTER COLUMN TYPE",
+			expectedSeverity: SeverityCritical,
+			expectedLock:     AccessExclusive,
+			expectedLocks:    []string{"users: AccessExclusive"},
+		},
+		{
+			name:             "strongest lock before a weaker subcommand",
+			sql:              "ALTER TABLE users ALTER COLUMN id TYPE bigint, ALTER COLUMN email SET STATISTICS 1000",
+			expectedOp:       "ALTER TABLE ALTER COLUMN TYPE",
+			expectedSeverity: SeverityCritical,
+			expectedLock:     AccessExclusive,
+			expectedLocks:    []string{"users: AccessExclusive"},
+		},
+		{
+			name:             "most severe among equal locks",
+			sql:              "ALTER TABLE users ADD COLUMN note text, ALTER COLUMN id TYPE bigint",
+			expectedOp:       "ALTER TABLE ALTER COLUMN TYPE",
+			expectedSeverity: SeverityCritical,
+			expectedLock:     AccessExclusive,
+			expectedLocks:    []string{"users: AccessExclusive"},
+		},
+		{
+			name:             "most severe subcommand with a weaker lock than another",
+			sql:              "ALTER TABLE orders ADD CONSTRAINT fk FOREIGN KEY (uid) REFERENCES users(id), ADD COLUMN note text",
+			expectedOp:       "ALTER TABLE ADD FOREIGN KEY",
+			expectedSeverity: SeverityWarning,
+			expectedLock:     AccessExclusive,
+			expectedLocks:    []string{"orders: AccessExclusive", "users: RowShare"},
+		},
+		{
+			name:             "referenced tables of every subcommand are locked",
+			sql:              "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID, ALTER COLUMN id TYPE bigint",
+			expectedOp:       "ALTER TABLE ALTER COLUMN TYPE",
+			expectedSeverity: SeverityCritical,
+			expectedLock:     AccessExclusive,
+			expectedLocks:    []string{"orders: AccessExclusive", "users: RowShare"},
+		},
+	}
+
+	p := parser.NewParser()
+	a := New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			result, err := a.AnalyzeStatement(parsed.Statements[0], InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if result.Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %q, got %q", tt.expectedOp, result.Operation())
+			}
+			if result.Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, result.Severity)
+			}
+			if result.LockType() != tt.expectedLock {
+				t.Errorf("Expected lock %s, got %s", tt.expectedLock, result.LockType())
+			}
+			tableLocks := result.TableLocks()
+			sort.Strings(tableLocks)
+			if !reflect.DeepEqual(tableLocks, tt.expectedLocks) {
+				t.Errorf("Expected table locks %v, got %v", tt.expectedLocks, tableLocks)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_RoutineBody(t *testing.T) {
+	tests := []struct {
+		name                string
+		sql                 string
+		mode                TransactionMode
+		expectedOp          string
+		expectedSeverity    Severity
+		expectedErrorReason ErrorReason
+		expectedNote        string
+	}{
+		{
+			name:                "plpgsql function body with VACUUM",
+			sql:                 "CREATE FUNCTION cleanup() RETURNS void LANGUAGE plpgsql AS $$ BEGIN VACUUM logs; END $$",
+			mode:                NoTransaction,
+			expectedOp:          "CREATE FUNCTION",
+			expectedSeverity:    SeverityError,
+			expectedErrorReason: ErrorReasonCannotRunInRoutine,
+			expectedNote:        "the body runs VACUUM, which cannot be executed inside a function or procedure",
+		},
+		{
+			name:                "plpgsql statement in a nested block",
+			sql:                 "CREATE PROCEDURE reindex() LANGUAGE plpgsql AS $$ BEGIN IF true THEN CREATE INDEX CONCURRENTLY idx_logs_at ON logs (at); END IF; END $$",
+			mode:                InTransaction,
+			expectedOp:          "CREATE PROCEDURE",
+			expectedSeverity:    SeverityError,
+			expectedErrorReason: ErrorReasonCannotRunInRoutine,
+			expectedNote:        "the body runs CREATE INDEX CONCURRENTLY",
+		},
+		{
+			name:                "SQL function body",
+			sql:                 "CREATE FUNCTION cleanup() RETURNS void LANGUAGE sql AS 'VACUUM FULL logs'",
+			mode:                InTransaction,
+			expectedOp:          "CREATE FUNCTION",
+			expectedSeverity:    SeverityError,
+			expectedErrorReason: ErrorReasonCannotRunInRoutine,
+			expectedNote:        "the body runs VACUUM FULL",
+		},
+		{
+			name:             "body without such statements",
+			sql:              "CREATE FUNCTION touch() RETURNS void LANGUAGE plpgsql AS $$ BEGIN UPDATE logs SET at = now() WHERE id = 1; PERFORM pg_sleep(1); END $$",
+			mode:             InTransaction,
+			expectedOp:       "CREATE FUNCTION",
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "dynamic EXECUTE is not checked",
+			sql:              "CREATE FUNCTION cleanup() RETURNS void LANGUAGE plpgsql AS $$ BEGIN EXECUTE 'VACUUM logs'; END $$",
+			mode:             InTransaction,
+			expectedOp:       "CREATE FUNCTION",
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:                "CALL of a procedure whose body cannot run",
+			sql:                 "CREATE PROCEDURE app.cleanup() LANGUAGE plpgsql AS $$ BEGIN VACUUM logs; END $$;\nCALL app.cleanup();",
+			mode:                NoTransaction,
+			expectedOp:          "CALL",
+			expectedSeverity:    SeverityError,
+			expectedErrorReason: ErrorReasonCannotRunInRoutine,
+			expectedNote:        "app.cleanup runs VACUUM, which cannot be executed inside a procedure",
+		},
+		{
+			name:                "schema-qualified CALL of a procedure created unqualified",
+			sql:                 "CREATE PROCEDURE cleanup() LANGUAGE plpgsql AS $$ BEGIN VACUUM logs; END $$;\nCALL public.cleanup();",
+			mode:                NoTransaction,
+			expectedOp:          "CALL",
+			expectedSeverity:    SeverityError,
+			expectedErrorReason: ErrorReasonCannotRunInRoutine,
+			expectedNote:        "public.cleanup runs VACUUM",
+		},
+		{
+			name:             "CALL after the body is replaced",
+			sql:              "CREATE PROCEDURE cleanup() LANGUAGE plpgsql AS $$ BEGIN VACUUM logs; END $$;\nCREATE OR REPLACE PROCEDURE cleanup() LANGUAGE plpgsql AS $$ BEGIN DELETE FROM logs WHERE at < now(); END $$;\nCALL cleanup();",
+			mode:             NoTransaction,
+			expectedOp:       "CALL",
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "CALL of an unknown procedure",
+			sql:              "CALL cleanup()",
+			mode:             InTransaction,
+			expectedOp:       "CALL",
+			expectedSeverity: SeverityInfo,
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := New().Analyze(parsed, tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			result := results[len(results)-1]
+			if result.Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %q, got %q", tt.expectedOp, result.Operation())
+			}
+			if result.Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, result.Severity)
+			}
+			if result.ErrorReason() != tt.expectedErrorReason {
+				t.Errorf("Expected error reason %q, got %q", tt.expectedErrorReason, result.ErrorReason())
+			}
+			notes := strings.Join(result.Notes(), "\n")
+			if tt.expectedNote == "" && notes != "" {
+				t.Errorf("Expected no notes, got %v", result.Notes())
+			}
+			if !strings.Contains(notes, tt.expectedNote) {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+			}
+		})
+	}
+
+	// Statement splitting stops at the semicolons inside BEGIN ATOMIC, so parse the statement whole
+	t.Run("BEGIN ATOMIC body", func(t *testing.T) {
+		stmt, err := p.ParseStatement("CREATE PROCEDURE setup() BEGIN ATOMIC INSERT INTO logs VALUES (1); ALTER SYSTEM SET work_mem = '64MB'; END", 1)
+		if err != nil {
+			t.Fatalf("Failed to parse SQL: %v", err)
+		}
+
+		result, err := New().AnalyzeStatement(*stmt, InTransaction)
+		if err != nil {
+			t.Fatalf("Failed to analyze: %v", err)
+		}
+		if result.Severity != SeverityError || result.ErrorReason() != ErrorReasonCannotRunInRoutine {
+			t.Errorf("Expected ERROR %q, got %v %q", ErrorReasonCannotRunInRoutine, result.Severity, result.ErrorReason())
+		}
+		if notes := strings.Join(result.Notes(), "\n"); !strings.Contains(notes, "the body runs ALTER SYSTEM") {
+			t.Errorf("Expected note about ALTER SYSTEM, got %v", result.Notes())
+		}
+	})
+}
+
+func TestAnalyzer_PreparedStatements(t *testing.T) {
+	tests := []struct {
+		name               string
+		sql                string
+		expectedOps        []string
+		expectedSeverities []Severity
+		expectedNotes      []string // substring per statement, "" means no notes
+	}{
+		{
+			name:               "PREPARE and EXECUTE carry the prepared DELETE",
+			sql:                "PREPARE p AS DELETE FROM t;\nEXECUTE p;",
+			expectedOps:        []string{"DELETE without WHERE (prepared as p)", "DELETE without WHERE (executes p)"},
+			expectedSeverities: []Severity{SeverityCritical, SeverityCritical},
+			expectedNotes: []string{
+				"PREPARE only defines p; these locks are taken each time EXECUTE p runs it",
+				"runs the statement prepared as p at line 1",
+			},
+		},
+		{
+			name:               "parameters do not hide the WHERE clause",
+			sql:                "PREPARE q (int) AS UPDATE t SET x = 1 WHERE id = $1;\nEXECUTE q(1);",
+			expectedOps:        []string{"UPDATE with WHERE (prepared as q)", "UPDATE with WHERE (executes q)"},
+			expectedSeverities: []Severity{SeverityWarning, SeverityWarning},
+			expectedNotes:      []string{"PREPARE only defines q", "prepared as q at line 1"},
+		},
+		{
+			name:               "DEALLOCATE forgets the prepared statement",
+			sql:                "PREPARE p AS DELETE FROM t;\nDEALLOCATE p;\nEXECUTE p;",
+			expectedOps:        []string{"DELETE without WHERE (prepared as p)", "DEALLOCATE", "EXECUTE"},
+			expectedSeverities: []Severity{SeverityCritical, SeverityInfo, SeverityInfo},
+			expectedNotes:      []string{"PREPARE only defines p", "", "p is not prepared earlier in the input"},
+		},
+		{
+			name:               "DEALLOCATE ALL forgets every prepared statement",
+			sql:                "PREPARE p AS DELETE FROM t;\nDEALLOCATE ALL;\nEXECUTE p;",
+			expectedOps:        []string{"DELETE without WHERE (prepared as p)", "DEALLOCATE", "EXECUTE"},
+			expectedSeverities: []Severity{SeverityCritical, SeverityInfo, SeverityInfo},
+			expectedNotes:      []string{"PREPARE only defines p", "", "p is not prepared earlier in the input"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewParser().ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := New().Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+			if len(results) != len(tt.expectedOps) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expectedOps), len(results))
+			}
+
+			for i, result := range results {
+				if result.Operation() != tt.expectedOps[i] {
+					t.Errorf("Statement %d: expected operation %q, got %q", i+1, tt.expectedOps[i], result.Operation())
+				}
+				if result.Severity != tt.expectedSeverities[i] {
+					t.Errorf("Statement %d: expected severity %v, got %v", i+1, tt.expectedSeverities[i], result.Severity)
+				}
+				notes := strings.Join(result.Notes(), "\n")
+				if tt.expectedNotes[i] == "" && notes != "" {
+					t.Errorf("Statement %d: expected no notes, got %v", i+1, result.Notes())
+				}
+				if !strings.Contains(notes, tt.expectedNotes[i]) {
+					t.Errorf("Statement %d: expected note containing %q, got %v", i+1, tt.expectedNotes[i], result.Notes())
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_TreatIfExistsAsWarning(t *testing.T) {
+	tests := []struct {
+		name             string
+		sql              string
+		options          Options
+		expectedOp       string
+		expectedSeverity Severity
+	}{
+		{
+			name:             "guarded drop stays CRITICAL by default",
+			sql:              "DROP TABLE IF EXISTS t",
+			expectedOp:       "DROP TABLE (if exists)",
+			expectedSeverity: SeverityCritical,
+		},
+		{
+			name:             "guarded drop is downgraded",
+			sql:              "DROP TABLE IF EXISTS t",
+			options:          Options{TreatIfExistsAsWarning: true},
+			expectedOp:       "DROP TABLE (if exists)",
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name:             "guarded drop with CASCADE is downgraded",
+			sql:              "DROP TABLE IF EXISTS t CASCADE",
+			options:          Options{TreatIfExistsAsWarning: true},
+			expectedOp:       "DROP TABLE (if exists, cascade)",
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name:             "unguarded drop is not downgraded",
+			sql:              "DROP TABLE t",
+			options:          Options{TreatIfExistsAsWarning: true},
+			expectedOp:       "DROP TABLE",
+			expectedSeverity: SeverityCritical,
+		},
+		{
+			name:             "other guarded drops are not downgraded",
+			sql:              "DROP INDEX IF EXISTS idx_t",
+			options:          Options{TreatIfExistsAsWarning: true},
+			expectedOp:       "DROP INDEX",
+			expectedSeverity: SeverityCritical,
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(tt.options).Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if results[0].Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %q, got %q", tt.expectedOp, results[0].Operation())
+			}
+			if results[0].Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, results[0].Severity)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_PartitionedIndex(t *testing.T) {
+	tests := []struct {
+		name             string
+		sql              string
+		options          Options
+		mode             TransactionMode
+		expectedOp       string
+		expectedSeverity Severity
+		expectedReason   ErrorReason
+		expectedNote     string
+	}{
+		{
+			name:             "parent created with PARTITION BY in the input",
+			sql:              "CREATE TABLE events (id int, at date) PARTITION BY RANGE (at);\nCREATE INDEX idx_events_at ON events (at);",
+			expectedOp:       "CREATE INDEX ON PARTITIONED TABLE",
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "CONCURRENTLY must be applied per partition",
+		},
+		{
+			name:             "parent named in the options",
+			sql:              "CREATE UNIQUE INDEX ON events (id, at);",
+			options:          Options{PartitionedTables: []string{"events"}},
+			expectedOp:       "CREATE UNIQUE INDEX ON PARTITIONED TABLE",
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "events is partitioned",
+		},
+		{
+			name:             "CONCURRENTLY is not supported on the parent",
+			sql:              "CREATE INDEX CONCURRENTLY ON events (at);",
+			options:          Options{PartitionedTables: []string{"events"}},
+			expectedOp:       "CREATE INDEX CONCURRENTLY",
+			expectedSeverity: SeverityError,
+			expectedReason:   ErrorReasonCannotRunInTransaction,
+			expectedNote:     "CONCURRENTLY is not supported on partitioned tables; create the index ON ONLY events",
+		},
+		{
+			name:             "CONCURRENTLY on the parent fails outside a transaction too",
+			sql:              "CREATE INDEX CONCURRENTLY ON events (at);",
+			options:          Options{PartitionedTables: []string{"events"}},
+			mode:             NoTransaction,
+			expectedOp:       "CREATE INDEX CONCURRENTLY",
+			expectedSeverity: SeverityError,
+			expectedReason:   ErrorReasonPartitionedTable,
+			expectedNote:     "CONCURRENTLY is not supported on partitioned tables; create the index ON ONLY events",
+		},
+		{
+			name:             "ON ONLY builds no partition indexes",
+			sql:              "CREATE INDEX ON ONLY events (at);",
+			options:          Options{PartitionedTables: []string{"events"}},
+			expectedOp:       "CREATE INDEX",
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "ON ONLY creates an INVALID index on events alone",
+		},
+		{
+			name:             "unknown table is a plain index",
+			sql:              "CREATE INDEX ON events (at);",
+			expectedOp:       "CREATE INDEX",
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "",
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(tt.options).Analyze(parsed, tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			result := results[len(results)-1]
+			if result.Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %q, got %q", tt.expectedOp, result.Operation())
+			}
+			if result.Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, result.Severity)
+			}
+			if result.ErrorReason() != tt.expectedReason {
+				t.Errorf("Expected error reason %q, got %q", tt.expectedReason, result.ErrorReason())
+			}
+			notes := result.Notes()
+			if tt.expectedNote == "" {
+				if len(notes) != 0 {
+					t.Errorf("Expected no notes, got %v", notes)
+				}
+				return
+			}
+			if len(notes) == 0 || !strings.Contains(strings.Join(notes, "\n"), tt.expectedNote) {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, notes)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ReindexSystemCatalog(t *testing.T) {
+	tests := []struct {
+		name             string
+		sql              string
+		mode             TransactionMode
+		expectedSeverity Severity
+		expectedReason   ErrorReason
+	}{
+		{"SYSTEM CONCURRENTLY", "REINDEX (CONCURRENTLY) SYSTEM mydb", NoTransaction, SeverityError, ErrorReasonSystemCatalog},
+		{"pg_catalog schema CONCURRENTLY", "REINDEX (CONCURRENTLY) SCHEMA pg_catalog", NoTransaction, SeverityError, ErrorReasonSystemCatalog},
+		{"catalog table CONCURRENTLY", "REINDEX (CONCURRENTLY) TABLE pg_catalog.pg_class", NoTransaction, SeverityError, ErrorReasonSystemCatalog},
+		{"catalog table CONCURRENTLY in a transaction", "REINDEX (CONCURRENTLY) TABLE pg_catalog.pg_class", InTransaction, SeverityError, ErrorReasonCannotRunInTransaction},
+		{"SYSTEM without CONCURRENTLY", "REINDEX SYSTEM mydb", NoTransaction, SeverityCritical, ErrorReasonNone},
+		{"user table CONCURRENTLY", "REINDEX (CONCURRENTLY) TABLE users", NoTransaction, SeverityWarning, ErrorReasonNone},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := New().Analyze(parsed, tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			if results[0].Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, results[0].Severity)
+			}
+			if results[0].ErrorReason() != tt.expectedReason {
+				t.Errorf("Expected error reason %q, got %q", tt.expectedReason, results[0].ErrorReason())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_PublishedTables(t *testing.T) {
+	const publishedNote = "is published for logical replication, which does not replicate schema changes"
+
+	tests := []struct {
+		name             string
+		sql              string
+		options          Options
+		expectedOp       string
+		expectedSeverity Severity
+		expectedNote     string
+	}{
+		{
+			name:             "table named in the options",
+			sql:              "ALTER TABLE users ADD COLUMN nickname text;",
+			options:          Options{PublishedTables: []string{"users"}},
+			expectedOp:       "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedSeverity: SeverityInfo,
+			expectedNote:     "users " + publishedNote,
+		},
+		{
+			name:             "unpublished table",
+			sql:              "ALTER TABLE orders ADD COLUMN note text;",
+			options:          Options{PublishedTables: []string{"users"}},
+			expectedOp:       "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "replica identity change",
+			sql:              "ALTER TABLE users REPLICA IDENTITY FULL;",
+			options:          Options{PublishedTables: []string{"users"}},
+			expectedOp:       "ALTER TABLE REPLICA IDENTITY",
+			expectedSeverity: SeverityWarning,
+			expectedNote:     "check that a replica identity change still matches the subscribers' keys",
+		},
+		{
+			name:             "table added by CREATE PUBLICATION in the input",
+			sql:              "CREATE PUBLICATION app_pub FOR TABLE app.users;\nALTER TABLE app.users ADD COLUMN nickname text;",
+			expectedOp:       "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedSeverity: SeverityInfo,
+			expectedNote:     "app.users " + publishedNote,
+		},
+		{
+			name:             "table added by ALTER PUBLICATION in the input",
+			sql:              "ALTER PUBLICATION app_pub ADD TABLE users;\nALTER TABLE users DROP COLUMN nickname;",
+			expectedOp:       "ALTER TABLE DROP COLUMN",
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "users " + publishedNote,
+		},
+		{
+			name:             "FOR ALL TABLES publishes every table",
+			sql:              "CREATE PUBLICATION app_pub FOR ALL TABLES;\nALTER TABLE orders ADD COLUMN note text;",
+			expectedOp:       "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedSeverity: SeverityInfo,
+			expectedNote:     "orders " + publishedNote,
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(tt.options).Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			// The note leaves the operation and its severity unchanged
+			result := results[len(results)-1]
+			if result.Operation() != tt.expectedOp {
+				t.Errorf("Expected operation %q, got %q", tt.expectedOp, result.Operation())
+			}
+			if result.Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, result.Severity)
+			}
+			notes := strings.Join(result.Notes(), "\n")
+			if tt.expectedNote == "" {
+				if strings.Contains(notes, publishedNote) {
+					t.Errorf("Expected no publication note, got %v", result.Notes())
+				}
+				return
+			}
+			if !strings.Contains(notes, tt.expectedNote) {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_MaxLockLevel(t *testing.T) {
+	const policyNote = "maximum allowed by policy"
+
+	tests := []struct {
+		name             string
+		sql              string
+		mode             TransactionMode
+		maxLockLevel     LockType
+		expectedSeverity Severity
+		expectedNote     string
+	}{
+		{
+			name:             "AccessExclusive exceeds a ShareUpdateExclusive ceiling",
+			sql:              "ALTER TABLE users ADD CONSTRAINT pk PRIMARY KEY USING INDEX idx;",
+			mode:             InTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "AccessExclusive lock is stronger than the ShareUpdateExclusive " + policyNote,
+		},
+		{
+			name:             "RowExclusive stays under a ShareUpdateExclusive ceiling",
+			sql:              "INSERT INTO users (id) VALUES (1);",
+			mode:             InTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "a lock equal to the ceiling passes",
+			sql:              "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);",
+			mode:             NoTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name:             "foreign key exceeds a RowShare ceiling",
+			sql:              "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id);",
+			mode:             InTransaction,
+			maxLockLevel:     RowShare,
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "ShareRowExclusive lock is stronger than the RowShare " + policyNote,
+		},
+		{
+			name:             "ERROR stays ERROR",
+			sql:              "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);",
+			mode:             InTransaction,
+			maxLockLevel:     AccessShare,
+			expectedSeverity: SeverityError,
+			expectedNote:     policyNote,
+		},
+		{
+			name:             "CREATE TABLE locks no existing table",
+			sql:              "CREATE TABLE audit_log (id bigint PRIMARY KEY, payload jsonb);",
+			mode:             InTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "CREATE SCHEMA locks no table",
+			sql:              "CREATE SCHEMA reporting;",
+			mode:             InTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityInfo,
+		},
+		{
+			name:             "database-wide VACUUM FULL exceeds a ShareUpdateExclusive ceiling",
+			sql:              "VACUUM FULL;",
+			mode:             NoTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "AccessExclusive lock is stronger than the ShareUpdateExclusive " + policyNote,
+		},
+		{
+			name:             "REINDEX DATABASE exceeds a ShareUpdateExclusive ceiling",
+			sql:              "REINDEX DATABASE mydb;",
+			mode:             NoTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityCritical,
+			expectedNote:     "AccessExclusive lock is stronger than the ShareUpdateExclusive " + policyNote,
+		},
+		{
+			name:             "database-wide ANALYZE stays under a ShareUpdateExclusive ceiling",
+			sql:              "ANALYZE;",
+			mode:             NoTransaction,
+			maxLockLevel:     ShareUpdateExclusive,
+			expectedSeverity: SeverityWarning,
+		},
+		{
+			name:             "no ceiling",
+			sql:              "ALTER TABLE users ADD CONSTRAINT pk PRIMARY KEY USING INDEX idx;",
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(Options{MaxLockLevel: tt.maxLockLevel}).Analyze(parsed, tt.mode)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			result := results[0]
+			if result.Severity != tt.expectedSeverity {
+				t.Errorf("Expected severity %v, got %v", tt.expectedSeverity, result.Severity)
+			}
+			notes := strings.Join(result.Notes(), "\n")
+			if tt.expectedNote == "" {
+				if strings.Contains(notes, policyNote) {
+					t.Errorf("Expected no policy note, got %v", result.Notes())
+				}
+				return
+			}
+			if !strings.Contains(notes, tt.expectedNote) {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_SchemaObjects(t *testing.T) {
+	// The options stand in for object counts read from a catalog
+	catalog := Options{SchemaObjects: map[string]map[string]int{
+		"app":     {"tables": 12, "functions": 3, "sequences": 4, "views": 0},
+		"staging": {},
+	}}
+
+	tests := []struct {
+		name         string
+		sql          string
+		expectedNote string
+	}{
+		{
+			name:         "schema in the catalog",
+			sql:          "DROP SCHEMA app CASCADE;",
+			expectedNote: "CASCADE drops everything in schema app: 3 functions, 4 sequences, 12 tables, plus every object elsewhere that depends on them",
+		},
+		{
+			name:         "empty schema",
+			sql:          "DROP SCHEMA staging CASCADE;",
+			expectedNote: "schema staging holds no objects according to the catalog",
+		},
+		{
+			name:         "schema missing from the catalog",
+			sql:          "DROP SCHEMA archive CASCADE;",
+			expectedNote: "CASCADE drops every object in schema archive",
+		},
+		{
+			name:         "each dropped schema",
+			sql:          "DROP SCHEMA archive, app CASCADE;",
+			expectedNote: "CASCADE drops everything in schema app: 3 functions",
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(catalog).Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			// The summary leaves the operation and its severity unchanged
+			result := results[0]
+			if result.Operation() != "DROP SCHEMA CASCADE" || result.Severity != SeverityCritical {
+				t.Errorf("Expected CRITICAL DROP SCHEMA CASCADE, got %v %q", result.Severity, result.Operation())
+			}
+			if notes := strings.Join(result.Notes(), "\n"); !strings.Contains(notes, tt.expectedNote) {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+			}
+		})
+	}
+}
+
+func TestAnalyzer_NoOpRelOptions(t *testing.T) {
+	// The options stand in for pg_class.reloptions read from a catalog
+	catalog := Options{TableOptions: map[string]map[string]string{
+		"users":  {"fillfactor": "90", "autovacuum_enabled": "off"},
+		"orders": {},
+	}}
+
+	tests := []struct {
+		name         string
+		sql          string
+		options      Options
+		expectedNote string // "" means no no-op note
+	}{
+		{
+			name:         "option already has the value",
+			sql:          "ALTER TABLE users SET (fillfactor = 90);",
+			options:      catalog,
+			expectedNote: "likely a no-op: the storage parameters of users already have these values (fillfactor = 90)",
+		},
+		{
+			name:         "boolean spelled differently",
+			sql:          "ALTER TABLE users SET (autovacuum_enabled = false);",
+			options:      catalog,
+			expectedNote: "(autovacuum_enabled = false)",
+		},
+		{
+			name:         "unset option set to its default",
+			sql:          "ALTER TABLE orders SET (fillfactor = 100);",
+			options:      catalog,
+			expectedNote: "(fillfactor = 100)",
+		},
+		{
+			name:         "reset of an unset option",
+			sql:          "ALTER TABLE orders RESET (fillfactor);",
+			options:      catalog,
+			expectedNote: "(fillfactor is not set)",
+		},
+		{
+			name:    "option changes",
+			sql:     "ALTER TABLE users SET (fillfactor = 80);",
+			options: catalog,
+		},
+		{
+			name:    "one of several options changes",
+			sql:     "ALTER TABLE users SET (fillfactor = 90, autovacuum_enabled = true);",
+			options: catalog,
+		},
+		{
+			name:    "table not in the catalog",
+			sql:     "ALTER TABLE accounts SET (fillfactor = 100);",
+			options: catalog,
+		},
+		{
+			name:         "table created with WITH in the input",
+			sql:          "CREATE TABLE events (id int) WITH (fillfactor = 70);\nALTER TABLE events SET (fillfactor = 70);",
+			expectedNote: "(fillfactor = 70)",
+		},
+		{
+			name:    "earlier ALTER in the input changed the option",
+			sql:     "ALTER TABLE users SET (fillfactor = 80);\nALTER TABLE users SET (fillfactor = 90);",
+			options: catalog,
+		},
+	}
+
+	p := parser.NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := p.ParseSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Failed to parse SQL: %v", err)
+			}
+
+			results, err := NewWithOptions(tt.options).Analyze(parsed, InTransaction)
+			if err != nil {
+				t.Fatalf("Failed to analyze: %v", err)
+			}
+
+			result := results[len(results)-1]
+			if result.Severity != SeverityInfo {
+				t.Errorf("Expected severity INFO, got %s", result.Severity)
+			}
+			notes := strings.Join(result.Notes(), "\n")
+			if tt.expectedNote == "" {
+				if strings.Contains(notes, "no-op") {
+					t.Errorf("Expected no no-op note, got %v", result.Notes())
+				}
+				return
+			}
+			if !strings.Contains(notes, tt.expectedNote) {
+				t.Errorf("Expected note containing %q, got %v", tt.expectedNote, result.Notes())
+			}
+		})
+	}
+}
+
+// ===== QUOTED IDENTIFIERS TEST =====
+
+func TestAnalyzer_QuotedIdentifiers(t *testing.T) {
+	tests := []struct {
+		name             string
+		sql              string
+		mode             TransactionMode
+		expectedSeverity Severity
+		expectedOp       string
+		expectedLocks    map[string]string
+	}{
+		// === Basic quoted vs unquoted identifiers ===
+		{
+			name:             "simple quoted lowercase table",
+			sql:              `SELECT * FROM "users"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{"users": "AccessShare"},
+		},
+		{
+			name:             "simple unquoted lowercase table",
+			sql:              `SELECT * FROM users`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{"users": "AccessShare"},
+		},
+		{
+			name:             "quoted uppercase table",
+			sql:              `SELECT * FROM "Users"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{`"Users"`: "AccessShare"},
+		},
+		{
+			name:             "quoted mixed case table",
+			sql:              `UPDATE "UserAccounts" SET active = true WHERE id = 1`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "UPDATE with WHERE",
+			expectedLocks:    map[string]string{`"UserAccounts"`: "RowExclusive"},
+		},
+
+		// === Special characters that require quoting ===
+		{
+			name:             "table with hyphen",
+			sql:              `SELECT * FROM "user-accounts"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{`"user-accounts"`: "AccessShare"},
+		},
+		{
+			name:             "table with space",
+			sql:              `UPDATE "user accounts" SET status = 'active'`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "UPDATE without WHERE",
+			expectedLocks:    map[string]string{`"user accounts"`: "RowExclusive"},
+		},
+		{
+			name:             "table with dot notation",
+			sql:              `DELETE FROM "my.table" WHERE id > 100`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "DELETE with WHERE",
+			expectedLocks:    map[string]string{`"my.table"`: "RowExclusive"},
+		},
+		{
+			name:             "table starting with number",
+			sql:              `INSERT INTO "123_logs" (message) VALUES ('test')`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "INSERT",
+			expectedLocks:    map[string]string{`"123_logs"`: "RowExclusive"},
+		},
+		{
+			name:             "table with special symbols",
+			sql:              `SELECT * FROM "user$data"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{`"user$data"`: "AccessShare"},
+		},
+
+		// === Schema qualified identifiers ===
+		{
+			name:             "quoted schema and table",
+			sql:              `SELECT * FROM "public"."users"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{"public.users": "AccessShare"},
+		},
+		{
+			name:             "unquoted schema, quoted table",
+			sql:              `SELECT * FROM public."Users"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{`public."Users"`: "AccessShare"},
+		},
+		{
+			name:             "quoted schema, unquoted table",
+			sql:              `UPDATE "MySchema".users SET active = false`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "UPDATE without WHERE",
+			expectedLocks:    map[string]string{`"MySchema".users`: "RowExclusive"},
+		},
+
+		// === PostgreSQL reserved words as identifiers ===
+		{
+			name:             "reserved word USER",
+			sql:              `SELECT * FROM "user"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "SELECT",
+			expectedLocks:    map[string]string{`"user"`: "AccessShare"},
+		},
+		{
+			name:             "reserved word ORDER",
+			sql:              `UPDATE "order" SET status = 'shipped' WHERE id = 1`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "UPDATE with WHERE",
+			expectedLocks:    map[string]string{`"order"`: "RowExclusive"},
+		},
+		{
+			name:             "reserved word GROUP",
+			sql:              `INSERT INTO "group" (name) VALUES ('admins')`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "INSERT",
+			expectedLocks:    map[string]string{`"group"`: "RowExclusive"},
+		},
+		{
+			name:             "reserved word TABLE",
+			sql:              `DELETE FROM "table" WHERE created < NOW() - INTERVAL '1 year'`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "DELETE with WHERE",
+			expectedLocks:    map[string]string{`"table"`: "RowExclusive"},
+		},
+		{
+			name:             "reserved word SELECT as table",
+			sql:              `CREATE INDEX idx_select ON "select" (id)`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "CREATE INDEX",
+			expectedLocks:    map[string]string{`"select"`: "Share"},
+		},
+		{
+			name:             "reserved word WHERE as table",
+			sql:              `DROP TABLE "where"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "DROP TABLE",
+			expectedLocks:    map[string]string{`"where"`: "AccessExclusive"},
+		},
+		{
+			name:             "reserved word FROM as column",
+			sql:              `ALTER TABLE messages ADD COLUMN "from" text`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityInfo,
+			expectedOp:       "ALTER TABLE ADD COLUMN without DEFAULT",
+			expectedLocks:    map[string]string{"messages": "AccessExclusive"},
+		},
+		{
+			name:             "reserved word CHECK as table",
+			sql:              `TRUNCATE "check"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "TRUNCATE",
+			expectedLocks:    map[string]string{`"check"`: "AccessExclusive"},
+		},
+
+		// === DDL operations with quoted identifiers ===
 		{
 			name:             "CREATE TABLE with all quoted",
 			sql:              `CREATE TABLE "MyTable" ("ID" serial PRIMARY KEY, "UserName" text)`,
@@ -2795,6 +5960,22 @@ func TestAnalyzer_QuotedIdentifiers(t *testing.T) {
 			expectedOp:       "ALTER TABLE ALTER COLUMN TYPE",
 			expectedLocks:    map[string]string{`"user-data"`: "AccessExclusive"},
 		},
+		{
+			name:             "REINDEX schema-qualified quoted index",
+			sql:              `REINDEX INDEX "app"."My Index"`,
+			mode:             InTransaction,
+			expectedSeverity: SeverityCritical,
+			expectedOp:       "REINDEX",
+			expectedLocks:    map[string]string{`app."My Index"`: "AccessExclusive"},
+		},
+		{
+			name:             "REINDEX CONCURRENTLY quoted index",
+			sql:              `REINDEX INDEX CONCURRENTLY "Sales Data"."idx_Orders"`,
+			mode:             NoTransaction,
+			expectedSeverity: SeverityWarning,
+			expectedOp:       "REINDEX CONCURRENTLY",
+			expectedLocks:    map[string]string{`"Sales Data"."idx_Orders"`: "ShareUpdateExclusive"},
+		},
 		{
 			name:             "CREATE INDEX CONCURRENTLY on quoted table",
 			sql:              `CREATE INDEX CONCURRENTLY "idx_Users_email" ON "Users" (email)`,
@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LockTypes lists every table-level lock mode from weakest to strongest
+var LockTypes = []LockType{
+	AccessShare,
+	RowShare,
+	RowExclusive,
+	ShareUpdateExclusive,
+	Share,
+	ShareRowExclusive,
+	Exclusive,
+	AccessExclusive,
+}
+
+// lockConflicts is PostgreSQL's table-level lock conflict matrix
+var lockConflicts = map[LockType][]LockType{
+	AccessShare:          {AccessExclusive},
+	RowShare:             {Exclusive, AccessExclusive},
+	RowExclusive:         {Share, ShareRowExclusive, Exclusive, AccessExclusive},
+	ShareUpdateExclusive: {ShareUpdateExclusive, Share, ShareRowExclusive, Exclusive, AccessExclusive},
+	Share:                {RowExclusive, ShareUpdateExclusive, ShareRowExclusive, Exclusive, AccessExclusive},
+	ShareRowExclusive:    {RowExclusive, ShareUpdateExclusive, Share, ShareRowExclusive, Exclusive, AccessExclusive},
+	Exclusive:            {RowShare, RowExclusive, ShareUpdateExclusive, Share, ShareRowExclusive, Exclusive, AccessExclusive},
+	AccessExclusive:      LockTypes,
+}
+
+// lockAcquirers lists statements that typically acquire each lock mode
+var lockAcquirers = map[LockType][]string{
+	AccessShare:          {"SELECT"},
+	RowShare:             {"SELECT FOR UPDATE", "SELECT FOR NO KEY UPDATE", "SELECT FOR SHARE", "SELECT FOR KEY SHARE"},
+	RowExclusive:         {"INSERT", "UPDATE", "DELETE", "MERGE"},
+	ShareUpdateExclusive: {"VACUUM", "ANALYZE", "CREATE INDEX CONCURRENTLY", "REINDEX CONCURRENTLY", "CREATE STATISTICS", "ALTER TABLE VALIDATE CONSTRAINT"},
+	Share:                {"CREATE INDEX"},
+	ShareRowExclusive:    {"CREATE TRIGGER", "ALTER TABLE ADD FOREIGN KEY"},
+	Exclusive:            {"REFRESH MATERIALIZED VIEW CONCURRENTLY"},
+	AccessExclusive:      {"DROP TABLE", "TRUNCATE", "REINDEX", "CLUSTER", "VACUUM FULL", "REFRESH MATERIALIZED VIEW", "LOCK TABLE", "most ALTER TABLE forms"},
+}
+
+// ParseLockType returns the lock type named name, ignoring case and an optional "Lock" suffix
+// as in pg_locks (e.g. AccessExclusiveLock)
+func ParseLockType(name string) (LockType, error) {
+	trimmed := strings.TrimSpace(name)
+	if len(trimmed) > len("Lock") && strings.EqualFold(trimmed[len(trimmed)-len("Lock"):], "Lock") {
+		trimmed = trimmed[:len(trimmed)-len("Lock")]
+	}
+	for _, lockType := range LockTypes {
+		if strings.EqualFold(trimmed, string(lockType)) {
+			return lockType, nil
+		}
+	}
+
+	names := make([]string, len(LockTypes))
+	for i, lockType := range LockTypes {
+		names[i] = string(lockType)
+	}
+	return "", fmt.Errorf("unknown lock type %q: must be one of %s", name, strings.Join(names, ", "))
+}
+
+// ConflictingLocks returns the lock modes that conflict with lockType, from weakest to strongest
+func ConflictingLocks(lockType LockType) []LockType {
+	return lockConflicts[lockType]
+}
+
+// Conflicts reports whether a and b cannot be held on the same table by different transactions
+func Conflicts(a, b LockType) bool {
+	for _, conflict := range lockConflicts[a] {
+		if conflict == b {
+			return true
+		}
+	}
+	return false
+}
+
+// TypicalStatements returns statements that typically acquire lockType
+func TypicalStatements(lockType LockType) []string {
+	return lockAcquirers[lockType]
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import "testing"
+
+func TestLockConflictsAreSymmetric(t *testing.T) {
+	for _, a := range LockTypes {
+		for _, b := range LockTypes {
+			if Conflicts(a, b) != Conflicts(b, a) {
+				t.Errorf("Conflicts(%s, %s) = %v but Conflicts(%s, %s) = %v", a, b, Conflicts(a, b), b, a, Conflicts(b, a))
+			}
+		}
+	}
+}
+
+func TestParseLockType(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    LockType
+		wantErr bool
+	}{
+		{name: "AccessExclusive", want: AccessExclusive},
+		{name: "shareupdateexclusive", want: ShareUpdateExclusive},
+		{name: "RowExclusiveLock", want: RowExclusive},
+		{name: "Lock", wantErr: true},
+		{name: "SuperExclusive", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLockType(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %s", tt.name, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseLockType(%q) = %s, %v; want %s", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package analyzer
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/pganalyze/pg_query_go/v6"
@@ -91,7 +92,9 @@ func (a *analyzer) analyzeDMLRecursive(node *pg_query.Node, mode TransactionMode
 		result := &operationInfo{
 			operation:            mostSevere.operation,
 			tableLock:            mostSevere.tableLock,
-			message:              mostSevere.message,
+			qualifiers:           mostSevere.qualifiers,
+			notes:                mostSevere.notes,
+			unfilteredDelete:     mostSevere.unfilteredDelete,
 			additionalTableLocks: make(map[string]LockType),
 		}
 
@@ -222,9 +225,9 @@ func (a *analyzer) analyzeInsertMain(stmt *pg_query.InsertStmt) *operationInfo {
 
 	// Check for INSERT SELECT first
 	if stmt.SelectStmt != nil {
-		// Only mark as INSERT SELECT if it's actually selecting from another table
-		selectStmt := stmt.SelectStmt.GetSelectStmt()
-		if selectStmt != nil && len(selectStmt.FromClause) > 0 {
+		// Only mark as INSERT SELECT if it's actually selecting from another table;
+		// SELECT from VALUES or constants is just a plain INSERT
+		if selectReadsTables(stmt.SelectStmt.GetSelectStmt()) {
 			operation = "INSERT SELECT"
 		}
 	}
@@ -234,10 +237,50 @@ func (a *analyzer) analyzeInsertMain(stmt *pg_query.InsertStmt) *operationInfo {
 		operation = "INSERT ON CONFLICT"
 	}
 
-	return &operationInfo{
+	opInfo := &operationInfo{
 		operation: operation,
 		tableLock: RowExclusive,
 	}
+
+	// A long VALUES list writes all its rows, and their WAL, in one statement
+	rows := len(stmt.SelectStmt.GetSelectStmt().GetValuesLists())
+	if a.options.LargeInsertRows > 0 && rows >= a.options.LargeInsertRows {
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"inserts %d rows in one statement, holding RowExclusive on %s while their WAL is written and keeping vacuum from removing dead rows older than the transaction; insert them in batches or load them with COPY",
+			rows, getQualifiedTableName(stmt.Relation)))
+	}
+
+	return opInfo
+}
+
+// selectReadsTables reports whether a SELECT scans any relation, as opposed to
+// producing rows only from VALUES lists and constant subqueries
+func selectReadsTables(stmt *pg_query.SelectStmt) bool {
+	if stmt == nil {
+		return false
+	}
+	if stmt.Op != pg_query.SetOperation_SETOP_NONE {
+		return selectReadsTables(stmt.Larg) || selectReadsTables(stmt.Rarg)
+	}
+	for _, item := range stmt.FromClause {
+		if fromItemReadsTables(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromItemReadsTables reports whether a FROM clause item scans a relation.
+// Function calls are treated as reading tables since their bodies are opaque.
+func fromItemReadsTables(item *pg_query.Node) bool {
+	switch n := item.Node.(type) {
+	case *pg_query.Node_RangeSubselect:
+		return selectReadsTables(n.RangeSubselect.Subquery.GetSelectStmt())
+	case *pg_query.Node_JoinExpr:
+		return fromItemReadsTables(n.JoinExpr.Larg) || fromItemReadsTables(n.JoinExpr.Rarg)
+	default:
+		return true
+	}
 }
 
 // analyzeSelectMain analyzes SELECT without recursion
@@ -255,6 +298,11 @@ func (a *analyzer) analyzeSelectMain(stmt *pg_query.SelectStmt) *operationInfo {
 		}
 	}
 
+	// Advisory lock functions serialize with other sessions rather than locking tables
+	if opInfo := analyzeAdvisoryLock(stmt); opInfo != nil {
+		return opInfo
+	}
+
 	// Regular SELECT
 	return &operationInfo{
 		operation: "SELECT",
@@ -262,14 +310,94 @@ func (a *analyzer) analyzeSelectMain(stmt *pg_query.SelectStmt) *operationInfo {
 	}
 }
 
+// analyzeAdvisoryLock classifies a SELECT that calls pg_advisory_lock or one of its variants
+func analyzeAdvisoryLock(stmt *pg_query.SelectStmt) *operationInfo {
+	for _, target := range stmt.TargetList {
+		resTarget := target.GetResTarget()
+		if resTarget == nil {
+			continue
+		}
+		for _, name := range collectFunctionNames(resTarget.Val) {
+			if !strings.HasPrefix(name, "pg_advisory_") && !strings.HasPrefix(name, "pg_try_advisory_") ||
+				strings.Contains(name, "unlock") {
+				continue
+			}
+			try := strings.HasPrefix(name, "pg_try_")
+			xact := strings.Contains(name, "_xact_")
+			shared := strings.HasSuffix(name, "_shared")
+
+			opInfo := &operationInfo{
+				operation: "SELECT pg_advisory_lock",
+				tableLock: AccessShare,
+			}
+			if try {
+				opInfo.operation = "SELECT pg_try_advisory_lock"
+			}
+			if xact {
+				opInfo.qualifiers = append(opInfo.qualifiers, "transaction")
+			}
+			if shared {
+				opInfo.qualifiers = append(opInfo.qualifiers, "shared")
+			}
+
+			if try {
+				opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+					"%s() returns false instead of waiting when another session holds the advisory lock; check the result before continuing",
+					name))
+			} else {
+				opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+					"%s() waits until no other session holds the advisory lock, serializing with them; without lock_timeout it can wait indefinitely",
+					name))
+			}
+			if xact {
+				opInfo.notes = append(opInfo.notes, "the advisory lock is released at the end of the transaction; outside a transaction block it is released as soon as the statement finishes")
+			} else {
+				opInfo.notes = append(opInfo.notes, "the advisory lock is held by the session until pg_advisory_unlock() or disconnect, even after COMMIT or ROLLBACK")
+			}
+			return opInfo
+		}
+	}
+	return nil
+}
+
 // analyzeMergeMain analyzes MERGE without recursion
 func (a *analyzer) analyzeMergeMain(stmt *pg_query.MergeStmt) *operationInfo {
 	// Default to "without WHERE" - the main analyzer will check SQL text
 	// for "WHEN MATCHED AND" pattern to determine if it has WHERE conditions
-	return &operationInfo{
+	opInfo := &operationInfo{
 		operation: "MERGE without WHERE",
 		tableLock: RowExclusive,
 	}
+
+	target := getQualifiedTableName(stmt.Relation)
+	filteredSource := stmt.SourceRelation.GetRangeSubselect() != nil
+	for _, when := range stmt.MergeWhenClauses {
+		clause := when.GetMergeWhenClause()
+		if clause == nil || clause.CommandType != pg_query.CmdType_CMD_DELETE {
+			continue
+		}
+		if !slices.Contains(opInfo.qualifiers, "delete") {
+			opInfo.qualifiers = append(opInfo.qualifiers, "delete")
+		}
+		if clause.Condition != nil {
+			continue
+		}
+
+		// An unconditional DELETE branch is not narrowed by conditions on the other branches
+		switch clause.MatchKind {
+		case pg_query.MergeMatchKind_MERGE_WHEN_NOT_MATCHED_BY_SOURCE:
+			// Filtering the source only widens the rows missing from it
+			opInfo.unfilteredDelete = true
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+				"WHEN NOT MATCHED BY SOURCE THEN DELETE removes every row of %s missing from the source, as impactful as DELETE without WHERE", target))
+		case pg_query.MergeMatchKind_MERGE_WHEN_MATCHED:
+			opInfo.unfilteredDelete = opInfo.unfilteredDelete || !filteredSource
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+				"WHEN MATCHED THEN DELETE removes every row of %s matching the source, as impactful as DELETE without WHERE unless the source is filtered", target))
+		}
+	}
+
+	return opInfo
 }
 
 // extractTablesFromNode extracts all table names from a node
@@ -406,7 +534,7 @@ func (a *analyzer) analyzeLockingClause(stmt *pg_query.SelectStmt) *operationInf
 }
 
 // analyzeAlterTable analyzes ALTER TABLE statements
-func (a *analyzer) analyzeAlterTable(stmt *pg_query.AlterTableStmt) *operationInfo {
+func (a *analyzer) analyzeAlterTable(stmt *pg_query.AlterTableStmt, mode TransactionMode) *operationInfo {
 	// Check if this is actually an ALTER INDEX
 	if stmt.Objtype == pg_query.ObjectType_OBJECT_INDEX {
 		// For ALTER INDEX, we'll analyze the command but return ALTER INDEX operation
@@ -425,20 +553,72 @@ func (a *analyzer) analyzeAlterTable(stmt *pg_query.AlterTableStmt) *operationIn
 		}
 	}
 
-	// Analyze each command in the ALTER TABLE
+	// All subcommands run under one lock held for the whole statement, so report the most severe
+	// subcommand (the one with the strongest lock among equals) with the strongest lock of any, and
+	// keep the notes and table locks of all
+	var mostSevere *operationInfo
+	var mostSevereSeverity Severity
+	var mostSevereLock, strongestLock LockType
+	var notes []string
+	var additionalTableLocks map[string]LockType
 	for _, cmd := range stmt.Cmds {
 		alterCmd := cmd.GetAlterTableCmd()
-		if alterCmd != nil {
-			op := a.analyzeAlterTableCmd(alterCmd)
-			if op != nil {
-				return op
+		if alterCmd == nil {
+			continue
+		}
+		op := a.analyzeAlterTableCmd(alterCmd)
+		if op == nil {
+			continue
+		}
+
+		for _, note := range op.notes {
+			if !slices.Contains(notes, note) {
+				notes = append(notes, note)
+			}
+		}
+		for table, lock := range op.additionalTableLocks {
+			if additionalTableLocks == nil {
+				additionalTableLocks = make(map[string]LockType)
 			}
+			if lockLevel(lock) > lockLevel(additionalTableLocks[table]) {
+				additionalTableLocks[table] = lock
+			}
+		}
+
+		severity, lock := a.registry.getSeverityAndLock(op.operation, mode)
+		if lockLevel(lock) > lockLevel(strongestLock) {
+			strongestLock = lock
+		}
+		if mostSevere == nil || severity > mostSevereSeverity ||
+			(severity == mostSevereSeverity && lockLevel(lock) > lockLevel(mostSevereLock)) {
+			mostSevere, mostSevereSeverity, mostSevereLock = op, severity, lock
 		}
 	}
 
+	if note := a.noOpRelOptionsNote(stmt); note != "" {
+		notes = append(notes, note)
+	}
+
+	// Logical replication does not replicate schema changes
+	if a.isPublished(stmt.Relation) {
+		table := getQualifiedTableName(stmt.Relation)
+		notes = append(notes, fmt.Sprintf(
+			"%s is published for logical replication, which does not replicate schema changes; coordinate with subscribers: add columns on subscribers before the publisher, drop them there after, and check that a replica identity change still matches the subscribers' keys",
+			table))
+	}
+
+	if mostSevere != nil {
+		mostSevere.notes = notes
+		mostSevere.additionalTableLocks = additionalTableLocks
+		mostSevere.statementLock = strongestLock
+		return mostSevere
+	}
+
 	return &operationInfo{
-		operation: "ALTER TABLE",
-		tableLock: AccessExclusive,
+		operation:    "ALTER TABLE",
+		tableLock:    AccessExclusive,
+		notes:        notes,
+		unrecognized: true,
 	}
 }
 
@@ -448,14 +628,20 @@ func (a *analyzer) analyzeAlterTableCmd(cmd *pg_query.AlterTableCmd) *operationI
 	case pg_query.AlterTableType_AT_AddColumn:
 		return a.analyzeAddColumn(cmd)
 	case pg_query.AlterTableType_AT_DropColumn:
+		note := "fails if a view or rule depends on this column; dropping and recreating those dependents takes an AccessExclusive lock on each of them"
+		if cmd.Behavior == pg_query.DropBehavior_DROP_CASCADE {
+			note = "CASCADE also drops views, rules and constraints that depend on this column, taking an AccessExclusive lock on each of them"
+		}
 		return &operationInfo{
 			operation: "ALTER TABLE DROP COLUMN",
 			tableLock: AccessExclusive,
+			notes:     []string{note},
 		}
 	case pg_query.AlterTableType_AT_AlterColumnType:
 		return &operationInfo{
 			operation: "ALTER TABLE ALTER COLUMN TYPE",
 			tableLock: AccessExclusive,
+			notes:     []string{"fails if a view or rule depends on this column; dependent views must be dropped and recreated around the change, taking an AccessExclusive lock on each of them"},
 		}
 	case pg_query.AlterTableType_AT_SetTableSpace:
 		return &operationInfo{
@@ -526,11 +712,13 @@ func (a *analyzer) analyzeAlterTableCmd(cmd *pg_query.AlterTableCmd) *operationI
 		return &operationInfo{
 			operation: "ALTER TABLE SET",
 			tableLock: ShareUpdateExclusive,
+			notes:     relOptionNotes(cmd.Def, false),
 		}
 	case pg_query.AlterTableType_AT_ResetRelOptions:
 		return &operationInfo{
 			operation: "ALTER TABLE RESET",
 			tableLock: ShareUpdateExclusive,
+			notes:     relOptionNotes(cmd.Def, true),
 		}
 	case pg_query.AlterTableType_AT_ClusterOn:
 		return &operationInfo{
@@ -576,6 +764,7 @@ func (a *analyzer) analyzeAlterTableCmd(cmd *pg_query.AlterTableCmd) *operationI
 		return &operationInfo{
 			operation: "ALTER TABLE ENABLE ROW LEVEL SECURITY",
 			tableLock: AccessExclusive,
+			notes:     []string{"without policies on the table, enabling row level security denies all access to every role but the owner; create the policies first"},
 		}
 	case pg_query.AlterTableType_AT_DisableRowSecurity:
 		return &operationInfo{
@@ -675,17 +864,57 @@ func (a *analyzer) analyzeAlterTableCmd(cmd *pg_query.AlterTableCmd) *operationI
 		return &operationInfo{
 			operation: "ALTER TABLE ALTER COLUMN ADD IDENTITY",
 			tableLock: AccessExclusive,
+			notes: []string{fmt.Sprintf(
+				"creates a sequence for %s that starts at 1 regardless of existing values; the column must be NOT NULL without a DEFAULT, and the sequence should be moved past MAX(%s) with ALTER COLUMN %s RESTART WITH before inserts rely on it",
+				cmd.Name, cmd.Name, cmd.Name)},
 		}
 	case pg_query.AlterTableType_AT_DropIdentity:
 		return &operationInfo{
 			operation: "ALTER TABLE ALTER COLUMN DROP IDENTITY",
 			tableLock: AccessExclusive,
+			notes: []string{fmt.Sprintf(
+				"drops the identity sequence of %s; INSERTs that omit %s will fail its NOT NULL constraint unless a DEFAULT is set",
+				cmd.Name, cmd.Name)},
+		}
+	case pg_query.AlterTableType_AT_SetIdentity:
+		return &operationInfo{
+			operation: "ALTER TABLE ALTER COLUMN SET IDENTITY",
+			tableLock: AccessExclusive,
+			notes:     identityOptionNotes(cmd),
 		}
 	}
 
 	return nil
 }
 
+// identityOptionNotes explains the effect of SET GENERATED and RESTART on an identity column
+func identityOptionNotes(cmd *pg_query.AlterTableCmd) []string {
+	var notes []string
+	if cmd.Def == nil || cmd.Def.GetList() == nil {
+		return notes
+	}
+	for _, item := range cmd.Def.GetList().Items {
+		def := item.GetDefElem()
+		if def == nil {
+			continue
+		}
+		switch def.Defname {
+		case "restart":
+			notes = append(notes, fmt.Sprintf(
+				"RESTART changes the next value of the identity sequence of %s; restarting at or below existing values causes duplicate key errors on later inserts",
+				cmd.Name))
+		case "generated":
+			// ATTRIBUTE_IDENTITY_ALWAYS is 'a'
+			if def.Arg != nil && def.Arg.GetInteger() != nil && def.Arg.GetInteger().Ival == 'a' {
+				notes = append(notes, fmt.Sprintf(
+					"SET GENERATED ALWAYS rejects INSERTs that supply %s unless they use OVERRIDING SYSTEM VALUE",
+					cmd.Name))
+			}
+		}
+	}
+	return notes
+}
+
 // analyzeAddColumn analyzes ADD COLUMN commands
 func (a *analyzer) analyzeAddColumn(cmd *pg_query.AlterTableCmd) *operationInfo {
 	if cmd.Def == nil {
@@ -703,6 +932,135 @@ func (a *analyzer) analyzeAddColumn(cmd *pg_query.AlterTableCmd) *operationInfo
 		}
 	}
 
+	opInfo := addColumnOperation(colDef)
+
+	// An inline REFERENCES clause adds a foreign key, validated against the referenced table
+	for _, constraint := range colDef.Constraints {
+		constr := constraint.GetConstraint()
+		if constr == nil || constr.Contype != pg_query.ConstrType_CONSTR_FOREIGN {
+			continue
+		}
+		fk := foreignKeyOperation("ALTER TABLE ADD COLUMN with FOREIGN KEY", constr)
+		if opInfo.operation != "ALTER TABLE ADD COLUMN with volatile DEFAULT" {
+			opInfo.operation = fk.operation
+		}
+		opInfo.additionalTableLocks = fk.additionalTableLocks
+		if constr.Pktable != nil {
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+				"inline REFERENCES %s is validated while the AccessExclusive lock is held; add the column first, then ADD FOREIGN KEY ... NOT VALID and VALIDATE CONSTRAINT separately",
+				getQualifiedTableName(constr.Pktable)))
+		}
+		break
+	}
+
+	// Inline PRIMARY KEY or UNIQUE builds an index under the AccessExclusive lock
+	for _, constraint := range colDef.Constraints {
+		constr := constraint.GetConstraint()
+		if constr == nil {
+			continue
+		}
+		var kind string
+		switch constr.Contype {
+		case pg_query.ConstrType_CONSTR_PRIMARY:
+			kind = "PRIMARY KEY"
+		case pg_query.ConstrType_CONSTR_UNIQUE:
+			kind = "UNIQUE"
+		default:
+			continue
+		}
+		if opInfo.operation != "ALTER TABLE ADD COLUMN with volatile DEFAULT" {
+			opInfo.operation = "ALTER TABLE ADD COLUMN with " + kind
+		}
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"inline %s builds a unique index on %s while the AccessExclusive lock is held; add the column first, then CREATE UNIQUE INDEX CONCURRENTLY and ALTER TABLE ... ADD CONSTRAINT ... %s USING INDEX",
+			kind, colDef.Colname, kind))
+		break
+	}
+
+	if note := notNullWithoutDefaultNote(colDef); note != "" {
+		opInfo.notes = append(opInfo.notes, note)
+	}
+
+	return opInfo
+}
+
+// serialTypes are the serial pseudo-types, whose columns get an implicit nextval() DEFAULT
+var serialTypes = map[string]bool{
+	"smallserial": true, "serial2": true,
+	"serial": true, "serial4": true,
+	"bigserial": true, "serial8": true,
+}
+
+// notNullWithoutDefaultNote notes an added NOT NULL or PRIMARY KEY column without a DEFAULT, which
+// fails on any table that has rows, as every existing row would get NULL
+func notNullWithoutDefaultNote(colDef *pg_query.ColumnDef) string {
+	if colDef.Identity != "" || colDef.Generated != "" {
+		return ""
+	}
+	if colDef.TypeName != nil && len(colDef.TypeName.Names) == 1 {
+		if str := colDef.TypeName.Names[0].GetString_(); str != nil && serialTypes[str.Sval] {
+			return ""
+		}
+	}
+
+	notNull := colDef.IsNotNull
+	for _, constraint := range colDef.Constraints {
+		constr := constraint.GetConstraint()
+		if constr == nil {
+			continue
+		}
+		switch constr.Contype {
+		case pg_query.ConstrType_CONSTR_DEFAULT:
+			// DEFAULT NULL fills existing rows with NULL just like no DEFAULT
+			if !isNullExpr(constr.RawExpr) {
+				return ""
+			}
+		case pg_query.ConstrType_CONSTR_IDENTITY, pg_query.ConstrType_CONSTR_GENERATED:
+			return ""
+		case pg_query.ConstrType_CONSTR_NOTNULL, pg_query.ConstrType_CONSTR_PRIMARY:
+			notNull = true
+		}
+	}
+	if !notNull {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"NOT NULL column %s has no DEFAULT, so this fails unless the table is empty; add a DEFAULT, or add the column nullable, backfill it and then SET NOT NULL",
+		colDef.Colname)
+}
+
+// isNullExpr reports whether an expression is a NULL literal, possibly cast as in NULL::int
+func isNullExpr(expr *pg_query.Node) bool {
+	if cast := expr.GetTypeCast(); cast != nil {
+		return isNullExpr(cast.Arg)
+	}
+	aConst := expr.GetAConst()
+	return aConst != nil && aConst.Isnull
+}
+
+// uniqueKeyNote notes that adding a PRIMARY KEY or UNIQUE constraint fails if existing rows
+// violate it, after the lock has been waited for and part of the index built
+func uniqueKeyNote(kind string, constraint *pg_query.Constraint) string {
+	var columns []string
+	for _, key := range constraint.Keys {
+		if str := key.GetString_(); str != nil {
+			columns = append(columns, str.Sval)
+		}
+	}
+	violations := "duplicate values"
+	if kind == "PRIMARY KEY" {
+		violations = "duplicate values or NULLs"
+	}
+	if len(columns) == 0 {
+		return fmt.Sprintf("%s fails if existing rows contain %s; check for them before running the migration", kind, violations)
+	}
+	return fmt.Sprintf("%s fails if existing rows contain %s in (%s); check for them before running the migration",
+		kind, violations, strings.Join(columns, ", "))
+}
+
+// addColumnOperation classifies ADD COLUMN by its default and generation clauses
+func addColumnOperation(colDef *pg_query.ColumnDef) *operationInfo {
 	// Check for GENERATED ALWAYS AS
 	if colDef.Identity != "" || colDef.Generated != "" {
 		return &operationInfo{
@@ -717,10 +1075,16 @@ func (a *analyzer) analyzeAddColumn(cmd *pg_query.AlterTableCmd) *operationInfo
 			if constr.RawExpr != nil {
 				// Check if default is volatile
 				if isVolatileDefault(constr.RawExpr) {
-					return &operationInfo{
+					opInfo := &operationInfo{
 						operation: "ALTER TABLE ADD COLUMN with volatile DEFAULT",
 						tableLock: AccessExclusive,
 					}
+					if function, sequence, ok := sequenceDefault(constr.RawExpr); ok {
+						opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+							"the default calls %s() for every existing row while the table is rewritten, consuming one value of sequence %s per row",
+							function, sequence))
+					}
+					return opInfo
 				}
 				return &operationInfo{
 					operation: "ALTER TABLE ADD COLUMN with constant DEFAULT",
@@ -744,27 +1108,155 @@ func isVolatileDefault(expr *pg_query.Node) bool {
 
 	// Check for function calls
 	if funcCall := expr.GetFuncCall(); funcCall != nil {
-		funcName := ""
-		if len(funcCall.Funcname) > 0 {
-			for _, name := range funcCall.Funcname {
-				if str := name.GetString_(); str != nil {
-					funcName += str.Sval
-				}
-			}
+		return isVolatileFunction(getFunctionName(funcCall))
+	}
+
+	return false
+}
+
+// isVolatileFunction checks an unqualified function name against known volatile functions
+func isVolatileFunction(funcName string) bool {
+	volatileFuncs := []string{"random", "now", "current_timestamp", "current_date",
+		"current_time", "timeofday", "clock_timestamp", "statement_timestamp",
+		"transaction_timestamp", "uuid_generate_v4", "gen_random_uuid", "nextval", "setval"}
+
+	return slices.Contains(volatileFuncs, strings.ToLower(funcName))
+}
+
+// sequenceDefault returns the function and sequence of a nextval() or setval() default; the
+// sequence is "?" when it is not a literal
+func sequenceDefault(expr *pg_query.Node) (string, string, bool) {
+	funcCall := expr.GetFuncCall()
+	if funcCall == nil || len(funcCall.Funcname) == 0 {
+		return "", "", false
+	}
+	function := strings.ToLower(funcCall.Funcname[len(funcCall.Funcname)-1].GetString_().GetSval())
+	if function != "nextval" && function != "setval" {
+		return "", "", false
+	}
+
+	if len(funcCall.Args) > 0 {
+		arg := funcCall.Args[0]
+		if typeCast := arg.GetTypeCast(); typeCast != nil {
+			arg = typeCast.Arg
+		}
+		if sval := arg.GetAConst().GetSval(); sval != nil {
+			return function, sval.Sval, true
+		}
+	}
+	return function, "?", true
+}
+
+// getFunctionName returns the unqualified name of a function call, dropping any schema
+func getFunctionName(funcCall *pg_query.FuncCall) string {
+	if len(funcCall.Funcname) == 0 {
+		return ""
+	}
+	return funcCall.Funcname[len(funcCall.Funcname)-1].GetString_().GetSval()
+}
+
+// collectFunctionNames collects function names called within an expression
+func collectFunctionNames(expr *pg_query.Node) []string {
+	if expr == nil {
+		return nil
+	}
+
+	var names []string
+	switch n := expr.Node.(type) {
+	case *pg_query.Node_FuncCall:
+		names = append(names, getFunctionName(n.FuncCall))
+		for _, arg := range n.FuncCall.Args {
+			names = append(names, collectFunctionNames(arg)...)
+		}
+	case *pg_query.Node_AExpr:
+		names = append(names, collectFunctionNames(n.AExpr.Lexpr)...)
+		names = append(names, collectFunctionNames(n.AExpr.Rexpr)...)
+	case *pg_query.Node_BoolExpr:
+		for _, arg := range n.BoolExpr.Args {
+			names = append(names, collectFunctionNames(arg)...)
 		}
+	case *pg_query.Node_TypeCast:
+		names = append(names, collectFunctionNames(n.TypeCast.Arg)...)
+	case *pg_query.Node_CoalesceExpr:
+		for _, arg := range n.CoalesceExpr.Args {
+			names = append(names, collectFunctionNames(arg)...)
+		}
+	case *pg_query.Node_NullTest:
+		names = append(names, collectFunctionNames(n.NullTest.Arg)...)
+	case *pg_query.Node_List:
+		for _, item := range n.List.Items {
+			names = append(names, collectFunctionNames(item)...)
+		}
+	}
+	return names
+}
+
+// relOptionNotes describes the operational impact of storage parameters being set or reset
+func relOptionNotes(def *pg_query.Node, reset bool) []string {
+	if def == nil || def.GetList() == nil {
+		return nil
+	}
 
-		// List of known volatile functions
-		volatileFuncs := []string{"random", "now", "current_timestamp", "current_date",
-			"current_time", "timeofday", "clock_timestamp", "statement_timestamp",
-			"transaction_timestamp", "uuid_generate_v4", "gen_random_uuid"}
+	var notes []string
+	for _, item := range def.GetList().Items {
+		elem := item.GetDefElem()
+		if elem == nil {
+			continue
+		}
 
-		for _, vf := range volatileFuncs {
-			if strings.Contains(strings.ToLower(funcName), vf) {
-				return true
+		relation := "this table"
+		name := elem.Defname
+		if elem.Defnamespace != "" {
+			relation = "this table's " + strings.ToUpper(elem.Defnamespace) + " relation"
+			name = elem.Defnamespace + "." + elem.Defname
+		}
+
+		switch {
+		case reset && elem.Defname == "autovacuum_enabled":
+			notes = append(notes, fmt.Sprintf("restores the default autovacuum setting on %s", relation))
+		case reset:
+			continue
+		case elem.Defname == "autovacuum_enabled" && isFalseOption(elem.Arg):
+			notes = append(notes, fmt.Sprintf("disables autovacuum on %s; dead tuples and bloat accumulate until it is re-enabled or vacuumed manually", relation))
+		case elem.Defname == "vacuum_index_cleanup" && isFalseOption(elem.Arg):
+			notes = append(notes, fmt.Sprintf("disables index cleanup during VACUUM on %s; index bloat accumulates", relation))
+		case elem.Defname == "vacuum_truncate" && isFalseOption(elem.Arg):
+			notes = append(notes, fmt.Sprintf("disables truncation of empty pages during VACUUM on %s; disk space is not returned to the OS", relation))
+		case strings.HasPrefix(elem.Defname, "autovacuum_") && elem.Defname != "autovacuum_enabled":
+			notes = append(notes, fmt.Sprintf("changes autovacuum tuning (%s) on %s", name, relation))
+		}
+	}
+	return notes
+}
+
+// isFalseOption checks whether a storage parameter value is a boolean false
+func isFalseOption(arg *pg_query.Node) bool {
+	if arg == nil {
+		// A bare option name means true
+		return false
+	}
+
+	value := ""
+	switch v := arg.Node.(type) {
+	case *pg_query.Node_String_:
+		value = v.String_.Sval
+	case *pg_query.Node_TypeName:
+		// Unquoted words such as off are parsed as type names
+		if len(v.TypeName.Names) == 1 {
+			if str := v.TypeName.Names[0].GetString_(); str != nil {
+				value = str.Sval
 			}
 		}
+	case *pg_query.Node_Integer:
+		return v.Integer.Ival == 0
+	case *pg_query.Node_Boolean:
+		return !v.Boolean.Boolval
 	}
 
+	switch strings.ToLower(value) {
+	case "false", "off", "no", "f", "n", "0":
+		return true
+	}
 	return false
 }
 
@@ -772,21 +1264,28 @@ func isVolatileDefault(expr *pg_query.Node) bool {
 func (a *analyzer) analyzeAddConstraint(cmd *pg_query.AlterTableCmd) *operationInfo {
 	if cmd.Def == nil {
 		return &operationInfo{
-			operation: "ALTER TABLE ADD CONSTRAINT",
-			tableLock: AccessExclusive,
+			operation:    "ALTER TABLE ADD CONSTRAINT",
+			tableLock:    AccessExclusive,
+			unrecognized: true,
 		}
 	}
 
 	constraint := cmd.Def.GetConstraint()
 	if constraint == nil {
 		return &operationInfo{
-			operation: "ALTER TABLE ADD CONSTRAINT",
-			tableLock: AccessExclusive,
+			operation:    "ALTER TABLE ADD CONSTRAINT",
+			tableLock:    AccessExclusive,
+			unrecognized: true,
 		}
 	}
 
-	// Check for NOT VALID
+	// Check for NOT VALID; foreign keys keep their label and the referenced table's lock
 	if constraint.SkipValidation {
+		if constraint.Contype == pg_query.ConstrType_CONSTR_FOREIGN {
+			opInfo := foreignKeyOperation("ALTER TABLE ADD FOREIGN KEY NOT VALID", constraint)
+			opInfo.notes = []string{"existing rows are not checked, only new or updated ones; run ALTER TABLE ... VALIDATE CONSTRAINT afterwards"}
+			return opInfo
+		}
 		return &operationInfo{
 			operation: "ALTER TABLE ADD CONSTRAINT NOT VALID",
 			tableLock: ShareRowExclusive,
@@ -795,14 +1294,32 @@ func (a *analyzer) analyzeAddConstraint(cmd *pg_query.AlterTableCmd) *operationI
 
 	switch constraint.Contype {
 	case pg_query.ConstrType_CONSTR_PRIMARY:
+		// USING INDEX promotes an existing unique index, so no index build or table scan is needed
+		if constraint.Indexname != "" {
+			return &operationInfo{
+				operation: "ALTER TABLE ADD PRIMARY KEY USING INDEX",
+				tableLock: AccessExclusive,
+				notes:     []string{"AccessExclusive lock is held only briefly to attach the existing index"},
+			}
+		}
 		return &operationInfo{
 			operation: "ALTER TABLE ADD PRIMARY KEY",
 			tableLock: AccessExclusive,
+			notes:     []string{uniqueKeyNote("PRIMARY KEY", constraint)},
 		}
 	case pg_query.ConstrType_CONSTR_UNIQUE:
+		// Like a primary key, USING INDEX turns an existing unique index into the constraint
+		if constraint.Indexname != "" {
+			return &operationInfo{
+				operation: "ALTER TABLE ADD CONSTRAINT UNIQUE USING INDEX",
+				tableLock: AccessExclusive,
+				notes:     []string{"AccessExclusive lock is held only briefly to attach the existing index"},
+			}
+		}
 		return &operationInfo{
 			operation: "ALTER TABLE ADD CONSTRAINT UNIQUE",
 			tableLock: AccessExclusive,
+			notes:     []string{uniqueKeyNote("UNIQUE", constraint)},
 		}
 	case pg_query.ConstrType_CONSTR_EXCLUSION:
 		return &operationInfo{
@@ -810,19 +1327,7 @@ func (a *analyzer) analyzeAddConstraint(cmd *pg_query.AlterTableCmd) *operationI
 			tableLock: AccessExclusive,
 		}
 	case pg_query.ConstrType_CONSTR_FOREIGN:
-		opInfo := &operationInfo{
-			operation:            "ALTER TABLE ADD FOREIGN KEY",
-			tableLock:            ShareRowExclusive,
-			additionalTableLocks: make(map[string]LockType),
-		}
-		// Extract referenced table from the constraint
-		if constraint.Pktable != nil {
-			refTableName := getQualifiedTableName(constraint.Pktable)
-			if refTableName != "" {
-				opInfo.additionalTableLocks[refTableName] = RowShare
-			}
-		}
-		return opInfo
+		return foreignKeyOperation("ALTER TABLE ADD FOREIGN KEY", constraint)
 	case pg_query.ConstrType_CONSTR_CHECK:
 		return &operationInfo{
 			operation: "ALTER TABLE ADD CONSTRAINT CHECK",
@@ -831,9 +1336,27 @@ func (a *analyzer) analyzeAddConstraint(cmd *pg_query.AlterTableCmd) *operationI
 	}
 
 	return &operationInfo{
-		operation: "ALTER TABLE ADD CONSTRAINT",
-		tableLock: AccessExclusive,
+		operation:    "ALTER TABLE ADD CONSTRAINT",
+		tableLock:    AccessExclusive,
+		unrecognized: true,
+	}
+}
+
+// foreignKeyOperation builds a foreign key operation, attributing a RowShare lock to the referenced table
+func foreignKeyOperation(operation string, constraint *pg_query.Constraint) *operationInfo {
+	opInfo := &operationInfo{
+		operation:            operation,
+		tableLock:            ShareRowExclusive,
+		additionalTableLocks: make(map[string]LockType),
 	}
+	// Extract referenced table from the constraint
+	if constraint.Pktable != nil {
+		refTableName := getQualifiedTableName(constraint.Pktable)
+		if refTableName != "" {
+			opInfo.additionalTableLocks[refTableName] = RowShare
+		}
+	}
+	return opInfo
 }
 
 // analyzeCreate analyzes CREATE TABLE statements
@@ -846,14 +1369,177 @@ func (a *analyzer) analyzeCreate(stmt *pg_query.CreateStmt) *operationInfo {
 		}
 	}
 
-	return &operationInfo{
+	// PARTITION OF locks the parent table while the partition is added
+	if stmt.Partbound != nil && len(stmt.InhRelations) > 0 {
+		opInfo := &operationInfo{
+			operation: "CREATE TABLE PARTITION OF",
+			tableLock: AccessExclusive,
+		}
+		parent := ""
+		if rv := stmt.InhRelations[0].GetRangeVar(); rv != nil {
+			parent = getQualifiedTableName(rv)
+		}
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"takes an AccessExclusive lock on parent table %s; to avoid blocking queries on the parent, create the table standalone and use ALTER TABLE %s ATTACH PARTITION (ShareUpdateExclusive)",
+			parent, parent))
+		if !stmt.Partbound.IsDefault {
+			opInfo.notes = append(opInfo.notes, "if the parent has a DEFAULT partition, it is scanned for rows belonging to the new partition while the lock is held")
+		}
+		return opInfo
+	}
+
+	opInfo := &operationInfo{
 		operation: "CREATE TABLE",
 		tableLock: AccessExclusive,
 	}
+	if stmt.Partspec != nil {
+		opInfo.qualifiers = append(opInfo.qualifiers, "partitioned")
+	}
+
+	// LIKE reads the source table's definition under AccessShare
+	for _, elt := range stmt.TableElts {
+		like := elt.GetTableLikeClause()
+		if like == nil || like.Relation == nil {
+			continue
+		}
+		source := getQualifiedTableName(like.Relation)
+		if opInfo.additionalTableLocks == nil {
+			opInfo.additionalTableLocks = make(map[string]LockType)
+		}
+		opInfo.additionalTableLocks[source] = AccessShare
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"copies column definitions from %s%s; later changes to %s are not applied to the new table",
+			source, describeLikeOptions(like.Options), source))
+	}
+	return opInfo
+}
+
+// likeOptionNames lists the properties LIKE can copy, in CREATE_TABLE_LIKE_* bit order
+var likeOptionNames = []string{
+	"comments", "compression", "constraints", "defaults", "generated columns",
+	"identity", "indexes", "statistics", "storage",
+}
+
+// describeLikeOptions describes the INCLUDING options of a LIKE clause,
+// or returns an empty string when only column names and types are copied
+func describeLikeOptions(options uint32) string {
+	included := []string{}
+	for bit, name := range likeOptionNames {
+		if options&(1<<bit) != 0 {
+			included = append(included, name)
+		}
+	}
+	switch len(included) {
+	case 0:
+		return ""
+	case len(likeOptionNames):
+		return " including all properties"
+	default:
+		return " including " + strings.Join(included, ", ")
+	}
+}
+
+// analyzeAlterDomain analyzes ALTER DOMAIN statements, separating constraint changes
+// that revalidate every column using the domain from those that do not
+func (a *analyzer) analyzeAlterDomain(stmt *pg_query.AlterDomainStmt) *operationInfo {
+	const revalidationNote = "checks every existing value of this domain, holding a Share lock on each table with a column of this type and blocking writes to it until the scan finishes"
+
+	switch stmt.Subtype {
+	case "C":
+		if constraint := stmt.Def.GetConstraint(); constraint != nil && constraint.SkipValidation {
+			return &operationInfo{
+				operation: "ALTER DOMAIN ADD CONSTRAINT NOT VALID",
+				tableLock: AccessShare,
+				notes:     []string{"existing values are not checked, only new or updated ones; run ALTER DOMAIN ... VALIDATE CONSTRAINT afterwards"},
+			}
+		}
+		return &operationInfo{
+			operation: "ALTER DOMAIN ADD CONSTRAINT",
+			tableLock: Share,
+			notes:     []string{revalidationNote + "; add the constraint NOT VALID and validate it separately"},
+		}
+	case "V":
+		return &operationInfo{
+			operation: "ALTER DOMAIN VALIDATE CONSTRAINT",
+			tableLock: Share,
+			notes:     []string{revalidationNote},
+		}
+	case "X":
+		return &operationInfo{
+			operation: "ALTER DOMAIN DROP CONSTRAINT",
+			tableLock: AccessExclusive,
+		}
+	}
+
+	return &operationInfo{
+		operation: "ALTER DOMAIN",
+		tableLock: AccessExclusive,
+	}
 }
 
 // analyzeDrop analyzes DROP statements
 func (a *analyzer) analyzeDrop(stmt *pg_query.DropStmt) *operationInfo {
+	opInfo := a.analyzeDropObject(stmt)
+	if stmt.MissingOk && stmt.RemoveType == pg_query.ObjectType_OBJECT_TABLE {
+		opInfo.qualifiers = append(opInfo.qualifiers, "if exists")
+		opInfo.notes = append(opInfo.notes, "IF EXISTS makes the drop idempotent, as in teardown or down migrations, but still takes AccessExclusive and removes the data when the table exists")
+	}
+	if stmt.Behavior != pg_query.DropBehavior_DROP_CASCADE {
+		return opInfo
+	}
+
+	// DROP SCHEMA CASCADE is classified separately and lists what it removes; other object types carry a qualifier
+	if stmt.RemoveType == pg_query.ObjectType_OBJECT_SCHEMA {
+		opInfo.notes = append(opInfo.notes, a.dropSchemaCascadeNotes(stmt)...)
+		return opInfo
+	}
+	opInfo.qualifiers = append(opInfo.qualifiers, "cascade")
+	opInfo.notes = append(opInfo.notes, "CASCADE also drops every dependent object (e.g. views, foreign keys, columns using a dropped type) without listing them; drop dependents explicitly to review what is removed")
+	return opInfo
+}
+
+// dropSchemaCascadeNotes summarizes the objects each dropped schema holds when Options.SchemaObjects
+// knows the schema, and warns that everything in it is removed otherwise
+func (a *analyzer) dropSchemaCascadeNotes(stmt *pg_query.DropStmt) []string {
+	var notes []string
+	for _, object := range stmt.Objects {
+		schema := object.GetString_().GetSval()
+		if schema == "" {
+			continue
+		}
+
+		counts, known := a.options.SchemaObjects[schema]
+		if !known {
+			notes = append(notes, fmt.Sprintf(
+				"CASCADE drops every object in schema %s (tables with their data, views, sequences, functions, types) and every object elsewhere that depends on them, without listing them; review the schema's contents before dropping it",
+				schema))
+			continue
+		}
+
+		kinds := make([]string, 0, len(counts))
+		for kind, count := range counts {
+			if count > 0 {
+				kinds = append(kinds, kind)
+			}
+		}
+		if len(kinds) == 0 {
+			notes = append(notes, fmt.Sprintf("schema %s holds no objects according to the catalog", schema))
+			continue
+		}
+		slices.Sort(kinds)
+		summary := make([]string, len(kinds))
+		for i, kind := range kinds {
+			summary[i] = fmt.Sprintf("%d %s", counts[kind], kind)
+		}
+		notes = append(notes, fmt.Sprintf(
+			"CASCADE drops everything in schema %s: %s, plus every object elsewhere that depends on them",
+			schema, strings.Join(summary, ", ")))
+	}
+	return notes
+}
+
+// analyzeDropObject classifies DROP statements by object type
+func (a *analyzer) analyzeDropObject(stmt *pg_query.DropStmt) *operationInfo {
 	cascade := ""
 	if stmt.Behavior == pg_query.DropBehavior_DROP_CASCADE {
 		cascade = " CASCADE"
@@ -962,6 +1648,16 @@ func (a *analyzer) analyzeDrop(stmt *pg_query.DropStmt) *operationInfo {
 			operation: "DROP CAST",
 			tableLock: AccessExclusive,
 		}
+	case pg_query.ObjectType_OBJECT_ACCESS_METHOD:
+		return &operationInfo{
+			operation: "DROP ACCESS METHOD",
+			tableLock: AccessShare,
+		}
+	case pg_query.ObjectType_OBJECT_TRANSFORM:
+		return &operationInfo{
+			operation: "DROP TRANSFORM",
+			tableLock: AccessShare,
+		}
 	case pg_query.ObjectType_OBJECT_COLLATION:
 		return &operationInfo{
 			operation: "DROP COLLATION",
@@ -1030,56 +1726,111 @@ func (a *analyzer) analyzeDrop(stmt *pg_query.DropStmt) *operationInfo {
 	}
 
 	return &operationInfo{
-		operation: "DROP",
-		tableLock: AccessExclusive,
+		operation:    "DROP",
+		tableLock:    AccessExclusive,
+		unrecognized: true,
 	}
 }
 
 // analyzeTruncate analyzes TRUNCATE statements
 func (a *analyzer) analyzeTruncate(stmt *pg_query.TruncateStmt) *operationInfo {
-	return &operationInfo{
+	opInfo := &operationInfo{
 		operation: "TRUNCATE",
 		tableLock: AccessExclusive,
 	}
+
+	// CONTINUE IDENTITY is the default and cannot be told apart from an explicit one
+	if stmt.RestartSeqs {
+		opInfo.qualifiers = append(opInfo.qualifiers, "restart identity")
+		opInfo.notes = append(opInfo.notes, "RESTART IDENTITY resets the sequences owned by columns of the truncated tables, so new rows get ids from the start again; ids kept elsewhere, such as in other tables or caches, can collide with them")
+	} else {
+		opInfo.notes = append(opInfo.notes, "CONTINUE IDENTITY (the default) keeps the sequences owned by columns of the truncated tables, so new rows get ids after the last one used; add RESTART IDENTITY to reset them")
+	}
+	return opInfo
 }
 
 // analyzeVacuum analyzes VACUUM statements
 func (a *analyzer) analyzeVacuum(stmt *pg_query.VacuumStmt) *operationInfo {
-	options := []string{}
-
-	// Check for FULL option
+	enabled := make(map[string]bool)
+	var notes []string
 	for _, opt := range stmt.Options {
-		if defElem := opt.GetDefElem(); defElem != nil {
-			if defElem.Defname == "full" {
-				options = append(options, "FULL")
+		defElem := opt.GetDefElem()
+		if defElem == nil {
+			continue
+		}
+		switch defElem.Defname {
+		case "full", "freeze", "analyze", "skip_locked", "disable_page_skipping", "only_database_stats":
+			enabled[defElem.Defname] = !isFalseOption(defElem.Arg)
+		case "process_main", "process_toast", "truncate":
+			if isFalseOption(defElem.Arg) {
+				notes = append(notes, fmt.Sprintf("%s false leaves part of the work undone; a later VACUUM has to catch up", strings.ToUpper(defElem.Defname)))
 			}
-			if defElem.Defname == "freeze" {
-				options = append(options, "FREEZE")
-			}
-			if defElem.Defname == "analyze" {
-				options = append(options, "ANALYZE")
+		case "index_cleanup":
+			if isFalseOption(defElem.Arg) {
+				notes = append(notes, "INDEX_CLEANUP off skips index vacuuming, which finishes sooner but leaves dead index entries and bloat; reserve it for wraparound emergencies")
 			}
+		case "buffer_usage_limit":
+			notes = append(notes, "BUFFER_USAGE_LIMIT caps the shared buffers VACUUM uses; a small limit reduces cache eviction but makes it run, and hold its lock, longer")
+		case "parallel":
+			notes = append(notes, "PARALLEL vacuums indexes with background workers; it does not apply to VACUUM FULL")
+		}
+	}
+
+	if enabled["only_database_stats"] {
+		return &operationInfo{
+			operation: "VACUUM ONLY_DATABASE_STATS",
+			tableLock: AccessShare,
+			scope:     ScopeDatabase,
+			notes:     []string{"only updates the database-wide oldest unfrozen transaction ID without vacuuming any table, so it takes no table locks"},
 		}
 	}
 
+	// FULL rewrites the table, so it decides the lock; FREEZE and ANALYZE refine a plain VACUUM
 	operation := "VACUUM"
-	if len(options) > 0 {
-		operation = "VACUUM " + strings.Join(options, " ")
+	var qualifiers []string
+	for _, option := range []string{"full", "freeze", "analyze"} {
+		if !enabled[option] {
+			continue
+		}
+		if operation == "VACUUM" {
+			operation = "VACUUM " + strings.ToUpper(option)
+			continue
+		}
+		qualifiers = append(qualifiers, option)
 	}
 
 	lockType := ShareUpdateExclusive
-	if contains(operation, "FULL") {
+	if enabled["full"] {
 		lockType = AccessExclusive
 	}
 
-	return &operationInfo{
-		operation: operation,
-		tableLock: lockType,
+	if enabled["skip_locked"] {
+		qualifiers = append(qualifiers, "skip locked")
+		note := "SKIP_LOCKED skips tables whose lock is not immediately available instead of waiting, so it never queues behind other sessions or blocks the sessions queued behind it; skipped tables are left unvacuumed"
+		if enabled["full"] {
+			note += ", and a table it does lock is still held AccessExclusive for the whole rewrite"
+		}
+		notes = append([]string{note}, notes...)
 	}
+	if enabled["disable_page_skipping"] {
+		qualifiers = append(qualifiers, "disable page skipping")
+		notes = append(notes, "DISABLE_PAGE_SKIPPING visits every page, including all-visible and all-frozen ones, so it runs and holds its lock much longer")
+	}
+
+	opInfo := &operationInfo{
+		operation:  operation,
+		tableLock:  lockType,
+		qualifiers: qualifiers,
+		notes:      notes,
+	}
+	if len(stmt.Rels) == 0 {
+		opInfo.scope = ScopeDatabase
+	}
+	return opInfo
 }
 
 // analyzeIndex analyzes INDEX statements
-func (a *analyzer) analyzeIndex(stmt *pg_query.IndexStmt) *operationInfo {
+func (a *analyzer) analyzeIndex(stmt *pg_query.IndexStmt, mode TransactionMode) *operationInfo {
 	operation := "CREATE INDEX"
 	if stmt.Unique {
 		operation = "CREATE UNIQUE INDEX"
@@ -1094,10 +1845,70 @@ func (a *analyzer) analyzeIndex(stmt *pg_query.IndexStmt) *operationInfo {
 		lockType = ShareUpdateExclusive
 	}
 
-	return &operationInfo{
+	// Without ONLY, an index on a partitioned table is built on every partition in one statement
+	partitioned := stmt.Relation != nil && stmt.Relation.Inh && a.partitionedTables[getQualifiedTableName(stmt.Relation)]
+	if partitioned && !stmt.Concurrent {
+		operation = fmt.Sprintf("%s ON PARTITIONED TABLE", operation)
+	}
+
+	opInfo := &operationInfo{
 		operation: operation,
 		tableLock: lockType,
 	}
+	if partitioned {
+		table := getQualifiedTableName(stmt.Relation)
+		if stmt.Concurrent {
+			// Inside a transaction block it already fails for that reason
+			if mode == NoTransaction {
+				opInfo.errorReason = ErrorReasonPartitionedTable
+			}
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+				"%s is partitioned and CONCURRENTLY is not supported on partitioned tables; create the index ON ONLY %s, then build it CONCURRENTLY on each partition and ALTER INDEX ... ATTACH PARTITION",
+				table, table))
+		} else {
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+				"%s is partitioned; the index is built on every partition while each holds a Share lock, and CONCURRENTLY must be applied per partition",
+				table))
+		}
+	}
+	if stmt.Relation != nil && !stmt.Relation.Inh && a.partitionedTables[getQualifiedTableName(stmt.Relation)] {
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"ON ONLY creates an INVALID index on %s alone; it becomes valid once an index on each partition is attached with ALTER INDEX ... ATTACH PARTITION",
+			getQualifiedTableName(stmt.Relation)))
+	}
+
+	// Label the index kind; severity and lock are the same for every kind
+	if stmt.WhereClause != nil {
+		opInfo.qualifiers = append(opInfo.qualifiers, "partial")
+	}
+
+	var funcNames []string
+	hasExpression := false
+	for _, param := range stmt.IndexParams {
+		if elem := param.GetIndexElem(); elem != nil && elem.Expr != nil {
+			hasExpression = true
+			funcNames = append(funcNames, collectFunctionNames(elem.Expr)...)
+		}
+	}
+	if hasExpression {
+		opInfo.qualifiers = append(opInfo.qualifiers, "expression")
+	}
+
+	if len(stmt.IndexIncludingParams) > 0 {
+		opInfo.qualifiers = append(opInfo.qualifiers, "covering")
+	}
+
+	// Index expressions must be IMMUTABLE and are evaluated for every row during the build
+	for _, funcName := range funcNames {
+		if isVolatileFunction(funcName) {
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf("index expression calls %s(), which is not IMMUTABLE; the index build will fail", funcName))
+		}
+	}
+	if len(funcNames) > 0 && len(opInfo.notes) == 0 {
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf("index expression calls %s(); it must be IMMUTABLE and is evaluated for every row during the build", strings.Join(funcNames, "(), ")))
+	}
+
+	return opInfo
 }
 
 // analyzeLock analyzes LOCK statements
@@ -1151,81 +1962,142 @@ func (a *analyzer) analyzeMerge(stmt *pg_query.MergeStmt) *operationInfo {
 
 // analyzeCopy analyzes COPY statements
 func (a *analyzer) analyzeCopy(stmt *pg_query.CopyStmt) *operationInfo {
-	if stmt.IsFrom {
-		return &operationInfo{
-			operation: "COPY FROM",
-			tableLock: RowExclusive,
-		}
-	}
-	return &operationInfo{
+	opInfo := &operationInfo{
 		operation: "COPY TO",
 		tableLock: AccessShare,
 	}
+	if stmt.IsFrom {
+		opInfo.operation = "COPY FROM"
+		opInfo.tableLock = RowExclusive
+	}
+
+	if stmt.IsProgram {
+		opInfo.operation += " PROGRAM"
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"runs the shell command '%s' on the database server as the operating system user running PostgreSQL; it requires superuser or pg_execute_server_program, and the migration depends on the command being available and succeeding on the server",
+			stmt.Filename))
+	}
+	return opInfo
 }
 
 // analyzeAnalyze analyzes ANALYZE statements
 func (a *analyzer) analyzeAnalyze(stmt *pg_query.VacuumStmt) *operationInfo {
-	return &operationInfo{
+	opInfo := &operationInfo{
 		operation: "ANALYZE",
 		tableLock: ShareUpdateExclusive,
 	}
+
+	if len(stmt.Rels) == 0 {
+		opInfo.scope = ScopeDatabase
+		opInfo.notes = append(opInfo.notes, "analyzes every table in the current database, taking a ShareUpdateExclusive lock on each; inside a transaction block all of these locks are held until commit")
+		return opInfo
+	}
+
+	// Column-scoped ANALYZE samples only the listed columns
+	for _, rel := range stmt.Rels {
+		vacRel := rel.GetVacuumRelation()
+		if vacRel == nil || vacRel.Relation == nil || len(vacRel.VaCols) == 0 {
+			continue
+		}
+		columns := make([]string, 0, len(vacRel.VaCols))
+		for _, col := range vacRel.VaCols {
+			if str := col.GetString_(); str != nil {
+				columns = append(columns, str.Sval)
+			}
+		}
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"limited to columns %s of %s; statistics for other columns are left unchanged",
+			strings.Join(columns, ", "), getQualifiedTableName(vacRel.Relation)))
+	}
+
+	return opInfo
 }
 
 // analyzeCluster analyzes CLUSTER statements
 func (a *analyzer) analyzeCluster(stmt *pg_query.ClusterStmt) *operationInfo {
-	return &operationInfo{
+	// CLUSTER without a table reclusters every previously clustered table and cannot run in a transaction block
+	if stmt.Relation == nil {
+		return &operationInfo{
+			operation: "CLUSTER without table",
+			tableLock: AccessExclusive,
+			scope:     ScopeDatabase,
+			notes: []string{
+				"reclusters every table in the database that was clustered before, one after another, each under an AccessExclusive lock; name the tables to cluster instead",
+			},
+		}
+	}
+
+	opInfo := &operationInfo{
 		operation: "CLUSTER",
 		tableLock: AccessExclusive,
 	}
+	if stmt.Indexname == "" {
+		opInfo.qualifiers = append(opInfo.qualifiers, "previous index")
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"reclusters %s on the index it was last clustered on, and fails if it was never clustered; name the index with USING to make the migration explicit",
+			getQualifiedTableName(stmt.Relation)))
+	}
+	return opInfo
 }
 
 // analyzeReindex analyzes REINDEX statements
-func (a *analyzer) analyzeReindex(stmt *pg_query.ReindexStmt) *operationInfo {
+func (a *analyzer) analyzeReindex(stmt *pg_query.ReindexStmt, mode TransactionMode) *operationInfo {
+	// VERBOSE and TABLESPACE do not change locking; only CONCURRENTLY does
+	concurrently := reindexConcurrently(stmt.Params)
+
 	switch stmt.Kind {
 	case pg_query.ReindexObjectType_REINDEX_OBJECT_DATABASE:
+		if concurrently {
+			return &operationInfo{
+				operation: "REINDEX DATABASE CONCURRENTLY",
+				tableLock: ShareUpdateExclusive,
+				notes:     []string{"system catalogs cannot be reindexed concurrently and are skipped; reindex them separately with REINDEX SYSTEM"},
+			}
+		}
 		return &operationInfo{
 			operation: "REINDEX DATABASE",
 			tableLock: AccessExclusive,
 		}
 	case pg_query.ReindexObjectType_REINDEX_OBJECT_SCHEMA:
+		if concurrently {
+			opInfo := &operationInfo{
+				operation: "REINDEX SCHEMA CONCURRENTLY",
+				tableLock: ShareUpdateExclusive,
+			}
+			if stmt.Name == "pg_catalog" {
+				rejectSystemConcurrently(opInfo, mode)
+			}
+			return opInfo
+		}
 		return &operationInfo{
 			operation: "REINDEX SCHEMA",
 			tableLock: AccessExclusive,
 		}
 	case pg_query.ReindexObjectType_REINDEX_OBJECT_SYSTEM:
-		return &operationInfo{
+		opInfo := &operationInfo{
 			operation: "REINDEX SYSTEM",
 			tableLock: AccessExclusive,
 		}
-	case pg_query.ReindexObjectType_REINDEX_OBJECT_TABLE:
-		if stmt.Params != nil {
-			for _, defElem := range stmt.Params {
-				if de := defElem.GetDefElem(); de != nil && de.Defname == "concurrently" {
-					return &operationInfo{
-						operation: "REINDEX CONCURRENTLY",
-						tableLock: ShareUpdateExclusive,
-					}
-				}
-			}
-		}
-		return &operationInfo{
-			operation: "REINDEX TABLE",
-			tableLock: AccessExclusive,
+		if concurrently {
+			rejectSystemConcurrently(opInfo, mode)
 		}
-	case pg_query.ReindexObjectType_REINDEX_OBJECT_INDEX:
-		if stmt.Params != nil {
-			for _, defElem := range stmt.Params {
-				if de := defElem.GetDefElem(); de != nil && de.Defname == "concurrently" {
-					return &operationInfo{
-						operation: "REINDEX CONCURRENTLY",
-						tableLock: ShareUpdateExclusive,
-					}
-				}
+		return opInfo
+	case pg_query.ReindexObjectType_REINDEX_OBJECT_TABLE, pg_query.ReindexObjectType_REINDEX_OBJECT_INDEX:
+		if concurrently {
+			opInfo := &operationInfo{
+				operation: "REINDEX CONCURRENTLY",
+				tableLock: ShareUpdateExclusive,
 			}
+			if stmt.Relation != nil && stmt.Relation.Schemaname == "pg_catalog" {
+				rejectSystemConcurrently(opInfo, mode)
+			}
+			return opInfo
 		}
-		return &operationInfo{
-			operation: "REINDEX",
-			tableLock: AccessExclusive,
+		if stmt.Kind == pg_query.ReindexObjectType_REINDEX_OBJECT_TABLE {
+			return &operationInfo{
+				operation: "REINDEX TABLE",
+				tableLock: AccessExclusive,
+			}
 		}
 	}
 
@@ -1235,12 +2107,36 @@ func (a *analyzer) analyzeReindex(stmt *pg_query.ReindexStmt) *operationInfo {
 	}
 }
 
+// reindexSystemConcurrentlyNote explains that CONCURRENTLY is rejected for system catalogs
+const reindexSystemConcurrentlyNote = "system catalogs cannot be reindexed concurrently; PostgreSQL rejects this statement, and without CONCURRENTLY it takes AccessExclusive locks"
+
+// rejectSystemConcurrently marks a concurrent reindex of system catalogs as failing
+func rejectSystemConcurrently(opInfo *operationInfo, mode TransactionMode) {
+	// Inside a transaction block it already fails for that reason
+	if mode == NoTransaction {
+		opInfo.errorReason = ErrorReasonSystemCatalog
+	}
+	opInfo.notes = []string{reindexSystemConcurrentlyNote}
+}
+
+// reindexConcurrently checks the REINDEX option list for CONCURRENTLY, which may be
+// combined with other options such as VERBOSE or disabled with CONCURRENTLY false
+func reindexConcurrently(params []*pg_query.Node) bool {
+	for _, param := range params {
+		if de := param.GetDefElem(); de != nil && de.Defname == "concurrently" {
+			return !isFalseOption(de.Arg)
+		}
+	}
+	return false
+}
+
 // analyzeCreateView analyzes CREATE VIEW statements
 func (a *analyzer) analyzeCreateView(stmt *pg_query.ViewStmt) *operationInfo {
 	if stmt.Replace {
 		return &operationInfo{
 			operation: "CREATE OR REPLACE VIEW",
 			tableLock: AccessExclusive,
+			notes:     []string{"can only append new output columns; renaming, reordering, dropping or retyping existing columns fails, and views depending on this view must then be dropped and recreated"},
 		}
 	}
 	return &operationInfo{
@@ -1345,10 +2241,25 @@ func (a *analyzer) analyzeAlterType(stmt *pg_query.AlterEnumStmt) *operationInfo
 
 // analyzeCreateDatabase analyzes CREATE DATABASE statements
 func (a *analyzer) analyzeCreateDatabase(stmt *pg_query.CreatedbStmt) *operationInfo {
-	return &operationInfo{
+	opInfo := &operationInfo{
 		operation: "CREATE DATABASE",
 		tableLock: AccessExclusive,
 	}
+
+	for _, option := range stmt.Options {
+		defElem := option.GetDefElem()
+		if defElem == nil || defElem.Defname != "template" {
+			continue
+		}
+		// template0 does not accept connections, so nothing can be connected to it
+		template := defElem.Arg.GetString_().GetSval()
+		if template != "" && template != "template0" {
+			opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+				"copying template %s fails if any other session is connected to it, after waiting only a few seconds; close its connections before running the migration",
+				template))
+		}
+	}
+	return opInfo
 }
 
 // analyzeAlterDatabase analyzes ALTER DATABASE statements
@@ -1401,15 +2312,30 @@ func (a *analyzer) analyzeGrant(stmt *pg_query.GrantStmt) *operationInfo {
 		objType = " ON FOREIGN DATA WRAPPER"
 	}
 
+	operation := "REVOKE" + objType
 	if stmt.IsGrant {
-		return &operationInfo{
-			operation: "GRANT" + objType,
-			tableLock: AccessShare,
+		operation = "GRANT" + objType
+	}
+
+	// Privileges on named tables are checked against each table under AccessShare
+	var tableLocks map[string]LockType
+	if stmt.Objtype == pg_query.ObjectType_OBJECT_TABLE && stmt.Targtype == pg_query.GrantTargetType_ACL_TARGET_OBJECT {
+		for _, obj := range stmt.Objects {
+			table := getQualifiedTableName(obj.GetRangeVar())
+			if table == "" {
+				continue
+			}
+			if tableLocks == nil {
+				tableLocks = make(map[string]LockType)
+			}
+			tableLocks[table] = AccessShare
 		}
 	}
+
 	return &operationInfo{
-		operation: "REVOKE" + objType,
-		tableLock: AccessShare,
+		operation:            operation,
+		tableLock:            AccessShare,
+		additionalTableLocks: tableLocks,
 	}
 }
 
@@ -1462,10 +2388,69 @@ func (a *analyzer) analyzeAlterDefaultPrivileges(stmt *pg_query.AlterDefaultPriv
 
 // analyzeCreateTrigger analyzes CREATE TRIGGER statements
 func (a *analyzer) analyzeCreateTrigger(stmt *pg_query.CreateTrigStmt) *operationInfo {
+	table := "the table"
+	if stmt.Relation != nil {
+		table = getQualifiedTableName(stmt.Relation)
+	}
+
+	timing := "AFTER"
+	switch {
+	case stmt.Timing&triggerTypeBefore != 0:
+		timing = "BEFORE"
+	case stmt.Timing&triggerTypeInstead != 0:
+		timing = "INSTEAD OF"
+	}
+	trigger := fmt.Sprintf("%s %s", timing, triggerEvents(stmt.Events))
+
+	var granularity string
+	switch {
+	case stmt.Isconstraint && stmt.Initdeferred:
+		granularity = fmt.Sprintf("constraint trigger (%s FOR EACH ROW) is INITIALLY DEFERRED: every row event is queued until COMMIT, so large transactions hold them all in memory", trigger)
+	case stmt.Isconstraint:
+		granularity = fmt.Sprintf("constraint trigger (%s FOR EACH ROW) runs once per affected row at the end of each statement, or at COMMIT after SET CONSTRAINTS ... DEFERRED", trigger)
+	case stmt.Row:
+		granularity = fmt.Sprintf("%s FOR EACH ROW trigger runs once per affected row, adding per-row overhead to every such write on %s", trigger, table)
+	default:
+		granularity = fmt.Sprintf("%s FOR EACH STATEMENT trigger runs once per statement however many rows it affects, so its overhead does not grow with the rows written", trigger)
+	}
+
 	return &operationInfo{
 		operation: "CREATE TRIGGER",
 		tableLock: ShareRowExclusive,
+		notes: []string{
+			granularity,
+			fmt.Sprintf("creating the trigger itself takes ShareRowExclusive on %s, blocking writes until the transaction ends", table),
+		},
+	}
+}
+
+// Trigger timing and event bits of CreateTrigStmt, as in PostgreSQL's pg_trigger.h
+const (
+	triggerTypeBefore   = 1 << 1
+	triggerTypeInsert   = 1 << 2
+	triggerTypeDelete   = 1 << 3
+	triggerTypeUpdate   = 1 << 4
+	triggerTypeTruncate = 1 << 5
+	triggerTypeInstead  = 1 << 6
+)
+
+// triggerEvents formats trigger event bits, e.g. "INSERT OR UPDATE"
+func triggerEvents(events int32) string {
+	var names []string
+	for _, event := range []struct {
+		bit  int32
+		name string
+	}{
+		{triggerTypeInsert, "INSERT"},
+		{triggerTypeUpdate, "UPDATE"},
+		{triggerTypeDelete, "DELETE"},
+		{triggerTypeTruncate, "TRUNCATE"},
+	} {
+		if events&event.bit != 0 {
+			names = append(names, event.name)
+		}
 	}
+	return strings.Join(names, " OR ")
 }
 
 // analyzeCreateRule analyzes CREATE RULE statements
@@ -1525,8 +2510,9 @@ func (a *analyzer) analyzeTransaction(stmt *pg_query.TransactionStmt) *operation
 	}
 
 	return &operationInfo{
-		operation: "TRANSACTION",
-		tableLock: AccessShare,
+		operation:    "TRANSACTION",
+		tableLock:    AccessShare,
+		unrecognized: true,
 	}
 }
 
@@ -1539,11 +2525,13 @@ func (a *analyzer) analyzeVariableSet(stmt *pg_query.VariableSetStmt) *operation
 			return &operationInfo{
 				operation: "SET LOCAL",
 				tableLock: AccessShare,
+				notes:     a.searchPathNotes(stmt),
 			}
 		}
 		return &operationInfo{
 			operation: "SET",
 			tableLock: AccessShare,
+			notes:     a.searchPathNotes(stmt),
 		}
 	case pg_query.VariableSetKind_VAR_SET_CURRENT:
 		return &operationInfo{
@@ -1554,6 +2542,7 @@ func (a *analyzer) analyzeVariableSet(stmt *pg_query.VariableSetStmt) *operation
 		return &operationInfo{
 			operation: "RESET",
 			tableLock: AccessShare,
+			notes:     a.searchPathNotes(stmt),
 		}
 	case pg_query.VariableSetKind_VAR_SET_MULTI:
 		// VAR_SET_MULTI is used for both SET TRANSACTION and SET CONSTRAINTS
@@ -1572,6 +2561,7 @@ func (a *analyzer) analyzeVariableSet(stmt *pg_query.VariableSetStmt) *operation
 		return &operationInfo{
 			operation: "RESET",
 			tableLock: AccessShare,
+			notes:     a.searchPathNotes(stmt),
 		}
 	case pg_query.VariableSetKind_VAR_RESET_ALL:
 		return &operationInfo{
@@ -1586,6 +2576,33 @@ func (a *analyzer) analyzeVariableSet(stmt *pg_query.VariableSetStmt) *operation
 	}
 }
 
+// searchPathNotes notes in verbose output that a SET or RESET of search_path changes how later
+// unqualified names resolve, which the analyzer does not follow
+func (a *analyzer) searchPathNotes(stmt *pg_query.VariableSetStmt) []string {
+	if !a.options.Verbose || !strings.EqualFold(stmt.Name, "search_path") {
+		return nil
+	}
+
+	// SET search_path = DEFAULT and RESET search_path have no value to name
+	path := "the default search_path"
+	if stmt.Kind == pg_query.VariableSetKind_VAR_SET_VALUE {
+		schemas := make([]string, 0, len(stmt.Args))
+		for _, arg := range stmt.Args {
+			if sval := arg.GetAConst().GetSval(); sval != nil && sval.Sval != "" {
+				schemas = append(schemas, sval.Sval)
+			}
+		}
+		path = "an empty search_path"
+		if len(schemas) > 0 {
+			path = "search_path " + strings.Join(schemas, ", ")
+		}
+	}
+	return []string{fmt.Sprintf(
+		"later unqualified names resolve under %s; pg-lock-check reports them as written without applying the path",
+		path,
+	)}
+}
+
 // analyzeAlterSystem analyzes ALTER SYSTEM statements
 func (a *analyzer) analyzeAlterSystem(stmt *pg_query.AlterSystemStmt) *operationInfo {
 	return &operationInfo{
@@ -1713,7 +2730,9 @@ func (a *analyzer) analyzeRename(stmt *pg_query.RenameStmt) *operationInfo {
 	case pg_query.ObjectType_OBJECT_SCHEMA:
 		return &operationInfo{
 			operation: "ALTER SCHEMA RENAME TO",
-			tableLock: AccessExclusive,
+			tableLock: AccessShare,
+			notes: []string{"locks only the schema, not its tables; search_path settings, function bodies and " +
+				"application queries that reference the old schema name must be updated"},
 		}
 	case pg_query.ObjectType_OBJECT_TABLESPACE:
 		return &operationInfo{
@@ -1722,8 +2741,9 @@ func (a *analyzer) analyzeRename(stmt *pg_query.RenameStmt) *operationInfo {
 		}
 	default:
 		return &operationInfo{
-			operation: "ALTER RENAME",
-			tableLock: AccessExclusive,
+			operation:    "ALTER RENAME",
+			tableLock:    AccessExclusive,
+			unrecognized: true,
 		}
 	}
 }
@@ -1784,13 +2804,9 @@ func (a *analyzer) analyzeDefine(stmt *pg_query.DefineStmt) *operationInfo {
 		}
 	default:
 		return &operationInfo{
-			operation: "UNKNOWN",
-			tableLock: AccessShare,
+			operation:    "UNKNOWN",
+			tableLock:    AccessShare,
+			unrecognized: true,
 		}
 	}
 }
-
-// Helper function to check if a string contains a substring
-func contains(s, substr string) bool {
-	return strings.Contains(s, substr)
-}
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/pganalyze/pg_query_go/v6"
+)
+
+// preparedStatement is a statement prepared earlier in the input, which later EXECUTEs run
+type preparedStatement struct {
+	query      *pg_query.Node
+	sql        string
+	lineNumber int
+}
+
+// preparedTarget returns the statement a PREPARE defines or an EXECUTE of a statement prepared
+// earlier runs, which is analyzed in their place, with its SQL, the qualifier naming the prepared
+// statement and a note. Other statements are returned unchanged with an empty qualifier.
+func (a *analyzer) preparedTarget(node *pg_query.Node, sql string) (target *pg_query.Node, targetSQL, qualifier, note string) {
+	if prepare := node.GetPrepareStmt(); prepare != nil && prepare.Query != nil {
+		return prepare.Query, sql, "prepared as " + prepare.Name, fmt.Sprintf(
+			"PREPARE only defines %s; these locks are taken each time EXECUTE %s runs it",
+			prepare.Name, prepare.Name)
+	}
+	if execute := node.GetExecuteStmt(); execute != nil {
+		if prepared, ok := a.prepared[execute.Name]; ok {
+			return prepared.query, prepared.sql, "executes " + execute.Name, fmt.Sprintf(
+				"runs the statement prepared as %s at line %d", execute.Name, prepared.lineNumber)
+		}
+	}
+	return node, sql, "", ""
+}
+
+// analyzeExecute analyzes EXECUTE of a statement not prepared earlier in the input
+func (a *analyzer) analyzeExecute(stmt *pg_query.ExecuteStmt) *operationInfo {
+	return &operationInfo{
+		operation: "EXECUTE",
+		tableLock: AccessShare,
+		notes: []string{fmt.Sprintf(
+			"%s is not prepared earlier in the input, so the locks of the statement it runs are unknown",
+			stmt.Name)},
+	}
+}
+
+// recordPrepared remembers the statements prepared in the input, so later EXECUTEs are analyzed
+// as them, and forgets those a DEALLOCATE removes
+func (a *analyzer) recordPrepared(stmt parser.ParsedStatement) {
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+	node := stmt.AST.Stmts[0].Stmt
+
+	if prepare := node.GetPrepareStmt(); prepare != nil && prepare.Query != nil {
+		a.prepared[prepare.Name] = preparedStatement{
+			query:      prepare.Query,
+			sql:        stmt.SQL,
+			lineNumber: stmt.LineNumber,
+		}
+	}
+	if deallocate := node.GetDeallocateStmt(); deallocate != nil {
+		if deallocate.Isall {
+			a.prepared = make(map[string]preparedStatement)
+		} else {
+			delete(a.prepared, deallocate.Name)
+		}
+	}
+}
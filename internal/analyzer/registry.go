@@ -31,6 +31,22 @@ func (r *operationRegistry) getSeverityAndLock(operation string, mode Transactio
 	return SeverityInfo, AccessShare
 }
 
+// known reports whether the operation has an entry, rather than the default INFO and AccessShare
+func (r *operationRegistry) known(operation string) bool {
+	_, exists := r.operations[operation]
+	return exists
+}
+
+// recommendedMode derives the safe transaction mode from the operation's ERROR entries
+func (r *operationRegistry) recommendedMode(operation string) RecommendedMode {
+	inTxn, _ := r.getSeverityAndLock(operation, InTransaction)
+	noTxn, _ := r.getSeverityAndLock(operation, NoTransaction)
+	if inTxn == SeverityError && noTxn != SeverityError {
+		return RecommendedModeNoTransaction
+	}
+	return RecommendedModeEither
+}
+
 // register adds an operation to the registry
 func (r *operationRegistry) register(operation string, inTxn, noTxn *registryOperationInfo) {
 	r.operations[operation] = map[TransactionMode]*registryOperationInfo{
@@ -69,6 +85,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("VACUUM ANALYZE",
 		&registryOperationInfo{SeverityError, ShareUpdateExclusive},
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
+	r.register("VACUUM ONLY_DATABASE_STATS",
+		&registryOperationInfo{SeverityError, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("CREATE INDEX CONCURRENTLY",
 		&registryOperationInfo{SeverityError, ShareUpdateExclusive},
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
@@ -78,6 +97,12 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("REINDEX CONCURRENTLY",
 		&registryOperationInfo{SeverityError, ShareUpdateExclusive},
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
+	r.register("REINDEX SCHEMA CONCURRENTLY",
+		&registryOperationInfo{SeverityError, ShareUpdateExclusive},
+		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
+	r.register("REINDEX DATABASE CONCURRENTLY",
+		&registryOperationInfo{SeverityError, ShareUpdateExclusive},
+		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
 	r.register("REFRESH MATERIALIZED VIEW CONCURRENTLY",
 		&registryOperationInfo{SeverityError, Exclusive},
 		&registryOperationInfo{SeverityWarning, Exclusive})
@@ -134,6 +159,12 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("CREATE UNIQUE INDEX",
 		&registryOperationInfo{SeverityCritical, Share},
 		&registryOperationInfo{SeverityCritical, Share})
+	r.register("CREATE INDEX ON PARTITIONED TABLE",
+		&registryOperationInfo{SeverityCritical, Share},
+		&registryOperationInfo{SeverityCritical, Share})
+	r.register("CREATE UNIQUE INDEX ON PARTITIONED TABLE",
+		&registryOperationInfo{SeverityCritical, Share},
+		&registryOperationInfo{SeverityCritical, Share})
 	r.register("REINDEX",
 		&registryOperationInfo{SeverityCritical, AccessExclusive},
 		&registryOperationInfo{SeverityCritical, AccessExclusive})
@@ -152,6 +183,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("CLUSTER",
 		&registryOperationInfo{SeverityCritical, AccessExclusive},
 		&registryOperationInfo{SeverityCritical, AccessExclusive})
+	r.register("CLUSTER without table",
+		&registryOperationInfo{SeverityError, AccessExclusive},
+		&registryOperationInfo{SeverityCritical, AccessExclusive})
 	r.register("REFRESH MATERIALIZED VIEW",
 		&registryOperationInfo{SeverityCritical, AccessExclusive},
 		&registryOperationInfo{SeverityCritical, AccessExclusive})
@@ -232,9 +266,18 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("SELECT INTO",
 		&registryOperationInfo{SeverityWarning, AccessShare},
 		&registryOperationInfo{SeverityWarning, AccessShare})
+	r.register("SELECT pg_advisory_lock",
+		&registryOperationInfo{SeverityWarning, AccessShare},
+		&registryOperationInfo{SeverityWarning, AccessShare})
 	r.register("COPY FROM",
 		&registryOperationInfo{SeverityWarning, RowExclusive},
 		&registryOperationInfo{SeverityWarning, RowExclusive})
+	r.register("COPY FROM PROGRAM",
+		&registryOperationInfo{SeverityWarning, RowExclusive},
+		&registryOperationInfo{SeverityWarning, RowExclusive})
+	r.register("COPY TO PROGRAM",
+		&registryOperationInfo{SeverityWarning, AccessShare},
+		&registryOperationInfo{SeverityWarning, AccessShare})
 	r.register("ANALYZE",
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive},
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
@@ -253,9 +296,27 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("ALTER TABLE ADD CONSTRAINT EXCLUDE",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER TABLE ADD PRIMARY KEY USING INDEX",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER TABLE ADD CONSTRAINT UNIQUE USING INDEX",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("ALTER TABLE ADD CONSTRAINT NOT VALID",
 		&registryOperationInfo{SeverityWarning, ShareRowExclusive},
 		&registryOperationInfo{SeverityWarning, ShareRowExclusive})
+	r.register("ALTER TABLE ADD COLUMN with PRIMARY KEY",
+		&registryOperationInfo{SeverityCritical, AccessExclusive},
+		&registryOperationInfo{SeverityCritical, AccessExclusive})
+	r.register("ALTER TABLE ADD COLUMN with UNIQUE",
+		&registryOperationInfo{SeverityCritical, AccessExclusive},
+		&registryOperationInfo{SeverityCritical, AccessExclusive})
+	r.register("ALTER TABLE ADD COLUMN with FOREIGN KEY",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER TABLE ADD FOREIGN KEY NOT VALID",
+		&registryOperationInfo{SeverityWarning, ShareRowExclusive},
+		&registryOperationInfo{SeverityWarning, ShareRowExclusive})
 	r.register("ALTER TABLE VALIDATE CONSTRAINT",
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive},
 		&registryOperationInfo{SeverityWarning, ShareUpdateExclusive})
@@ -289,6 +350,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("ALTER TABLE RENAME COLUMN",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER TABLE RENAME CONSTRAINT",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("ALTER TABLE INHERIT",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
@@ -352,18 +416,45 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("ALTER VIEW",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("CREATE OR REPLACE VIEW",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("ALTER VIEW RENAME TO",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER MATERIALIZED VIEW RENAME TO",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("ALTER SEQUENCE",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER SEQUENCE RENAME TO",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER SET SCHEMA",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER DATABASE RENAME TO",
+		&registryOperationInfo{SeverityWarning, Exclusive},
+		&registryOperationInfo{SeverityWarning, Exclusive})
 	r.register("ALTER TYPE",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("ALTER DOMAIN",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
+	r.register("ALTER DOMAIN ADD CONSTRAINT",
+		&registryOperationInfo{SeverityWarning, Share},
+		&registryOperationInfo{SeverityWarning, Share})
+	r.register("ALTER DOMAIN ADD CONSTRAINT NOT VALID",
+		&registryOperationInfo{SeverityWarning, AccessShare},
+		&registryOperationInfo{SeverityWarning, AccessShare})
+	r.register("ALTER DOMAIN VALIDATE CONSTRAINT",
+		&registryOperationInfo{SeverityWarning, Share},
+		&registryOperationInfo{SeverityWarning, Share})
+	r.register("ALTER DOMAIN DROP CONSTRAINT",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("REASSIGN OWNED",
 		&registryOperationInfo{SeverityWarning, AccessExclusive},
 		&registryOperationInfo{SeverityWarning, AccessExclusive})
@@ -384,6 +475,9 @@ func (r *operationRegistry) initializeOperations() {
 		&registryOperationInfo{SeverityWarning, Exclusive})
 
 	// INFO operations
+	r.register("SELECT",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("SELECT FOR UPDATE",
 		&registryOperationInfo{SeverityInfo, RowShare},
 		&registryOperationInfo{SeverityInfo, RowShare})
@@ -396,6 +490,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("SELECT FOR KEY SHARE",
 		&registryOperationInfo{SeverityInfo, RowShare},
 		&registryOperationInfo{SeverityInfo, RowShare})
+	r.register("SELECT pg_try_advisory_lock",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("INSERT",
 		&registryOperationInfo{SeverityInfo, RowExclusive},
 		&registryOperationInfo{SeverityInfo, RowExclusive})
@@ -423,6 +520,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("ALTER TABLE ALTER COLUMN DROP IDENTITY",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
+	r.register("ALTER TABLE ALTER COLUMN SET IDENTITY",
+		&registryOperationInfo{SeverityInfo, AccessExclusive},
+		&registryOperationInfo{SeverityInfo, AccessExclusive})
 	r.register("ALTER TABLE SET DEFAULT",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
@@ -450,6 +550,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("CREATE TABLE",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
+	r.register("CREATE TABLE PARTITION OF",
+		&registryOperationInfo{SeverityWarning, AccessExclusive},
+		&registryOperationInfo{SeverityWarning, AccessExclusive})
 	r.register("CREATE TEMPORARY TABLE",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
@@ -471,6 +574,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("CREATE SCHEMA",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
+	r.register("ALTER SCHEMA RENAME TO",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("CREATE EXTENSION",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
@@ -486,6 +592,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("DROP PROCEDURE",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
+	r.register("CALL",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("CREATE AGGREGATE",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
@@ -504,6 +613,18 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("DROP CAST",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
+	r.register("CREATE ACCESS METHOD",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("DROP ACCESS METHOD",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("CREATE TRANSFORM",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("DROP TRANSFORM",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("CREATE COLLATION",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
@@ -570,9 +691,21 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("DROP PUBLICATION",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
-	r.register("ALTER PUBLICATION ADD/DROP TABLE",
+	r.register("ALTER PUBLICATION",
+		&registryOperationInfo{SeverityInfo, AccessExclusive},
+		&registryOperationInfo{SeverityInfo, AccessExclusive})
+	r.register("ALTER PUBLICATION ADD TABLE",
+		&registryOperationInfo{SeverityInfo, ShareUpdateExclusive},
+		&registryOperationInfo{SeverityInfo, ShareUpdateExclusive})
+	r.register("ALTER PUBLICATION DROP TABLE",
 		&registryOperationInfo{SeverityInfo, ShareUpdateExclusive},
 		&registryOperationInfo{SeverityInfo, ShareUpdateExclusive})
+	r.register("ALTER PUBLICATION SET TABLE",
+		&registryOperationInfo{SeverityInfo, ShareUpdateExclusive},
+		&registryOperationInfo{SeverityInfo, ShareUpdateExclusive})
+	r.register("ALTER EXTENSION ADD/DROP",
+		&registryOperationInfo{SeverityInfo, AccessExclusive},
+		&registryOperationInfo{SeverityInfo, AccessExclusive})
 	r.register("ALTER DEFAULT PRIVILEGES",
 		&registryOperationInfo{SeverityInfo, AccessExclusive},
 		&registryOperationInfo{SeverityInfo, AccessExclusive})
@@ -615,9 +748,21 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("COMMENT ON",
 		&registryOperationInfo{SeverityInfo, AccessShare},
 		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("EXECUTE",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("DEALLOCATE",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("SECURITY LABEL",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("LOCK TABLE ACCESS SHARE",
 		&registryOperationInfo{SeverityInfo, AccessShare},
 		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("LOCK TABLE",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("LOCK TABLE ROW SHARE",
 		&registryOperationInfo{SeverityInfo, RowShare},
 		&registryOperationInfo{SeverityInfo, RowShare})
@@ -648,6 +793,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("SET TRANSACTION",
 		&registryOperationInfo{SeverityInfo, AccessShare},
 		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("SET CONSTRAINTS",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 	r.register("SET LOCAL",
 		&registryOperationInfo{SeverityInfo, AccessShare},
 		&registryOperationInfo{SeverityInfo, AccessShare})
@@ -657,6 +805,9 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("RESET",
 		&registryOperationInfo{SeverityInfo, AccessShare},
 		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("RESET ALL",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 
 	// No-transaction mode specific
 	r.register("ALTER DATABASE",
@@ -668,4 +819,13 @@ func (r *operationRegistry) initializeOperations() {
 	r.register("LOAD",
 		&registryOperationInfo{SeverityInfo, AccessShare},
 		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("LISTEN",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("NOTIFY",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
+	r.register("UNLISTEN",
+		&registryOperationInfo{SeverityInfo, AccessShare},
+		&registryOperationInfo{SeverityInfo, AccessShare})
 }
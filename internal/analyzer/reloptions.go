@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/pganalyze/pg_query_go/v6"
+)
+
+// relOptionDefaults are the values of storage parameters that are not set on a table
+var relOptionDefaults = map[string]string{
+	"fillfactor":           "100",
+	"autovacuum_enabled":   "true",
+	"vacuum_index_cleanup": "auto",
+	"vacuum_truncate":      "true",
+	"user_catalog_table":   "false",
+}
+
+// resetTableOptions forgets storage parameters other than those from the options
+func (a *analyzer) resetTableOptions() {
+	a.tableOptions = make(map[string]map[string]string, len(a.options.TableOptions))
+	for table, options := range a.options.TableOptions {
+		current := make(map[string]string, len(options))
+		for name, value := range options {
+			current[strings.ToLower(name)] = normalizeRelOptionValue(value)
+		}
+		a.tableOptions[table] = current
+	}
+}
+
+// recordTableOptions tracks the storage parameters of tables created with WITH (...) and
+// changed by ALTER TABLE SET/RESET (...)
+func (a *analyzer) recordTableOptions(stmt parser.ParsedStatement) {
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+
+	switch n := stmt.AST.Stmts[0].Stmt.Node.(type) {
+	case *pg_query.Node_CreateStmt:
+		if n.CreateStmt.Relation == nil {
+			return
+		}
+		// A table created in the input has exactly the parameters of its WITH clause
+		current := make(map[string]string)
+		for _, item := range n.CreateStmt.Options {
+			if elem := item.GetDefElem(); elem != nil {
+				current[relOptionName(elem)] = relOptionValue(elem.Arg)
+			}
+		}
+		a.tableOptions[getQualifiedTableName(n.CreateStmt.Relation)] = current
+	case *pg_query.Node_AlterTableStmt:
+		current, known := a.tableOptions[getQualifiedTableName(n.AlterTableStmt.Relation)]
+		if !known || n.AlterTableStmt.Objtype != pg_query.ObjectType_OBJECT_TABLE {
+			return
+		}
+		for _, cmd := range n.AlterTableStmt.Cmds {
+			alterCmd := cmd.GetAlterTableCmd()
+			if alterCmd == nil || alterCmd.Def.GetList() == nil {
+				continue
+			}
+			for _, item := range alterCmd.Def.GetList().Items {
+				elem := item.GetDefElem()
+				if elem == nil {
+					continue
+				}
+				switch alterCmd.Subtype {
+				case pg_query.AlterTableType_AT_SetRelOptions:
+					current[relOptionName(elem)] = relOptionValue(elem.Arg)
+				case pg_query.AlterTableType_AT_ResetRelOptions:
+					delete(current, relOptionName(elem))
+				}
+			}
+		}
+	}
+}
+
+// noOpRelOptionsNote notes an ALTER TABLE that only sets storage parameters to their current values
+// or resets unset ones, when the table's parameters are known from the options or the input
+func (a *analyzer) noOpRelOptionsNote(stmt *pg_query.AlterTableStmt) string {
+	if stmt.Relation == nil || len(stmt.Cmds) == 0 {
+		return ""
+	}
+	table := getQualifiedTableName(stmt.Relation)
+	current, known := a.tableOptions[table]
+	if !known {
+		return ""
+	}
+
+	var unchanged []string
+	for _, cmd := range stmt.Cmds {
+		alterCmd := cmd.GetAlterTableCmd()
+		if alterCmd == nil || alterCmd.Def.GetList() == nil {
+			return ""
+		}
+		reset := alterCmd.Subtype == pg_query.AlterTableType_AT_ResetRelOptions
+		if !reset && alterCmd.Subtype != pg_query.AlterTableType_AT_SetRelOptions {
+			return ""
+		}
+
+		for _, item := range alterCmd.Def.GetList().Items {
+			elem := item.GetDefElem()
+			if elem == nil {
+				return ""
+			}
+			name := relOptionName(elem)
+			value, set := current[name]
+			if reset {
+				if set {
+					return ""
+				}
+				unchanged = append(unchanged, name+" is not set")
+				continue
+			}
+			if !set {
+				value, set = relOptionDefaults[elem.Defname]
+			}
+			if !set || value != relOptionValue(elem.Arg) {
+				return ""
+			}
+			unchanged = append(unchanged, name+" = "+value)
+		}
+	}
+
+	return fmt.Sprintf(
+		"likely a no-op: the storage parameters of %s already have these values (%s), but the statement still takes a ShareUpdateExclusive lock; drop it from the migration",
+		table, strings.Join(unchanged, ", "))
+}
+
+// relOptionName returns a storage parameter name, prefixed with its namespace such as toast.
+func relOptionName(elem *pg_query.DefElem) string {
+	if elem.Defnamespace != "" {
+		return strings.ToLower(elem.Defnamespace + "." + elem.Defname)
+	}
+	return strings.ToLower(elem.Defname)
+}
+
+// relOptionValue returns a storage parameter value as text comparable with normalizeRelOptionValue
+func relOptionValue(arg *pg_query.Node) string {
+	if arg == nil {
+		// A bare parameter name means true
+		return "true"
+	}
+
+	switch v := arg.Node.(type) {
+	case *pg_query.Node_Integer:
+		return strconv.Itoa(int(v.Integer.Ival))
+	case *pg_query.Node_Float:
+		return normalizeRelOptionValue(v.Float.Fval)
+	case *pg_query.Node_Boolean:
+		return strconv.FormatBool(v.Boolean.Boolval)
+	case *pg_query.Node_String_:
+		return normalizeRelOptionValue(v.String_.Sval)
+	case *pg_query.Node_TypeName:
+		// Unquoted words such as off are parsed as type names
+		if len(v.TypeName.Names) == 1 {
+			if str := v.TypeName.Names[0].GetString_(); str != nil {
+				return normalizeRelOptionValue(str.Sval)
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeRelOptionValue lowercases a value and spells booleans as true or false, as pg_class.reloptions does
+func normalizeRelOptionValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "on", "yes", "t", "y":
+		return "true"
+	case "off", "no", "f", "n":
+		return "false"
+	}
+	return value
+}
@@ -0,0 +1,221 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/pganalyze/pg_query_go/v6"
+)
+
+// analyzeCreateFunction analyzes CREATE FUNCTION/PROCEDURE, checking the body for statements
+// that cannot run inside a routine
+func (a *analyzer) analyzeCreateFunction(stmt *pg_query.CreateFunctionStmt) *operationInfo {
+	opInfo := &operationInfo{
+		operation: "CREATE FUNCTION",
+		tableLock: AccessExclusive,
+	}
+	if stmt.IsProcedure {
+		opInfo.operation = "CREATE PROCEDURE"
+	}
+
+	for _, op := range a.routineNonTransactionalOperations(stmt) {
+		opInfo.errorReason = ErrorReasonCannotRunInRoutine
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"the body runs %s, which cannot be executed inside a function or procedure; every call fails when it is reached",
+			op))
+	}
+	return opInfo
+}
+
+// analyzeCall analyzes CALL, flagging procedures created earlier in the input whose body cannot run
+func (a *analyzer) analyzeCall(stmt *pg_query.CallStmt) *operationInfo {
+	opInfo := &operationInfo{
+		operation: "CALL",
+		tableLock: AccessShare,
+	}
+	if stmt.Funccall == nil {
+		return opInfo
+	}
+
+	name := qualifiedFunctionName(stmt.Funccall.Funcname)
+	for _, op := range a.routines[routineKey(stmt.Funccall.Funcname)] {
+		opInfo.errorReason = ErrorReasonCannotRunInRoutine
+		opInfo.notes = append(opInfo.notes, fmt.Sprintf(
+			"%s runs %s, which cannot be executed inside a procedure; run it as a top-level statement instead",
+			name, op))
+	}
+	return opInfo
+}
+
+// recordRoutine remembers the statements that cannot run in a routine created in the input,
+// so later CALLs of it are flagged
+func (a *analyzer) recordRoutine(stmt parser.ParsedStatement) {
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+	create := stmt.AST.Stmts[0].Stmt.GetCreateFunctionStmt()
+	if create == nil {
+		return
+	}
+
+	name := routineKey(create.Funcname)
+	if ops := a.routineNonTransactionalOperations(create); len(ops) > 0 {
+		a.routines[name] = ops
+	} else {
+		// CREATE OR REPLACE may fix an earlier body
+		delete(a.routines, name)
+	}
+}
+
+// routineNonTransactionalOperations lists the body statements that must run outside a transaction
+// block, which PostgreSQL also rejects inside functions and procedures
+func (a *analyzer) routineNonTransactionalOperations(stmt *pg_query.CreateFunctionStmt) []string {
+	var ops []string
+	for _, node := range routineBodyStatements(stmt) {
+		opInfo := a.analyzeNode(node, InTransaction)
+		if opInfo != nil && a.registry.recommendedMode(opInfo.operation) == RecommendedModeNoTransaction && !slices.Contains(ops, opInfo.operation) {
+			ops = append(ops, opInfo.operation)
+		}
+	}
+	return ops
+}
+
+// routineBodyStatements returns the statements of a BEGIN ATOMIC, LANGUAGE sql or LANGUAGE plpgsql body;
+// bodies that do not parse or use other languages yield none
+func routineBodyStatements(stmt *pg_query.CreateFunctionStmt) []*pg_query.Node {
+	if stmt.SqlBody != nil {
+		return flattenStatementList(stmt.SqlBody)
+	}
+
+	language, body := "sql", ""
+	for _, option := range stmt.Options {
+		def := option.GetDefElem()
+		if def == nil {
+			continue
+		}
+		switch def.Defname {
+		case "language":
+			if s := def.Arg.GetString_(); s != nil {
+				language = strings.ToLower(s.Sval)
+			}
+		case "as":
+			// C functions give the object file and symbol; SQL bodies are a single string
+			if list := def.Arg.GetList(); list != nil && len(list.Items) == 1 {
+				if s := list.Items[0].GetString_(); s != nil {
+					body = s.Sval
+				}
+			}
+		}
+	}
+
+	var queries []string
+	switch language {
+	case "sql":
+		queries = []string{body}
+	case "plpgsql":
+		queries = plpgsqlQueries(stmt)
+	}
+
+	var statements []*pg_query.Node
+	for _, query := range queries {
+		result, err := pg_query.Parse(query)
+		if err != nil {
+			continue
+		}
+		for _, raw := range result.Stmts {
+			statements = append(statements, raw.Stmt)
+		}
+	}
+	return statements
+}
+
+// flattenStatementList unwraps the nested lists of a BEGIN ATOMIC body
+func flattenStatementList(node *pg_query.Node) []*pg_query.Node {
+	list := node.GetList()
+	if list == nil {
+		return []*pg_query.Node{node}
+	}
+	var statements []*pg_query.Node
+	for _, item := range list.Items {
+		statements = append(statements, flattenStatementList(item)...)
+	}
+	return statements
+}
+
+// plpgsqlQueries returns the SQL statements a PL/pgSQL body executes directly,
+// leaving out expressions and dynamic EXECUTE strings
+func plpgsqlQueries(stmt *pg_query.CreateFunctionStmt) []string {
+	sql, err := pg_query.Deparse(&pg_query.ParseResult{
+		Stmts: []*pg_query.RawStmt{{Stmt: &pg_query.Node{Node: &pg_query.Node_CreateFunctionStmt{CreateFunctionStmt: stmt}}}},
+	})
+	if err != nil {
+		return nil
+	}
+	tree, err := pg_query.ParsePlPgSqlToJSON(sql)
+	if err != nil {
+		return nil
+	}
+
+	var functions []any
+	if err := json.Unmarshal([]byte(tree), &functions); err != nil {
+		return nil
+	}
+	var queries []string
+	collectPlpgsqlQueries(functions, &queries)
+	return queries
+}
+
+// collectPlpgsqlQueries walks the PL/pgSQL parse tree for the queries of PLpgSQL_stmt_execsql nodes
+func collectPlpgsqlQueries(node any, queries *[]string) {
+	switch n := node.(type) {
+	case []any:
+		for _, item := range n {
+			collectPlpgsqlQueries(item, queries)
+		}
+	case map[string]any:
+		if execsql, ok := n["PLpgSQL_stmt_execsql"].(map[string]any); ok {
+			if sqlstmt, ok := execsql["sqlstmt"].(map[string]any); ok {
+				if expr, ok := sqlstmt["PLpgSQL_expr"].(map[string]any); ok {
+					if query, ok := expr["query"].(string); ok {
+						*queries = append(*queries, query)
+					}
+				}
+			}
+			return
+		}
+		// Sort keys so the queries come out in the same order on every run
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectPlpgsqlQueries(n[key], queries)
+		}
+	}
+}
+
+// routineKey names a routine for lookups, so p and public.p are the same routine
+func routineKey(names []*pg_query.Node) string {
+	if len(names) == 2 {
+		if schema := names[0].GetString_(); schema != nil && schema.Sval == "public" {
+			return qualifiedFunctionName(names[1:])
+		}
+	}
+	return qualifiedFunctionName(names)
+}
+
+// qualifiedFunctionName joins a function name list, e.g. "app.refresh"
+func qualifiedFunctionName(names []*pg_query.Node) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if s := name.GetString_(); s != nil {
+			parts = append(parts, s.Sval)
+		}
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+// operationScopes lists operations whose locks reach beyond the objects named in the SQL,
+// or that name a single non-table object
+var operationScopes = map[string]Scope{
+	// Cluster-wide operations
+	"REINDEX SYSTEM":    ScopeSystem,
+	"ALTER SYSTEM":      ScopeSystem,
+	"CHECKPOINT":        ScopeSystem,
+	"CREATE TABLESPACE": ScopeSystem,
+	"DROP TABLESPACE":   ScopeSystem,
+	"ALTER TABLESPACE":  ScopeSystem,
+	"CREATE ROLE":       ScopeSystem,
+	"CREATE USER":       ScopeSystem,
+	"DROP ROLE":         ScopeSystem,
+	"ALTER ROLE":        ScopeSystem,
+
+	// Database-wide operations
+	"REINDEX DATABASE":              ScopeDatabase,
+	"REINDEX DATABASE CONCURRENTLY": ScopeDatabase,
+	"CREATE DATABASE":               ScopeDatabase,
+	"DROP DATABASE":                 ScopeDatabase,
+	"ALTER DATABASE":                ScopeDatabase,
+	"GRANT ON DATABASE":             ScopeDatabase,
+	"REVOKE ON DATABASE":            ScopeDatabase,
+	"DROP OWNED":                    ScopeDatabase,
+	"REASSIGN OWNED":                ScopeDatabase,
+	"CREATE EVENT TRIGGER":          ScopeDatabase,
+	"DROP EVENT TRIGGER":            ScopeDatabase,
+
+	// Schema-wide operations
+	"REINDEX SCHEMA":              ScopeSchema,
+	"REINDEX SCHEMA CONCURRENTLY": ScopeSchema,
+	"CREATE SCHEMA":               ScopeSchema,
+	"DROP SCHEMA":                 ScopeSchema,
+	"DROP SCHEMA CASCADE":         ScopeSchema,
+	"ALTER SCHEMA RENAME TO":      ScopeSchema,
+	"GRANT ON SCHEMA":             ScopeSchema,
+	"REVOKE ON SCHEMA":            ScopeSchema,
+
+	// Single index operations
+	"REINDEX":     ScopeObject,
+	"ALTER INDEX": ScopeObject,
+}
+
+// classifyScope determines the scope of an operation; an explicit scope from the
+// node analyzer wins, otherwise operations touching resolved tables are table-scoped
+func classifyScope(opInfo *operationInfo, hasTables bool) Scope {
+	if opInfo.scope != "" {
+		return opInfo.scope
+	}
+	if scope, ok := operationScopes[opInfo.operation]; ok {
+		return scope
+	}
+	if hasTables {
+		return ScopeTable
+	}
+	return ScopeObject
+}
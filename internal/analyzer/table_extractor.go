@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"github.com/nnaka2992/pg-lock-check/internal/identifier"
 	"github.com/pganalyze/pg_query_go/v6"
 )
 
@@ -95,6 +96,19 @@ func extractTablesWithContext(node *pg_query.Node) map[string]LockType {
 				extractReadTables(n.MergeStmt.SourceRelation, result)
 			}
 		}
+	case *pg_query.Node_ViewStmt:
+		if n.ViewStmt != nil && n.ViewStmt.Replace {
+			// CREATE OR REPLACE VIEW locks the existing view; source tables are only read
+			if n.ViewStmt.View != nil {
+				tableName := getQualifiedTableName(n.ViewStmt.View)
+				if tableName != "" {
+					result[tableName] = AccessExclusive
+				}
+			}
+			if n.ViewStmt.Query != nil {
+				extractReadTables(n.ViewStmt.Query, result)
+			}
+		}
 	case *pg_query.Node_CopyStmt:
 		if n.CopyStmt != nil {
 			if n.CopyStmt.Relation != nil {
@@ -149,13 +163,8 @@ func extractTablesWithContext(node *pg_query.Node) map[string]LockType {
 			lockType := AccessExclusive
 
 			// Check for CONCURRENTLY
-			if n.ReindexStmt.Params != nil {
-				for _, defElem := range n.ReindexStmt.Params {
-					if de := defElem.GetDefElem(); de != nil && de.Defname == "concurrently" {
-						lockType = ShareUpdateExclusive
-						break
-					}
-				}
+			if reindexConcurrently(n.ReindexStmt.Params) {
+				lockType = ShareUpdateExclusive
 			}
 
 			for _, table := range tables {
@@ -470,27 +479,27 @@ func (e *tableExtractor) extractFromDropStmt(stmt *pg_query.DropStmt) {
 					pg_query.ObjectType_OBJECT_POLICY:
 					// For TRIGGER/RULE/POLICY: first item is table name, second is object name
 					if len(parts) >= 1 {
-						objName = quoteIdentifier(parts[0]) // Table name is first
+						objName = identifier.Quote(parts[0]) // Table name is first
 					}
 				case pg_query.ObjectType_OBJECT_INDEX:
 					// For INDEX: treat the index name as the object being locked
 					if len(parts) == 1 {
-						objName = quoteIdentifier(parts[0])
+						objName = identifier.Quote(parts[0])
 					} else if len(parts) >= 2 {
 						// Schema.index
-						objName = quoteQualifiedIdentifier(parts[0], parts[1])
+						objName = identifier.QuoteQualified(parts[0], parts[1])
 					}
 				default:
 					// For other object types (TABLE, VIEW, etc.)
 					if len(parts) == 1 {
 						// Simple object name
-						objName = quoteIdentifier(parts[0])
+						objName = identifier.Quote(parts[0])
 					} else if len(parts) == 2 {
 						// Schema.object
-						objName = quoteQualifiedIdentifier(parts[0], parts[1])
+						objName = identifier.QuoteQualified(parts[0], parts[1])
 					} else if len(parts) > 2 {
 						// Could be database.schema.object, but usually just schema.object
-						objName = quoteQualifiedIdentifier(parts[len(parts)-2], parts[len(parts)-1])
+						objName = identifier.QuoteQualified(parts[len(parts)-2], parts[len(parts)-1])
 					}
 				}
 
@@ -834,5 +843,5 @@ func getQualifiedTableName(rv *pg_query.RangeVar) string {
 		return ""
 	}
 
-	return quoteQualifiedIdentifier(rv.Schemaname, rv.Relname)
+	return identifier.QuoteQualified(rv.Schemaname, rv.Relname)
 }
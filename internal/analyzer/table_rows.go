@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/identifier"
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+)
+
+// rowsDirective starts a comment giving expected table sizes, e.g. -- pg-lock-check:rows users=1000000
+const rowsDirective = "pg-lock-check:rows"
+
+// smallTableRows is the row count below which a table is small enough that rewriting or scanning
+// it under a strong lock takes only moments
+const smallTableRows = 10000
+
+// sizeProportionalOperations are CRITICAL only because they hold their lock for as long as it
+// takes to rewrite, scan or update every row of the table
+var sizeProportionalOperations = map[string]bool{
+	"UPDATE without WHERE": true,
+	"MERGE without WHERE":  true,
+	"CREATE INDEX":         true,
+	"CREATE UNIQUE INDEX":  true,
+	"REINDEX":              true,
+	"REINDEX TABLE":        true,
+	"CLUSTER":              true,
+	"VACUUM FULL":          true,
+	"ALTER TABLE ADD COLUMN with volatile DEFAULT": true,
+	"ALTER TABLE ADD COLUMN with PRIMARY KEY":      true,
+	"ALTER TABLE ADD COLUMN with UNIQUE":           true,
+	"ALTER TABLE ALTER COLUMN TYPE":                true,
+	"ALTER TABLE SET TABLESPACE":                   true,
+	"ALTER TABLE SET LOGGED":                       true,
+	"ALTER TABLE SET UNLOGGED":                     true,
+	"ALTER TABLE ADD PRIMARY KEY":                  true,
+	"ALTER TABLE ADD CONSTRAINT CHECK":             true,
+	"ALTER TABLE SET NOT NULL":                     true,
+}
+
+// tableRows records an expected row count and the line of the directive giving it
+type tableRows struct {
+	rows       int64
+	lineNumber int
+}
+
+// recordTableRows reads pg-lock-check:rows directives from the comments of a statement, which
+// apply to it and every later statement, and returns notes for entries that cannot be read
+func (a *analyzer) recordTableRows(stmt parser.ParsedStatement) []string {
+	tokens, err := parser.Scan(stmt.SQL)
+	if err != nil {
+		return nil
+	}
+
+	var notes []string
+	for _, token := range tokens {
+		if token.Kind != parser.TokenComment {
+			continue
+		}
+		text := stmt.SQL[token.Start:token.End]
+		text = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(text, "--"), "/*"), "*/")
+		entries, found := strings.CutPrefix(strings.TrimSpace(text), rowsDirective)
+		if !found {
+			continue
+		}
+
+		line := stmt.LineNumber + strings.Count(stmt.SQL[:token.Start], "\n")
+		for _, entry := range strings.FieldsFunc(entries, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			table, count, _ := strings.Cut(entry, "=")
+			rows, err := strconv.ParseInt(strings.ReplaceAll(count, "_", ""), 10, 64)
+			if table == "" || err != nil || rows < 0 {
+				notes = append(notes, fmt.Sprintf("ignored %s entry %q at line %d: expected table=rows, e.g. users=1000000", rowsDirective, entry, line))
+				continue
+			}
+			a.tableRows[tableKey(table)] = tableRows{rows: rows, lineNumber: line}
+		}
+	}
+	return notes
+}
+
+// tableKey normalizes a directive key or a locked table name, so users, public.users and
+// "users" are the same table and Users in a directive names the quoted "Users"
+func tableKey(name string) string {
+	parts := identifier.SplitQualified(name)
+	if len(parts) == 2 && parts[0] != "public" {
+		return identifier.QuoteQualified(parts[0], parts[1])
+	}
+	return identifier.Quote(parts[len(parts)-1])
+}
+
+// smallTableNote notes a size-proportional CRITICAL operation whose locked tables all have fewer
+// than smallTableRows rows by a pg-lock-check:rows directive, which is then reported as WARNING
+func (a *analyzer) smallTableNote(operation string, tableLocks map[string]LockType, primary LockType) string {
+	if !sizeProportionalOperations[operation] || len(tableLocks) == 0 {
+		return ""
+	}
+
+	var sizes []string
+	for table, lock := range tableLocks {
+		if lock != primary {
+			continue
+		}
+		hint, known := a.tableRows[tableKey(table)]
+		if !known || hint.rows >= smallTableRows {
+			return ""
+		}
+		sizes = append(sizes, fmt.Sprintf("%s: %d rows (line %d)", table, hint.rows, hint.lineNumber))
+	}
+	if len(sizes) == 0 {
+		return ""
+	}
+	sort.Strings(sizes)
+
+	return fmt.Sprintf(
+		"%s gives %s, fewer than %d, so the %s lock is held only briefly; reported as WARNING instead of CRITICAL",
+		rowsDirective, strings.Join(sizes, ", "), smallTableRows, primary)
+}
@@ -0,0 +1,434 @@
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nnaka2992/pg-lock-check/internal/parser"
+	"github.com/pganalyze/pg_query_go/v6"
+)
+
+// largeStatementTimeoutMillis is the statement_timeout above which strong-lock statements are noted
+const largeStatementTimeoutMillis = 5 * 60 * 1000
+
+// timeoutSetting records a statement_timeout value and where it was set
+type timeoutSetting struct {
+	millis     int64
+	lineNumber int
+}
+
+// isolationSetting records an isolation level stronger than READ COMMITTED and where it was set
+type isolationSetting struct {
+	level      string
+	lineNumber int
+}
+
+// heldLock records a statement holding an AccessExclusive lock until its transaction ends
+type heldLock struct {
+	result          *Result
+	laterStatements int
+}
+
+// mixedStatement records the first schema change or full-table DML in a transaction
+type mixedStatement struct {
+	operation  string
+	lineNumber int
+}
+
+// fullTableDML are the data changes that touch every row of a table, such as a backfill
+var fullTableDML = map[string]bool{
+	"UPDATE without WHERE": true,
+	"DELETE without WHERE": true,
+	"MERGE without WHERE":  true,
+	"INSERT SELECT":        true,
+}
+
+// concurrentIndexBuild records a CREATE INDEX CONCURRENTLY and the table it indexes
+type concurrentIndexBuild struct {
+	table      string
+	lineNumber int
+}
+
+// transactionState tracks session and transaction settings across statements in one Analyze call
+type transactionState struct {
+	sessionTimeout *timeoutSetting
+	localTimeout   *timeoutSetting
+
+	// Long-transaction check: locks held in the current transaction and the note threshold
+	heldLocks                []*heldLock
+	longTransactionThreshold int
+
+	// Tables already altered under AccessExclusive in the current transaction, with the first line
+	alteredTables map[string]int
+
+	// DDL/DML mix check: the first AccessExclusive DDL and full-table DML in the current transaction
+	mixedDDL *mixedStatement
+	mixedDML *mixedStatement
+
+	// The CREATE INDEX CONCURRENTLY of the previous statement, if it was one
+	concurrentIndex *concurrentIndexBuild
+
+	// Isolation level of the current transaction and the session default for later ones
+	transactionIsolation *isolationSetting
+	sessionIsolation     *isolationSetting
+}
+
+// applyTransactionChecks updates the tracked state with a statement and adds notes based on earlier statements
+func (a *analyzer) applyTransactionChecks(state *transactionState, stmt parser.ParsedStatement, result *Result, mode TransactionMode) {
+	if a.options.CheckStatementTimeout {
+		state.checkStatementTimeout(result)
+	}
+	if a.options.LongTransactionThreshold > 0 {
+		state.longTransactionThreshold = a.options.LongTransactionThreshold
+		state.trackHeldLocks(stmt, result, mode)
+	}
+	if a.options.CheckDDLDMLMix {
+		state.checkDDLDMLMix(stmt, result, mode)
+	}
+	state.checkRepeatedAlters(stmt, result, mode)
+	state.checkConcurrentIndexDML(stmt, result)
+	state.checkIsolation(result)
+
+	state.update(stmt, result)
+	state.trackIsolation(stmt, result, mode)
+}
+
+// checkRepeatedAlters notes ALTER TABLE statements taking AccessExclusive on a table
+// that an earlier ALTER TABLE in the same transaction already locked
+func (s *transactionState) checkRepeatedAlters(stmt parser.ParsedStatement, result *Result, mode TransactionMode) {
+	if endsTransaction(result.BaseOperation()) {
+		s.alteredTables = nil
+		return
+	}
+
+	if mode != InTransaction || result.Severity == SeverityError || !strings.HasPrefix(result.BaseOperation(), "ALTER TABLE") {
+		return
+	}
+
+	tables := make([]string, 0, len(result.TableLockTypes()))
+	for table, lock := range result.TableLockTypes() {
+		if lock == AccessExclusive {
+			tables = append(tables, table)
+		}
+	}
+	slices.Sort(tables)
+
+	for _, table := range tables {
+		if line, seen := s.alteredTables[table]; seen {
+			result.notes = append(result.notes, fmt.Sprintf(
+				"%s is already locked AccessExclusive by the ALTER TABLE at line %d in this transaction; combine them into a single ALTER TABLE %s statement with comma-separated actions to reduce lock churn",
+				table, line, table))
+			continue
+		}
+		if s.alteredTables == nil {
+			s.alteredTables = make(map[string]int)
+		}
+		s.alteredTables[table] = stmt.LineNumber
+	}
+}
+
+// checkDDLDMLMix notes an AccessExclusive schema change and a full-table DML in the same
+// transaction, on whichever of them runs second
+func (s *transactionState) checkDDLDMLMix(stmt parser.ParsedStatement, result *Result, mode TransactionMode) {
+	if endsTransaction(result.BaseOperation()) {
+		s.mixedDDL = nil
+		s.mixedDML = nil
+		return
+	}
+
+	if mode != InTransaction {
+		return
+	}
+
+	current := &mixedStatement{operation: result.BaseOperation(), lineNumber: stmt.LineNumber}
+	switch {
+	case fullTableDML[current.operation] && (result.Severity == SeverityCritical || result.Severity == SeverityWarning):
+		if s.mixedDDL != nil {
+			result.notes = append(result.notes, fmt.Sprintf(
+				"runs in the same transaction as the %s at line %d, whose AccessExclusive lock is held for the whole of this full-table %s; commit the schema change first and run the data change in its own transaction",
+				s.mixedDDL.operation, s.mixedDDL.lineNumber, current.operation))
+		}
+		if s.mixedDML == nil {
+			s.mixedDML = current
+		}
+	case result.LockType() == AccessExclusive && result.Severity != SeverityError && !strings.HasPrefix(current.operation, "LOCK"):
+		if s.mixedDML != nil {
+			result.notes = append(result.notes, fmt.Sprintf(
+				"takes AccessExclusive in the same transaction as the %s at line %d, so the locks of both are held until the transaction ends; run the schema change and the data change in separate transactions",
+				s.mixedDML.operation, s.mixedDML.lineNumber))
+		}
+		if s.mixedDDL == nil {
+			s.mixedDDL = current
+		}
+	}
+}
+
+// checkConcurrentIndexDML notes a full-table DML that immediately follows a CREATE INDEX
+// CONCURRENTLY on the table it changes
+func (s *transactionState) checkConcurrentIndexDML(stmt parser.ParsedStatement, result *Result) {
+	previous := s.concurrentIndex
+	s.concurrentIndex = nil
+
+	switch result.BaseOperation() {
+	case "CREATE INDEX CONCURRENTLY", "CREATE UNIQUE INDEX CONCURRENTLY":
+		if result.Severity == SeverityError {
+			return
+		}
+		// The build locks only the table it indexes
+		for table := range result.TableLockTypes() {
+			s.concurrentIndex = &concurrentIndexBuild{table: table, lineNumber: stmt.LineNumber}
+		}
+		return
+	}
+
+	if previous == nil || !fullTableDML[result.BaseOperation()] || (result.Severity != SeverityCritical && result.Severity != SeverityWarning) {
+		return
+	}
+	// users and public.users name the same table
+	changesIndexedTable := false
+	for table, lock := range result.TableLockTypes() {
+		if lock == RowExclusive && tableKey(table) == tableKey(previous.table) {
+			changesIndexedTable = true
+		}
+	}
+	if !changesIndexedTable {
+		return
+	}
+	result.notes = append(result.notes, fmt.Sprintf(
+		"follows the CREATE INDEX CONCURRENTLY on %s at line %d, so every row this full-table %s changes is also written to the new index, and it slows down a build still running from a parallel or retried migration; run the data change before building the index",
+		previous.table, previous.lineNumber, result.BaseOperation()))
+}
+
+// trackHeldLocks counts statements run while earlier AccessExclusive locks are held
+func (s *transactionState) trackHeldLocks(stmt parser.ParsedStatement, result *Result, mode TransactionMode) {
+	switch {
+	case endsTransaction(result.BaseOperation()):
+		s.endTransaction(fmt.Sprintf("%s at line %d", result.BaseOperation(), stmt.LineNumber))
+		return
+	case startsTransaction(result.BaseOperation()):
+		// Starting a transaction neither releases nor extends held locks
+		return
+	}
+
+	for _, held := range s.heldLocks {
+		held.laterStatements++
+	}
+
+	if mode == InTransaction && result.LockType() == AccessExclusive && result.Severity != SeverityError {
+		s.heldLocks = append(s.heldLocks, &heldLock{result: result})
+	}
+}
+
+// endTransaction notes held locks that were followed by too many statements before end
+func (s *transactionState) endTransaction(end string) {
+	for _, held := range s.heldLocks {
+		if held.laterStatements < s.longTransactionThreshold {
+			continue
+		}
+		held.result.notes = append(held.result.notes, fmt.Sprintf(
+			"AccessExclusive lock is held through %d later statements until %s; run this statement in its own short transaction",
+			held.laterStatements, end))
+	}
+	s.heldLocks = nil
+}
+
+// update records settings changed by a statement
+func (s *transactionState) update(stmt parser.ParsedStatement, result *Result) {
+	if endsTransaction(result.BaseOperation()) {
+		// SET LOCAL only lasts until the end of the transaction
+		s.localTimeout = nil
+		return
+	}
+
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+	setStmt := stmt.AST.Stmts[0].Stmt.GetVariableSetStmt()
+	if setStmt == nil {
+		return
+	}
+
+	switch setStmt.Kind {
+	case pg_query.VariableSetKind_VAR_RESET_ALL:
+		s.sessionTimeout = nil
+		s.localTimeout = nil
+		return
+	case pg_query.VariableSetKind_VAR_SET_VALUE, pg_query.VariableSetKind_VAR_SET_DEFAULT, pg_query.VariableSetKind_VAR_RESET:
+	default:
+		return
+	}
+
+	if !strings.EqualFold(setStmt.Name, "statement_timeout") {
+		return
+	}
+
+	var setting *timeoutSetting
+	if setStmt.Kind == pg_query.VariableSetKind_VAR_SET_VALUE {
+		millis, ok := parseTimeoutMillis(setStmt.Args)
+		if !ok {
+			return
+		}
+		setting = &timeoutSetting{millis: millis, lineNumber: stmt.LineNumber}
+	}
+
+	if setStmt.IsLocal {
+		s.localTimeout = setting
+	} else {
+		s.sessionTimeout = setting
+	}
+}
+
+// checkStatementTimeout notes strong-lock statements running without a bounded statement_timeout
+func (s *transactionState) checkStatementTimeout(result *Result) {
+	if lockLevel(result.LockType()) < lockLevel(Share) || result.Severity == SeverityError {
+		return
+	}
+
+	setting := s.localTimeout
+	if setting == nil {
+		setting = s.sessionTimeout
+	}
+	if setting == nil {
+		return
+	}
+
+	switch {
+	case setting.millis == 0:
+		result.notes = append(result.notes, fmt.Sprintf(
+			"runs with statement_timeout disabled (set at line %d); a blocked or long-running %s lock can hang indefinitely",
+			setting.lineNumber, result.LockType()))
+	case setting.millis > largeStatementTimeoutMillis:
+		result.notes = append(result.notes, fmt.Sprintf(
+			"runs with a very large statement_timeout of %dms (set at line %d); a blocked or long-running %s lock can be held for a long time",
+			setting.millis, setting.lineNumber, result.LockType()))
+	}
+}
+
+// trackIsolation records isolation levels set by BEGIN, SET TRANSACTION and SET SESSION CHARACTERISTICS
+func (s *transactionState) trackIsolation(stmt parser.ParsedStatement, result *Result, mode TransactionMode) {
+	if endsTransaction(result.BaseOperation()) {
+		s.transactionIsolation = nil
+		return
+	}
+
+	if stmt.AST == nil || len(stmt.AST.Stmts) == 0 {
+		return
+	}
+	node := stmt.AST.Stmts[0].Stmt
+
+	if txStmt := node.GetTransactionStmt(); txStmt != nil {
+		if txStmt.Kind == pg_query.TransactionStmtKind_TRANS_STMT_BEGIN || txStmt.Kind == pg_query.TransactionStmtKind_TRANS_STMT_START {
+			if level, ok := isolationLevel(txStmt.Options); ok {
+				s.transactionIsolation = newIsolationSetting(level, stmt.LineNumber)
+			}
+		}
+		return
+	}
+
+	setStmt := node.GetVariableSetStmt()
+	if setStmt == nil {
+		return
+	}
+
+	switch {
+	case setStmt.Kind == pg_query.VariableSetKind_VAR_SET_MULTI && setStmt.Name == "TRANSACTION":
+		// Outside a transaction SET TRANSACTION only warns and has no effect
+		if level, ok := isolationLevel(setStmt.Args); ok && mode == InTransaction {
+			s.transactionIsolation = newIsolationSetting(level, stmt.LineNumber)
+		}
+	case setStmt.Kind == pg_query.VariableSetKind_VAR_SET_MULTI && setStmt.Name == "SESSION CHARACTERISTICS":
+		if level, ok := isolationLevel(setStmt.Args); ok {
+			s.sessionIsolation = newIsolationSetting(level, stmt.LineNumber)
+		}
+	}
+}
+
+// checkIsolation notes strong-lock statements running under SERIALIZABLE or REPEATABLE READ
+func (s *transactionState) checkIsolation(result *Result) {
+	if lockLevel(result.LockType()) < lockLevel(Share) || result.Severity == SeverityError {
+		return
+	}
+
+	setting := s.transactionIsolation
+	if setting == nil {
+		setting = s.sessionIsolation
+	}
+	if setting == nil {
+		return
+	}
+
+	result.notes = append(result.notes, fmt.Sprintf(
+		"runs under %s isolation (set at line %d); concurrent transactions touching the table can fail with serialization errors while this %s lock is held, and DDL gains nothing from it; use the default READ COMMITTED for migrations",
+		setting.level, setting.lineNumber, result.LockType()))
+}
+
+// newIsolationSetting returns the setting for level, or nil for READ COMMITTED and READ UNCOMMITTED
+func newIsolationSetting(level string, lineNumber int) *isolationSetting {
+	switch level {
+	case "serializable", "repeatable read":
+		return &isolationSetting{level: strings.ToUpper(level), lineNumber: lineNumber}
+	}
+	return nil
+}
+
+// isolationLevel returns the transaction_isolation value among transaction options
+func isolationLevel(options []*pg_query.Node) (string, bool) {
+	for _, option := range options {
+		defElem := option.GetDefElem()
+		if defElem == nil || defElem.Defname != "transaction_isolation" {
+			continue
+		}
+		if sval := defElem.Arg.GetAConst().GetSval(); sval != nil {
+			return strings.ToLower(sval.Sval), true
+		}
+	}
+	return "", false
+}
+
+// parseTimeoutMillis converts a SET value such as 0, 30000 or '5min' to milliseconds
+func parseTimeoutMillis(args []*pg_query.Node) (int64, bool) {
+	if len(args) != 1 {
+		return 0, false
+	}
+	aConst := args[0].GetAConst()
+	if aConst == nil {
+		return 0, false
+	}
+
+	if ival := aConst.GetIval(); ival != nil {
+		return int64(ival.Ival), true
+	}
+	sval := aConst.GetSval()
+	if sval == nil {
+		return 0, false
+	}
+
+	value := strings.ToLower(strings.TrimSpace(sval.Sval))
+	units := []struct {
+		suffix string
+		millis int64
+	}{
+		{"ms", 1},
+		{"min", 60 * 1000},
+		{"s", 1000},
+		{"h", 60 * 60 * 1000},
+		{"d", 24 * 60 * 60 * 1000},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.millis
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(number * float64(multiplier)), true
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import "sort"
+
+// Transaction summarizes the locks held across an explicit BEGIN ... COMMIT or ROLLBACK block
+type Transaction struct {
+	// Start and End are the indexes of the results that open and end the block; a block still
+	// open at the end of the results ends at the last one
+	Start int
+	End   int
+	// EndOperation is COMMIT or ROLLBACK, or empty for a block still open at the end of the results
+	EndOperation string
+	// LockType is the strongest lock held on any table in the block, empty when no table is locked
+	LockType LockType
+	// Tables lists the locked tables from the strongest lock down and by name within the same lock
+	Tables []string
+	// TableLockTypes maps each table in Tables to the strongest lock held on it
+	TableLockTypes map[string]LockType
+}
+
+// Transactions follows BEGIN and COMMIT/ROLLBACK through results of Analyze, as the analyzer does,
+// and summarizes each explicit transaction block. Locks are held until the block ends, so each
+// table has the strongest lock any statement in the block took; ERROR statements take no locks.
+func Transactions(results []*Result) []Transaction {
+	var transactions []Transaction
+	var current *Transaction
+	var held map[string]LockType
+
+	end := func(index int, operation string) {
+		current.End = index
+		current.EndOperation = operation
+		current.LockType, current.Tables = heldTableLocks(held)
+		current.TableLockTypes = held
+		transactions = append(transactions, *current)
+		current = nil
+	}
+
+	for i, result := range results {
+		switch {
+		case startsTransaction(result.BaseOperation()):
+			// A nested BEGIN only warns and keeps the current transaction
+			if current == nil {
+				current = &Transaction{Start: i}
+				held = make(map[string]LockType)
+			}
+			continue
+		case endsTransaction(result.BaseOperation()):
+			if current != nil {
+				end(i, result.BaseOperation())
+			}
+			continue
+		}
+
+		if current == nil || result.Severity == SeverityError {
+			continue
+		}
+		for table, lock := range result.TableLockTypes() {
+			if lockLevel(lock) > lockLevel(held[table]) {
+				held[table] = lock
+			}
+		}
+	}
+
+	if current != nil {
+		end(len(results)-1, "")
+	}
+	return transactions
+}
+
+// heldTableLocks returns the strongest of the held locks and the tables ordered by their lock
+func heldTableLocks(held map[string]LockType) (LockType, []string) {
+	tables := make([]string, 0, len(held))
+	for table := range held {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if lockLevel(held[tables[i]]) != lockLevel(held[tables[j]]) {
+			return lockLevel(held[tables[i]]) > lockLevel(held[tables[j]])
+		}
+		return tables[i] < tables[j]
+	})
+
+	var strongest LockType
+	if len(tables) > 0 {
+		strongest = held[tables[0]]
+	}
+	return strongest, tables
+}
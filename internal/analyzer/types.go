@@ -1,5 +1,11 @@
 package analyzer
 
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
 // Severity represents the severity level of a database operation
 type Severity int
 
@@ -25,6 +31,23 @@ func (s Severity) String() string {
 	}
 }
 
+// Description explains in one line what the severity means. ERROR is about the transaction
+// context rather than risk: CRITICAL statements run but are dangerous, ERROR statements fail.
+func (s Severity) Description() string {
+	switch s {
+	case SeverityInfo:
+		return "safe to run; takes no lock that blocks normal reads or writes for long"
+	case SeverityWarning:
+		return "runs, but its lock blocks some operations or DDL while it runs; review before deploying"
+	case SeverityCritical:
+		return "runs, but blocks reads or writes on the table for its whole duration or destroys data; use a safer migration"
+	case SeverityError:
+		return "fails as written: PostgreSQL rejects it in the given transaction mode or context"
+	default:
+		return ""
+	}
+}
+
 // TransactionMode indicates whether the operation is executed within a transaction
 type TransactionMode int
 
@@ -58,20 +81,113 @@ const (
 	AccessExclusive      LockType = "AccessExclusive"
 )
 
+// ErrorReason explains why an operation has ERROR severity
+type ErrorReason string
+
+const (
+	// ErrorReasonNone is used for results that are not ERROR
+	ErrorReasonNone ErrorReason = ""
+	// ErrorReasonCannotRunInTransaction is used for operations that fail inside a transaction block
+	ErrorReasonCannotRunInTransaction ErrorReason = "cannot-run-in-transaction"
+	// ErrorReasonCannotRunInRoutine is used for routines whose body, or CALLs of procedures whose body,
+	// runs statements that fail inside a function or procedure
+	ErrorReasonCannotRunInRoutine ErrorReason = "cannot-run-in-routine"
+	// ErrorReasonPartitionedTable is used for CREATE INDEX CONCURRENTLY on a partitioned table,
+	// which PostgreSQL rejects outside a transaction block as well
+	ErrorReasonPartitionedTable ErrorReason = "unsupported-on-partitioned-table"
+	// ErrorReasonSystemCatalog is used for REINDEX CONCURRENTLY on system catalogs,
+	// which PostgreSQL rejects outside a transaction block as well
+	ErrorReasonSystemCatalog ErrorReason = "unsupported-on-system-catalog"
+)
+
+// Scope describes how far an operation's locks reach beyond the objects named in the SQL
+type Scope string
+
+const (
+	// ScopeObject is used for operations on a single named non-table object, such as an index
+	ScopeObject Scope = "object"
+	// ScopeTable is used for operations on the tables named in the statement
+	ScopeTable Scope = "table"
+	// ScopeSchema is used for operations on every object in a schema
+	ScopeSchema Scope = "schema"
+	// ScopeDatabase is used for operations on every object in the current database
+	ScopeDatabase Scope = "database"
+	// ScopeSystem is used for cluster-wide operations, such as on system catalogs or roles
+	ScopeSystem Scope = "system"
+)
+
+// RecommendedMode says how a statement should be wrapped to run safely
+type RecommendedMode string
+
+const (
+	// RecommendedModeNoTransaction is used for operations that must run outside a transaction block
+	RecommendedModeNoTransaction RecommendedMode = "no-transaction"
+	// RecommendedModeEither is used for operations that run the same way in both modes
+	RecommendedModeEither RecommendedMode = "either"
+)
+
+// lockLevel returns the strength of a lock type, from AccessShare (1) to AccessExclusive (8)
+func lockLevel(lockType LockType) int {
+	switch lockType {
+	case AccessShare:
+		return 1
+	case RowShare:
+		return 2
+	case RowExclusive:
+		return 3
+	case ShareUpdateExclusive:
+		return 4
+	case Share:
+		return 5
+	case ShareRowExclusive:
+		return 6
+	case Exclusive:
+		return 7
+	case AccessExclusive:
+		return 8
+	default:
+		return 0
+	}
+}
+
 // Result represents the analysis result of a SQL statement
 type Result struct {
 	Severity   Severity
 	operation  string
+	qualifiers []string
 	lockType   LockType
 	tableLocks []string
-	message    string
+	lockTypes  map[string]LockType
+	allLocks   []string
+	notes      []string
+	scope      Scope
+
+	errorReason     ErrorReason
+	recommendedMode RecommendedMode
+	unrecognized    bool
+
+	statement *pg_query.Node
 }
 
-// Operation returns the operation type
+// Operation returns the operation label, including any qualifiers such as "(partial)"
 func (r *Result) Operation() string {
+	if len(r.qualifiers) == 0 {
+		return r.operation
+	}
+	return r.operation + " (" + strings.Join(r.qualifiers, ", ") + ")"
+}
+
+// BaseOperation returns the operation without qualifiers, as used for severity and suggestion lookup
+func (r *Result) BaseOperation() string {
 	return r.operation
 }
 
+// Statement returns the statement node the result was analyzed from; for PREPARE and EXECUTE of a
+// statement prepared earlier, this is the prepared statement
+func (r *Result) Statement() *pg_query.Node {
+	return r.statement
+}
+
 // LockType returns the lock type for the operation
 func (r *Result) LockType() LockType {
 	return r.lockType
@@ -82,7 +198,38 @@ func (r *Result) TableLocks() []string {
 	return r.tableLocks
 }
 
-// Message returns any additional message about the operation
-func (r *Result) Message() string {
-	return r.message
+// TableLockTypes returns the lock taken on each table listed by TableLocks
+func (r *Result) TableLockTypes() map[string]LockType {
+	return r.lockTypes
+}
+
+// AllLocks returns the table locks formatted like TableLocks, in the order PostgreSQL acquires them
+func (r *Result) AllLocks() []string {
+	return r.allLocks
+}
+
+// Scope returns how far the operation's locks reach, e.g. ScopeDatabase for REINDEX DATABASE
+func (r *Result) Scope() Scope {
+	return r.scope
+}
+
+// ErrorReason returns why the operation is ERROR, or ErrorReasonNone
+func (r *Result) ErrorReason() ErrorReason {
+	return r.errorReason
+}
+
+// RecommendedMode returns whether the operation should run inside or outside a transaction block
+func (r *Result) RecommendedMode() RecommendedMode {
+	return r.recommendedMode
+}
+
+// Notes returns additional notes about the operation
+func (r *Result) Notes() []string {
+	return r.notes
+}
+
+// Unrecognized reports whether the statement only got a generic fallback operation with the default
+// INFO severity and AccessShare lock, e.g. a DROP of an object type not analyzed specifically
+func (r *Result) Unrecognized() bool {
+	return r.unrecognized
 }
@@ -1,4 +1,4 @@
-package analyzer
+package identifier
 
 import "strings"
 
@@ -31,8 +31,8 @@ var postgresReservedWords = map[string]bool{
 	"similar": true, "verbose": true,
 }
 
-// needsQuoting checks if a PostgreSQL identifier needs quoting
-func needsQuoting(identifier string) bool {
+// NeedsQuoting checks if a PostgreSQL identifier needs quoting
+func NeedsQuoting(identifier string) bool {
 	if len(identifier) == 0 {
 		return false
 	}
@@ -71,9 +71,9 @@ func needsQuoting(identifier string) bool {
 	return false
 }
 
-// quoteIdentifier quotes an identifier if it needs quoting
-func quoteIdentifier(identifier string) string {
-	if needsQuoting(identifier) {
+// Quote quotes an identifier if it needs quoting
+func Quote(identifier string) string {
+	if NeedsQuoting(identifier) {
 		// Escape any embedded quotes by doubling them
 		escaped := strings.ReplaceAll(identifier, `"`, `""`)
 		return `"` + escaped + `"`
@@ -81,16 +81,16 @@ func quoteIdentifier(identifier string) string {
 	return identifier
 }
 
-// quoteQualifiedIdentifier quotes a schema-qualified identifier if needed
-func quoteQualifiedIdentifier(schema, identifier string) string {
+// QuoteQualified quotes a schema-qualified identifier if needed
+func QuoteQualified(schema, identifier string) string {
 	if schema != "" {
-		return quoteIdentifier(schema) + "." + quoteIdentifier(identifier)
+		return Quote(schema) + "." + Quote(identifier)
 	}
-	return quoteIdentifier(identifier)
+	return Quote(identifier)
 }
 
-// unquoteIdentifier removes quotes from an identifier if present
-func unquoteIdentifier(identifier string) string {
+// Unquote removes quotes from an identifier if present
+func Unquote(identifier string) string {
 	if len(identifier) >= 2 && identifier[0] == '"' && identifier[len(identifier)-1] == '"' {
 		// Remove outer quotes and unescape any doubled quotes
 		unquoted := identifier[1 : len(identifier)-1]
@@ -99,7 +99,30 @@ func unquoteIdentifier(identifier string) string {
 	return identifier
 }
 
-// isQuoted checks if an identifier is already quoted
-func isQuoted(identifier string) bool {
+// IsQuoted checks if an identifier is already quoted
+func IsQuoted(identifier string) bool {
 	return len(identifier) >= 2 && identifier[0] == '"' && identifier[len(identifier)-1] == '"'
 }
+
+// SplitQualified splits a possibly schema-qualified name into its unquoted parts; dots inside
+// quotes do not split, e.g. public."my.table" is public and my.table
+func SplitQualified(name string) []string {
+	var parts []string
+	var part strings.Builder
+	quoted := false
+	for i := 0; i < len(name); i++ {
+		switch ch := name[i]; {
+		case ch == '"' && quoted && i+1 < len(name) && name[i+1] == '"':
+			part.WriteByte('"')
+			i++
+		case ch == '"':
+			quoted = !quoted
+		case ch == '.' && !quoted:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(ch)
+		}
+	}
+	return append(parts, part.String())
+}
@@ -1,6 +1,9 @@
-package analyzer
+package identifier
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestNeedsQuoting(t *testing.T) {
 	tests := []struct {
@@ -43,14 +46,14 @@ func TestNeedsQuoting(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := needsQuoting(tt.identifier); got != tt.want {
-				t.Errorf("needsQuoting(%q) = %v, want %v", tt.identifier, got, tt.want)
+			if got := NeedsQuoting(tt.identifier); got != tt.want {
+				t.Errorf("NeedsQuoting(%q) = %v, want %v", tt.identifier, got, tt.want)
 			}
 		})
 	}
 }
 
-func TestQuoteIdentifier(t *testing.T) {
+func TestQuote(t *testing.T) {
 	tests := []struct {
 		name       string
 		identifier string
@@ -76,14 +79,14 @@ func TestQuoteIdentifier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := quoteIdentifier(tt.identifier); got != tt.want {
-				t.Errorf("quoteIdentifier(%q) = %q, want %q", tt.identifier, got, tt.want)
+			if got := Quote(tt.identifier); got != tt.want {
+				t.Errorf("Quote(%q) = %q, want %q", tt.identifier, got, tt.want)
 			}
 		})
 	}
 }
 
-func TestQuoteQualifiedIdentifier(t *testing.T) {
+func TestQuoteQualified(t *testing.T) {
 	tests := []struct {
 		name       string
 		schema     string
@@ -110,15 +113,15 @@ func TestQuoteQualifiedIdentifier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := quoteQualifiedIdentifier(tt.schema, tt.identifier); got != tt.want {
-				t.Errorf("quoteQualifiedIdentifier(%q, %q) = %q, want %q",
+			if got := QuoteQualified(tt.schema, tt.identifier); got != tt.want {
+				t.Errorf("QuoteQualified(%q, %q) = %q, want %q",
 					tt.schema, tt.identifier, got, tt.want)
 			}
 		})
 	}
 }
 
-func TestUnquoteIdentifier(t *testing.T) {
+func TestUnquote(t *testing.T) {
 	tests := []struct {
 		name       string
 		identifier string
@@ -145,8 +148,8 @@ func TestUnquoteIdentifier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := unquoteIdentifier(tt.identifier); got != tt.want {
-				t.Errorf("unquoteIdentifier(%q) = %q, want %q", tt.identifier, got, tt.want)
+			if got := Unquote(tt.identifier); got != tt.want {
+				t.Errorf("Unquote(%q) = %q, want %q", tt.identifier, got, tt.want)
 			}
 		})
 	}
@@ -169,8 +172,31 @@ func TestIsQuoted(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isQuoted(tt.identifier); got != tt.want {
-				t.Errorf("isQuoted(%q) = %v, want %v", tt.identifier, got, tt.want)
+			if got := IsQuoted(tt.identifier); got != tt.want {
+				t.Errorf("IsQuoted(%q) = %v, want %v", tt.identifier, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitQualified(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"simple", "users", []string{"users"}},
+		{"qualified", "public.users", []string{"public", "users"}},
+		{"quoted", `"Users"`, []string{"Users"}},
+		{"quoted qualified", `"My Schema"."Users"`, []string{"My Schema", "Users"}},
+		{"dot inside quotes", `public."my.table"`, []string{"public", "my.table"}},
+		{"escaped quotes", `"a""b"`, []string{`a"b`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitQualified(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitQualified(%q) = %q, want %q", tt.in, got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// deparseExpression renders an expression node back to SQL by deparsing it as a SELECT target
+func deparseExpression(expr *pg_query.Node) (string, error) {
+	result := &pg_query.ParseResult{
+		Stmts: []*pg_query.RawStmt{{
+			Stmt: &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: &pg_query.SelectStmt{
+				TargetList: []*pg_query.Node{{Node: &pg_query.Node_ResTarget{ResTarget: &pg_query.ResTarget{Val: expr}}}},
+			}}},
+		}},
+	}
+
+	sql, err := pg_query.Deparse(result)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(sql, "SELECT "), nil
+}
+
+// qualifyColumnRefs returns a copy of expr with every unqualified column reference
+// prefixed by qualifier, e.g. "payload" becomes "new.payload"
+func qualifyColumnRefs(expr *pg_query.Node, qualifier string) *pg_query.Node {
+	qualified := proto.Clone(expr).(*pg_query.Node)
+	walkMessages(qualified.ProtoReflect(), func(msg protoreflect.Message) {
+		ref, ok := msg.Interface().(*pg_query.ColumnRef)
+		if !ok || len(ref.Fields) != 1 || ref.Fields[0].GetString_() == nil {
+			return
+		}
+		ref.Fields = append([]*pg_query.Node{pg_query.MakeStrNode(qualifier)}, ref.Fields...)
+	})
+	return qualified
+}
+
+// walkMessages calls fn for msg and every message nested in it
+func walkMessages(msg protoreflect.Message, fn func(protoreflect.Message)) {
+	fn(msg)
+	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if fd.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				walkMessages(list.Get(i).Message(), fn)
+			}
+			return true
+		}
+		walkMessages(value.Message(), fn)
+		return true
+	})
+}
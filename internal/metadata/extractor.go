@@ -31,7 +31,8 @@ func (e *extractor) Extract(ast *pg_query.Node, operation string) map[string]int
 		e.extractDeleteMetadata(ast, metadata)
 	case "MERGE without WHERE":
 		e.extractMergeMetadata(ast, metadata)
-	case "CREATE INDEX", "CREATE UNIQUE INDEX":
+	case "CREATE INDEX", "CREATE UNIQUE INDEX",
+		"CREATE INDEX ON PARTITIONED TABLE", "CREATE UNIQUE INDEX ON PARTITIONED TABLE":
 		e.extractCreateIndexMetadata(ast, metadata)
 	case "DROP INDEX":
 		e.extractDropIndexMetadata(ast, metadata)
@@ -51,8 +52,14 @@ func (e *extractor) Extract(ast *pg_query.Node, operation string) map[string]int
 		e.extractAlterTableAddPrimaryKeyMetadata(ast, metadata)
 	case "ALTER TABLE ADD CONSTRAINT CHECK":
 		e.extractAlterTableAddConstraintCheckMetadata(ast, metadata)
+	case "ALTER TABLE ADD FOREIGN KEY":
+		e.extractAlterTableAddForeignKeyMetadata(ast, metadata)
 	case "ALTER TABLE SET NOT NULL", "ALTER TABLE ALTER COLUMN SET NOT NULL":
 		e.extractAlterTableSetNotNullMetadata(ast, metadata)
+	case "ALTER TABLE ENABLE ROW LEVEL SECURITY":
+		e.extractAlterTableEnableRowLevelSecurityMetadata(ast, metadata)
+	case "ALTER TABLE ATTACH PARTITION":
+		e.extractAlterTableAttachPartitionMetadata(ast, metadata)
 	case "CLUSTER":
 		e.extractClusterMetadata(ast, metadata)
 	case "REFRESH MATERIALIZED VIEW":
@@ -146,6 +153,20 @@ func (e *extractor) extractMergeMetadata(node *pg_query.Node, metadata map[strin
 		metadata["mergeCondition"] = "ON condition"
 		metadata["matchedAction"] = "UPDATE action"
 		metadata["notMatchedAction"] = "INSERT action"
+
+		// DELETE branches change how the merge can be batched
+		for _, when := range stmt.MergeWhenClauses {
+			clause := when.GetMergeWhenClause()
+			if clause == nil || clause.CommandType != pg_query.CmdType_CMD_DELETE {
+				continue
+			}
+			switch clause.MatchKind {
+			case pg_query.MergeMatchKind_MERGE_WHEN_MATCHED:
+				metadata["deleteMatched"] = true
+			case pg_query.MergeMatchKind_MERGE_WHEN_NOT_MATCHED_BY_SOURCE:
+				metadata["deleteNotMatchedBySource"] = true
+			}
+		}
 	}
 }
 
@@ -196,9 +217,12 @@ func (e *extractor) extractReindexMetadata(node *pg_query.Node, metadata map[str
 	if node.GetReindexStmt() != nil {
 		stmt := node.GetReindexStmt()
 
-		// Get index name
+		// Get index name, with its schema when qualified
 		if stmt.Relation != nil {
 			metadata["indexName"] = stmt.Relation.Relname
+			if stmt.Relation.Schemaname != "" {
+				metadata["schema"] = stmt.Relation.Schemaname
+			}
 		}
 	}
 }
@@ -260,8 +284,15 @@ func (e *extractor) extractAlterTableAddColumnMetadata(node *pg_query.Node, meta
 							metadata["dataType"] = strings.Join(typeNames, ".")
 						}
 
-						// For default value, just use a placeholder
+						// The volatile default is rendered as written, such as nextval('seq')
 						metadata["defaultValue"] = "gen_random_uuid()"
+						for _, constraint := range colDef.Constraints {
+							if constr := constraint.GetConstraint(); constr != nil && constr.Contype == pg_query.ConstrType_CONSTR_DEFAULT {
+								if expr, err := deparseExpression(constr.RawExpr); err == nil {
+									metadata["defaultValue"] = expr
+								}
+							}
+						}
 					}
 					break
 				}
@@ -307,6 +338,16 @@ func (e *extractor) extractAlterTableAlterColumnTypeMetadata(node *pg_query.Node
 						}
 						metadata["newType"] = typeStr
 					}
+
+					// The USING expression must also drive the sync trigger and backfill
+					if colDef := alterCmd.GetDef().GetColumnDef(); colDef != nil && colDef.RawDefault != nil {
+						if expr, err := deparseExpression(colDef.RawDefault); err == nil {
+							metadata["usingExpression"] = expr
+						}
+						if expr, err := deparseExpression(qualifyColumnRefs(colDef.RawDefault, "new")); err == nil {
+							metadata["triggerExpression"] = expr
+						}
+					}
 					break
 				}
 			}
@@ -360,6 +401,50 @@ func (e *extractor) extractAlterTableAddConstraintCheckMetadata(node *pg_query.N
 	}
 }
 
+// extractAlterTableAddForeignKeyMetadata extracts metadata for ALTER TABLE ADD FOREIGN KEY
+func (e *extractor) extractAlterTableAddForeignKeyMetadata(node *pg_query.Node, metadata map[string]interface{}) {
+	if node.GetAlterTableStmt() != nil {
+		stmt := node.GetAlterTableStmt()
+
+		// Get table name
+		if stmt.Relation != nil {
+			metadata["tableName"] = stmt.Relation.Relname
+		}
+
+		// Get constraint details from first ADD FOREIGN KEY command
+		for _, cmd := range stmt.Cmds {
+			if alterCmd := cmd.GetAlterTableCmd(); alterCmd != nil && alterCmd.Subtype == pg_query.AlterTableType_AT_AddConstraint {
+				constraint := alterCmd.GetDef().GetConstraint()
+				if constraint == nil || constraint.Contype != pg_query.ConstrType_CONSTR_FOREIGN {
+					continue
+				}
+				if constraint.Conname != "" {
+					metadata["constraintName"] = constraint.Conname
+				}
+				metadata["columns"] = stringList(constraint.FkAttrs)
+				if constraint.Pktable != nil {
+					metadata["refTable"] = constraint.Pktable.Relname
+				}
+				if refColumns := stringList(constraint.PkAttrs); len(refColumns) > 0 {
+					metadata["refColumns"] = refColumns
+				}
+				break
+			}
+		}
+	}
+}
+
+// stringList returns the values of a list of String nodes, such as constraint columns
+func stringList(nodes []*pg_query.Node) []string {
+	var values []string
+	for _, node := range nodes {
+		if str := node.GetString_(); str != nil {
+			values = append(values, str.Sval)
+		}
+	}
+	return values
+}
+
 // extractAlterTableSetNotNullMetadata extracts metadata for ALTER TABLE SET NOT NULL
 func (e *extractor) extractAlterTableSetNotNullMetadata(node *pg_query.Node, metadata map[string]interface{}) {
 	if node.GetAlterTableStmt() != nil {
@@ -382,6 +467,127 @@ func (e *extractor) extractAlterTableSetNotNullMetadata(node *pg_query.Node, met
 	}
 }
 
+// extractAlterTableEnableRowLevelSecurityMetadata extracts metadata for ALTER TABLE ENABLE ROW LEVEL SECURITY
+func (e *extractor) extractAlterTableEnableRowLevelSecurityMetadata(node *pg_query.Node, metadata map[string]interface{}) {
+	if stmt := node.GetAlterTableStmt(); stmt != nil && stmt.Relation != nil {
+		metadata["tableName"] = stmt.Relation.Relname
+	}
+}
+
+// partitionKeyPlaceholder stands for the parent's partition key column, which ATTACH PARTITION does not name
+const partitionKeyPlaceholder = "<partition_key>"
+
+// extractAlterTableAttachPartitionMetadata extracts metadata for ALTER TABLE ATTACH PARTITION.
+// bounds is the FOR VALUES clause and boundsCheck the CHECK expression implied by it; both are
+// only set for LIST and single-column RANGE bounds, which a CHECK constraint can express.
+func (e *extractor) extractAlterTableAttachPartitionMetadata(node *pg_query.Node, metadata map[string]interface{}) {
+	stmt := node.GetAlterTableStmt()
+	if stmt == nil {
+		return
+	}
+	if stmt.Relation != nil {
+		metadata["parentTable"] = stmt.Relation.Relname
+	}
+
+	for _, cmd := range stmt.Cmds {
+		alterCmd := cmd.GetAlterTableCmd()
+		if alterCmd == nil || alterCmd.Subtype != pg_query.AlterTableType_AT_AttachPartition {
+			continue
+		}
+		pc := alterCmd.GetDef().GetPartitionCmd()
+		if pc == nil {
+			return
+		}
+		if pc.Name != nil {
+			metadata["partition"] = pc.Name.Relname
+		}
+		if bounds, check, ok := partitionBoundsCheck(pc.Bound); ok {
+			metadata["bounds"] = bounds
+			metadata["boundsCheck"] = check
+		}
+		return
+	}
+}
+
+// partitionBoundsCheck returns the FOR VALUES clause of a partition bound and the CHECK expression
+// matching it, written against the partition key placeholder
+func partitionBoundsCheck(bound *pg_query.PartitionBoundSpec) (string, string, bool) {
+	if bound == nil || bound.IsDefault {
+		return "", "", false
+	}
+	key := partitionKeyPlaceholder
+
+	switch bound.Strategy {
+	case "l":
+		var values []string
+		hasNull := false
+		for _, datum := range bound.Listdatums {
+			if c := datum.GetAConst(); c != nil && c.Isnull {
+				hasNull = true
+				continue
+			}
+			value, err := deparseExpression(datum)
+			if err != nil {
+				return "", "", false
+			}
+			values = append(values, value)
+		}
+
+		clause := strings.Join(values, ", ")
+		if hasNull {
+			clause = strings.Join(append(values, "NULL"), ", ")
+		}
+		var check string
+		switch {
+		case len(values) == 0:
+			check = key + " IS NULL"
+		case hasNull:
+			check = fmt.Sprintf("%s IS NULL OR %s IN (%s)", key, key, strings.Join(values, ", "))
+		default:
+			check = fmt.Sprintf("%s IS NOT NULL AND %s IN (%s)", key, key, strings.Join(values, ", "))
+		}
+		return fmt.Sprintf("FOR VALUES IN (%s)", clause), check, true
+	case "r":
+		// Multi-column range bounds compare row values, which a simple CHECK cannot mirror
+		if len(bound.Lowerdatums) != 1 || len(bound.Upperdatums) != 1 {
+			return "", "", false
+		}
+		lower, lowerSet, ok := rangeDatum(bound.Lowerdatums[0], "MINVALUE")
+		if !ok {
+			return "", "", false
+		}
+		upper, upperSet, ok := rangeDatum(bound.Upperdatums[0], "MAXVALUE")
+		if !ok {
+			return "", "", false
+		}
+
+		conditions := []string{key + " IS NOT NULL"}
+		if lowerSet {
+			conditions = append(conditions, fmt.Sprintf("%s >= %s", key, lower))
+		}
+		if upperSet {
+			conditions = append(conditions, fmt.Sprintf("%s < %s", key, upper))
+		}
+		return fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", lower, upper), strings.Join(conditions, " AND "), true
+	}
+	return "", "", false
+}
+
+// rangeDatum renders one side of a range bound, reporting whether it limits the range;
+// unbounded is MINVALUE or MAXVALUE, which the parser leaves as a column reference
+func rangeDatum(datum *pg_query.Node, unbounded string) (string, bool, bool) {
+	if ref := datum.GetColumnRef(); ref != nil && len(ref.Fields) == 1 {
+		if str := ref.Fields[0].GetString_(); str != nil && strings.EqualFold(str.Sval, unbounded) {
+			return unbounded, false, true
+		}
+	}
+	value, err := deparseExpression(datum)
+	if err != nil {
+		return "", false, false
+	}
+	return value, true, true
+}
+
 // extractClusterMetadata extracts metadata for CLUSTER
 func (e *extractor) extractClusterMetadata(node *pg_query.Node, metadata map[string]interface{}) {
 	if node.GetClusterStmt() != nil {
@@ -390,10 +596,15 @@ func (e *extractor) extractClusterMetadata(node *pg_query.Node, metadata map[str
 		// Get table name
 		if stmt.Relation != nil {
 			metadata["tableName"] = stmt.Relation.Relname
+			if stmt.Relation.Schemaname != "" {
+				metadata["schema"] = stmt.Relation.Schemaname
+			}
 		}
 
-		// Get index name
-		metadata["indexName"] = stmt.Indexname
+		// Get index name; CLUSTER without USING reuses the index the table was last clustered on
+		if stmt.Indexname != "" {
+			metadata["indexName"] = stmt.Indexname
+		}
 	}
 }
 
@@ -418,6 +629,9 @@ func (e *extractor) extractVacuumFullMetadata(node *pg_query.Node, metadata map[
 		if len(stmt.Rels) > 0 {
 			if vacRel := stmt.Rels[0].GetVacuumRelation(); vacRel != nil && vacRel.Relation != nil {
 				metadata["tableName"] = vacRel.Relation.Relname
+				if vacRel.Relation.Schemaname != "" {
+					metadata["schema"] = vacRel.Relation.Schemaname
+				}
 			}
 		}
 	}
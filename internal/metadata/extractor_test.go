@@ -37,6 +37,21 @@ func TestExtractMetadata(t *testing.T) {
 			},
 			shouldHaveIDColumn: true,
 		},
+		{
+			name:      "MERGE with DELETE branches",
+			sql:       "MERGE INTO users t USING staging s ON t.id = s.id WHEN MATCHED AND s.deleted THEN DELETE WHEN NOT MATCHED BY SOURCE THEN DELETE;",
+			operation: "MERGE without WHERE",
+			expectedMetadata: map[string]interface{}{
+				"targetTable":              "users",
+				"sourceTable":              "source_table",
+				"mergeCondition":           "ON condition",
+				"matchedAction":            "UPDATE action",
+				"notMatchedAction":         "INSERT action",
+				"deleteMatched":            true,
+				"deleteNotMatchedBySource": true,
+			},
+			shouldHaveIDColumn: true,
+		},
 		{
 			name:      "CREATE INDEX",
 			sql:       "CREATE INDEX idx_users_email ON users(email);",
@@ -73,6 +88,15 @@ func TestExtractMetadata(t *testing.T) {
 				"indexName": "idx_users_email",
 			},
 		},
+		{
+			name:      "REINDEX schema-qualified quoted index",
+			sql:       `REINDEX INDEX "app"."My Index";`,
+			operation: "REINDEX",
+			expectedMetadata: map[string]interface{}{
+				"indexName": "My Index",
+				"schema":    "app",
+			},
+		},
 		{
 			name:      "REINDEX TABLE",
 			sql:       "REINDEX TABLE users;",
@@ -100,6 +124,17 @@ func TestExtractMetadata(t *testing.T) {
 				"defaultValue": "gen_random_uuid()",
 			},
 		},
+		{
+			name:      "ALTER TABLE ADD COLUMN with nextval DEFAULT",
+			sql:       "ALTER TABLE orders ADD COLUMN n int DEFAULT nextval('order_seq');",
+			operation: "ALTER TABLE ADD COLUMN with volatile DEFAULT",
+			expectedMetadata: map[string]interface{}{
+				"tableName":    "orders",
+				"columnName":   "n",
+				"dataType":     "pg_catalog.int4",
+				"defaultValue": "nextval('order_seq')",
+			},
+		},
 		{
 			name:      "ALTER TABLE ALTER COLUMN TYPE",
 			sql:       "ALTER TABLE users ALTER COLUMN email TYPE VARCHAR(255);",
@@ -110,6 +145,18 @@ func TestExtractMetadata(t *testing.T) {
 				"newType":    "VARCHAR(255)",
 			},
 		},
+		{
+			name:      "ALTER TABLE ALTER COLUMN TYPE USING",
+			sql:       "ALTER TABLE events ALTER COLUMN payload TYPE jsonb USING payload::jsonb || '{}';",
+			operation: "ALTER TABLE ALTER COLUMN TYPE",
+			expectedMetadata: map[string]interface{}{
+				"tableName":         "events",
+				"columnName":        "payload",
+				"newType":           "JSONB",
+				"usingExpression":   "payload::jsonb || '{}'",
+				"triggerExpression": "new.payload::jsonb || '{}'",
+			},
+		},
 		{
 			name:      "ALTER TABLE ADD PRIMARY KEY",
 			sql:       "ALTER TABLE users ADD PRIMARY KEY (id);",
@@ -129,6 +176,28 @@ func TestExtractMetadata(t *testing.T) {
 				"checkExpression": "CHECK(...)",
 			},
 		},
+		{
+			name:      "ALTER TABLE ADD FOREIGN KEY",
+			sql:       "ALTER TABLE orders ADD CONSTRAINT fk_orders_user FOREIGN KEY (user_id) REFERENCES users (id);",
+			operation: "ALTER TABLE ADD FOREIGN KEY",
+			expectedMetadata: map[string]interface{}{
+				"tableName":      "orders",
+				"constraintName": "fk_orders_user",
+				"columns":        "user_id",
+				"refTable":       "users",
+				"refColumns":     "id",
+			},
+		},
+		{
+			name:      "ALTER TABLE ADD FOREIGN KEY without names",
+			sql:       "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users;",
+			operation: "ALTER TABLE ADD FOREIGN KEY",
+			expectedMetadata: map[string]interface{}{
+				"tableName": "orders",
+				"columns":   "user_id",
+				"refTable":  "users",
+			},
+		},
 		{
 			name:      "ALTER TABLE SET NOT NULL",
 			sql:       "ALTER TABLE users ALTER COLUMN email SET NOT NULL;",
@@ -138,6 +207,56 @@ func TestExtractMetadata(t *testing.T) {
 				"column":    "email",
 			},
 		},
+		{
+			name:      "ALTER TABLE ENABLE ROW LEVEL SECURITY",
+			sql:       "ALTER TABLE accounts ENABLE ROW LEVEL SECURITY;",
+			operation: "ALTER TABLE ENABLE ROW LEVEL SECURITY",
+			expectedMetadata: map[string]interface{}{
+				"tableName": "accounts",
+			},
+		},
+		{
+			name:      "ALTER TABLE ATTACH PARTITION range",
+			sql:       "ALTER TABLE events ATTACH PARTITION events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');",
+			operation: "ALTER TABLE ATTACH PARTITION",
+			expectedMetadata: map[string]interface{}{
+				"parentTable": "events",
+				"partition":   "events_2024",
+				"bounds":      "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+				"boundsCheck": "<partition_key> IS NOT NULL AND <partition_key> >= '2024-01-01' AND <partition_key> < '2025-01-01'",
+			},
+		},
+		{
+			name:      "ALTER TABLE ATTACH PARTITION unbounded range",
+			sql:       "ALTER TABLE events ATTACH PARTITION events_old FOR VALUES FROM (MINVALUE) TO (100);",
+			operation: "ALTER TABLE ATTACH PARTITION",
+			expectedMetadata: map[string]interface{}{
+				"parentTable": "events",
+				"partition":   "events_old",
+				"bounds":      "FOR VALUES FROM (MINVALUE) TO (100)",
+				"boundsCheck": "<partition_key> IS NOT NULL AND <partition_key> < 100",
+			},
+		},
+		{
+			name:      "ALTER TABLE ATTACH PARTITION list with NULL",
+			sql:       "ALTER TABLE orders ATTACH PARTITION orders_eu FOR VALUES IN ('de', 'fr', NULL);",
+			operation: "ALTER TABLE ATTACH PARTITION",
+			expectedMetadata: map[string]interface{}{
+				"parentTable": "orders",
+				"partition":   "orders_eu",
+				"bounds":      "FOR VALUES IN ('de', 'fr', NULL)",
+				"boundsCheck": "<partition_key> IS NULL OR <partition_key> IN ('de', 'fr')",
+			},
+		},
+		{
+			name:      "ALTER TABLE ATTACH PARTITION DEFAULT has no bounds",
+			sql:       "ALTER TABLE orders ATTACH PARTITION orders_other DEFAULT;",
+			operation: "ALTER TABLE ATTACH PARTITION",
+			expectedMetadata: map[string]interface{}{
+				"parentTable": "orders",
+				"partition":   "orders_other",
+			},
+		},
 		{
 			name:      "CLUSTER",
 			sql:       "CLUSTER users USING idx_users_id;",
@@ -147,6 +266,14 @@ func TestExtractMetadata(t *testing.T) {
 				"indexName": "idx_users_id",
 			},
 		},
+		{
+			name:      "CLUSTER on the previous index",
+			sql:       "CLUSTER users;",
+			operation: "CLUSTER",
+			expectedMetadata: map[string]interface{}{
+				"tableName": "users",
+			},
+		},
 		{
 			name:      "REFRESH MATERIALIZED VIEW",
 			sql:       "REFRESH MATERIALIZED VIEW user_stats;",
@@ -163,6 +290,15 @@ func TestExtractMetadata(t *testing.T) {
 				"tableName": "users",
 			},
 		},
+		{
+			name:      "VACUUM FULL with schema",
+			sql:       "VACUUM FULL app.users;",
+			operation: "VACUUM FULL",
+			expectedMetadata: map[string]interface{}{
+				"tableName": "users",
+				"schema":    "app",
+			},
+		},
 	}
 
 	for _, tt := range tests {
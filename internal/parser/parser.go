@@ -2,11 +2,13 @@ package parser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	pg_query "github.com/pganalyze/pg_query_go/v6"
+	pgparser "github.com/pganalyze/pg_query_go/v6/parser"
 )
 
 // Constants for parser operations
@@ -18,6 +20,15 @@ const (
 	initialLineNumber = 1
 )
 
+// GrammarVersion returns the PostgreSQL version whose grammar the parser follows, e.g. "17.4"
+func GrammarVersion() string {
+	result, err := pg_query.Parse("")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", result.Version/10000, result.Version%100)
+}
+
 // utf8BOM represents the UTF-8 byte order mark
 var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
 
@@ -31,12 +42,79 @@ type ParsedStatement struct {
 
 	// LineNumber is the line number where this statement starts (1-based)
 	LineNumber int
+
+	// File is the source file of this statement, when known
+	File string
+
+	// Index is the 0-based position of this statement in the analyzed input, set by callers that
+	// number statements before dropping some of them
+	Index int
+}
+
+// NormalizedSQL returns the statement with its literals replaced by $1, $2, ...,
+// so statements differing only in constants compare equal
+func (s ParsedStatement) NormalizedSQL() (string, error) {
+	return pg_query.Normalize(s.SQL)
+}
+
+// CanonicalSQL returns the statement deparsed from its AST, with consistent keyword casing and spacing
+func (s ParsedStatement) CanonicalSQL() (string, error) {
+	if s.AST == nil || len(s.AST.Stmts) == 0 {
+		return "", fmt.Errorf("no parsed statement to deparse")
+	}
+	return pg_query.Deparse(s.AST)
 }
 
 // ParseResult represents the result of parsing SQL content
 type ParseResult struct {
 	// Statements contains all successfully parsed SQL statements in order
 	Statements []ParsedStatement
+
+	// Errors contains statements that failed to parse, only populated by ParseSQLWithRecovery
+	Errors []StatementError
+}
+
+// StatementError describes a single statement that failed to parse
+type StatementError struct {
+	// SQL is the original SQL text for this statement
+	SQL string
+
+	// LineNumber is the line number where this statement starts (1-based)
+	LineNumber int
+
+	// Statement is the 1-based position of this statement in the input
+	Statement int
+
+	// File is the source file of this statement, when known
+	File string
+
+	// Err is the underlying parse error
+	Err error
+}
+
+// Error formats the error like the one returned when parsing stops at the first failure
+func (e StatementError) Error() string {
+	return fmt.Sprintf("parse error at line %d, statement %d: %v", e.LineNumber, e.Statement, e.Err)
+}
+
+// Unwrap returns the underlying parse error
+func (e StatementError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorLine returns the line of the error itself when the parser reports a position in the
+// statement, and the line where the statement starts otherwise
+func (e StatementError) ErrorLine() int {
+	var pgErr *pgparser.Error
+	if !errors.As(e.Err, &pgErr) || pgErr.Cursorpos <= 0 {
+		return e.LineNumber
+	}
+	// The position counts characters from 1, not bytes
+	runes := []rune(e.SQL)
+	if pgErr.Cursorpos > len(runes) {
+		return e.LineNumber
+	}
+	return e.LineNumber + strings.Count(string(runes[:pgErr.Cursorpos-1]), "\n")
 }
 
 // Parser interface defines the contract for SQL parsing operations
@@ -44,11 +122,18 @@ type Parser interface {
 	// ParseSQL parses a SQL string and returns parsed statements
 	ParseSQL(sql string) (*ParseResult, error)
 
+	// ParseSQLWithRecovery parses a SQL string, collecting statements that fail to parse
+	// into ParseResult.Errors instead of stopping at the first failure
+	ParseSQLWithRecovery(sql string) (*ParseResult, error)
+
 	// ParseFile reads and parses SQL from a file
 	ParseFile(filepath string) (*ParseResult, error)
 
 	// ParseFiles reads and parses multiple SQL files
 	ParseFiles(filepaths []string) (*ParseResult, error)
+
+	// ParseStatement parses a single, already split SQL statement
+	ParseStatement(sql string, lineNumber int) (*ParsedStatement, error)
 }
 
 // parser implements the Parser interface
@@ -61,6 +146,16 @@ func NewParser() Parser {
 
 // ParseSQL parses SQL string and returns parsed statements
 func (p *parser) ParseSQL(sql string) (*ParseResult, error) {
+	return p.parseSQL(sql, false)
+}
+
+// ParseSQLWithRecovery parses SQL string, continuing past statements that fail to parse
+func (p *parser) ParseSQLWithRecovery(sql string) (*ParseResult, error) {
+	return p.parseSQL(sql, true)
+}
+
+// parseSQL splits and parses SQL; with recovery, parse errors are collected per statement
+func (p *parser) parseSQL(sql string, recovery bool) (*ParseResult, error) {
 	if sql == "" {
 		return emptyParseResult(), nil
 	}
@@ -78,7 +173,7 @@ func (p *parser) ParseSQL(sql string) (*ParseResult, error) {
 		return emptyParseResult(), nil
 	}
 
-	return p.parseStatements(sql, statements)
+	return p.parseStatements(sql, statements, recovery)
 }
 
 // ParseFile reads and parses SQL from a file
@@ -116,8 +211,30 @@ func (p *parser) ParseFiles(filepaths []string) (*ParseResult, error) {
 	return &ParseResult{Statements: allStatements}, nil
 }
 
+// ParseStatement parses a single, already split SQL statement without re-splitting it
+func (p *parser) ParseStatement(sql string, lineNumber int) (*ParsedStatement, error) {
+	sql = strings.TrimSpace(cleanSQL(sql))
+	if sql == "" {
+		return nil, fmt.Errorf("statement cannot be empty")
+	}
+
+	ast, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("parse error at line %d: %w", lineNumber, err)
+	}
+	if len(ast.Stmts) != 1 {
+		return nil, fmt.Errorf("expected exactly one statement at line %d, got %d", lineNumber, len(ast.Stmts))
+	}
+
+	return &ParsedStatement{
+		AST:        ast,
+		SQL:        strings.TrimSpace(strings.TrimSuffix(sql, ";")),
+		LineNumber: lineNumber,
+	}, nil
+}
+
 // parseStatements processes individual SQL statements and creates ParsedStatement objects
-func (p *parser) parseStatements(originalSQL string, statements []string) (*ParseResult, error) {
+func (p *parser) parseStatements(originalSQL string, statements []string, recovery bool) (*ParseResult, error) {
 	result := &ParseResult{
 		Statements: make([]ParsedStatement, 0, len(statements)),
 	}
@@ -133,11 +250,25 @@ func (p *parser) parseStatements(originalSQL string, statements []string) (*Pars
 
 		stmtStart := offset + idx
 		lineNum := calculateLineNumber(originalSQL, stmtStart)
+		if !terminated(originalSQL[stmtStart+len(stmtSQL):]) {
+			stmtSQL = trimTrailingComments(stmtSQL)
+		}
 
 		// Parse individual statement to get its AST
 		ast, err := pg_query.Parse(stmtSQL)
 		if err != nil {
-			return nil, fmt.Errorf("parse error at line %d, statement %d: %w", lineNum, i+1, err)
+			stmtErr := StatementError{
+				SQL:        stmtSQL,
+				LineNumber: lineNum,
+				Statement:  i + 1,
+				Err:        err,
+			}
+			if !recovery {
+				return nil, stmtErr
+			}
+			result.Errors = append(result.Errors, stmtErr)
+			offset = stmtStart + len(stmtSQL)
+			continue
 		}
 
 		result.Statements = append(result.Statements, ParsedStatement{
@@ -153,6 +284,27 @@ func (p *parser) parseStatements(originalSQL string, statements []string) (*Pars
 	return result, nil
 }
 
+// terminated reports whether the text after a statement starts with its semicolon
+func terminated(rest string) bool {
+	return strings.HasPrefix(strings.TrimSpace(rest), ";")
+}
+
+// trimTrailingComments drops the comments after the last token of a statement that is not
+// terminated by a semicolon, so the final statement of a file is reported with the same text
+// whether or not the file ends with a semicolon
+func trimTrailingComments(sql string) string {
+	tokens, err := Scan(sql)
+	if err != nil {
+		return sql
+	}
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Kind != TokenComment {
+			return sql[:tokens[i].End]
+		}
+	}
+	return sql
+}
+
 // cleanSQL removes BOM and normalizes the SQL string
 func cleanSQL(sql string) string {
 	return string(stripBOM([]byte(sql)))
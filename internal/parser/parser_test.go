@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	pathutil "path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -500,6 +502,30 @@ UPDATE users
 COMMIT;`,
 			expectedLines: []int{1, 2, 5},
 		},
+		{
+			name: "Dollar-quoted function body followed by statements",
+			sql: `CREATE FUNCTION cleanup() RETURNS void AS $$
+BEGIN
+  DELETE FROM sessions;
+  DROP TABLE users;
+END;
+$$ LANGUAGE plpgsql;
+DROP TABLE users;`,
+			expectedLines: []int{1, 7},
+		},
+		{
+			name: "Tagged dollar quotes with nested dollar quotes",
+			sql: `CREATE FUNCTION build() RETURNS void AS $func$
+BEGIN
+  EXECUTE $sql$CREATE TABLE t (id int); DROP TABLE t;$sql$;
+  PERFORM $$;$$;
+END;
+$func$ LANGUAGE plpgsql;
+
+SELECT 1;
+DROP TABLE users;`,
+			expectedLines: []int{1, 8, 9},
+		},
 	}
 
 	parser := NewParser()
@@ -524,6 +550,280 @@ COMMIT;`,
 	}
 }
 
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		lineNumber int
+		wantSQL    string
+		wantErr    string
+	}{
+		{
+			name:       "single statement keeps line number",
+			sql:        "  UPDATE users SET active = false; ",
+			lineNumber: 42,
+			wantSQL:    "UPDATE users SET active = false",
+		},
+		{
+			name:       "empty statement",
+			sql:        "   ",
+			lineNumber: 1,
+			wantErr:    "statement cannot be empty",
+		},
+		{
+			name:       "multiple statements",
+			sql:        "SELECT 1; SELECT 2",
+			lineNumber: 3,
+			wantErr:    "expected exactly one statement at line 3, got 2",
+		},
+		{
+			name:       "syntax error",
+			sql:        "SELEC 1",
+			lineNumber: 5,
+			wantErr:    "parse error at line 5",
+		},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.ParseStatement(tt.sql, tt.lineNumber)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStatement() error = %v", err)
+			}
+			if stmt.SQL != tt.wantSQL {
+				t.Errorf("expected SQL %q, got %q", tt.wantSQL, stmt.SQL)
+			}
+			if stmt.LineNumber != tt.lineNumber {
+				t.Errorf("expected line %d, got %d", tt.lineNumber, stmt.LineNumber)
+			}
+		})
+	}
+}
+
+func TestNormalizedSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "literals replaced",
+			sql:  "UPDATE t SET x=5 WHERE id=1",
+			want: "UPDATE t SET x=$1 WHERE id=$2",
+		},
+		{
+			name: "string and list literals",
+			sql:  "DELETE FROM users WHERE status = 'inactive' AND id IN (1, 2)",
+			want: "DELETE FROM users WHERE status = $1 AND id IN ($2, $3)",
+		},
+		{
+			name: "no literals",
+			sql:  "ALTER TABLE users DROP COLUMN age",
+			want: "ALTER TABLE users DROP COLUMN age",
+		},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.ParseStatement(tt.sql, 1)
+			if err != nil {
+				t.Fatalf("ParseStatement() error = %v", err)
+			}
+			got, err := stmt.NormalizedSQL()
+			if err != nil {
+				t.Fatalf("NormalizedSQL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected normalized SQL %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCanonicalSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "keywords and spacing",
+			sql:  "update   t\n  set x=5    where id=1",
+			want: "UPDATE t SET x = 5 WHERE id = 1",
+		},
+		{
+			name: "comments dropped",
+			sql:  "-- add index\ncreate index   idx_users_email on users(email)",
+			want: "CREATE INDEX idx_users_email ON users USING btree (email)",
+		},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.ParseStatement(tt.sql, 1)
+			if err != nil {
+				t.Fatalf("ParseStatement() error = %v", err)
+			}
+			got, err := stmt.CanonicalSQL()
+			if err != nil {
+				t.Fatalf("CanonicalSQL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected canonical SQL %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := (ParsedStatement{SQL: "SELECT 1"}).CanonicalSQL(); err == nil {
+		t.Error("expected an error for a statement without AST")
+	}
+}
+
+func TestDollarQuotedSegmentation(t *testing.T) {
+	sql := `CREATE FUNCTION build() RETURNS void AS $func$
+BEGIN
+  EXECUTE $sql$CREATE TABLE t (id int); DROP TABLE t;$sql$;
+  PERFORM $$;$$;
+END;
+$func$ LANGUAGE plpgsql;
+DROP TABLE users;`
+
+	result, err := NewParser().ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("ParseSQL() error = %v", err)
+	}
+
+	if len(result.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(result.Statements))
+	}
+
+	if !strings.HasSuffix(result.Statements[0].SQL, "$func$ LANGUAGE plpgsql") {
+		t.Errorf("function body was split internally: %q", result.Statements[0].SQL)
+	}
+	if result.Statements[1].SQL != "DROP TABLE users" {
+		t.Errorf("expected trailing statement %q, got %q", "DROP TABLE users", result.Statements[1].SQL)
+	}
+	if result.Statements[1].LineNumber != 7 {
+		t.Errorf("expected trailing statement at line 7, got %d", result.Statements[1].LineNumber)
+	}
+}
+
+func TestTrailingSemicolon(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		line int
+	}{
+		{
+			name: "single line",
+			body: "SELECT 1;\nUPDATE users SET active = false",
+			want: "UPDATE users SET active = false",
+			line: 2,
+		},
+		{
+			name: "multi-line",
+			body: "SELECT 1;\n\nUPDATE users\n  SET active = false\n  WHERE id = 1",
+			want: "UPDATE users\n  SET active = false\n  WHERE id = 1",
+			line: 3,
+		},
+		{
+			name: "line comment inside the statement",
+			body: "SELECT 1;\nUPDATE users -- all rows\n  SET active = false",
+			want: "UPDATE users -- all rows\n  SET active = false",
+			line: 2,
+		},
+		{
+			name: "comment inside the statement",
+			body: "SELECT 1;\nUPDATE users /* all */ SET active = false",
+			want: "UPDATE users /* all */ SET active = false",
+			line: 2,
+		},
+		{
+			name: "dollar-quoted body",
+			body: "SELECT 1;\nDO $$ BEGIN PERFORM 1; END $$",
+			want: "DO $$ BEGIN PERFORM 1; END $$",
+			line: 2,
+		},
+	}
+
+	endings := map[string]string{
+		"no semicolon":                "",
+		"no semicolon, newline":       "\n",
+		"no semicolon, line comment":  " -- done\n",
+		"no semicolon, block comment": "\n/* done */",
+		"semicolon":                   ";",
+		"semicolon, newline":          ";\n",
+		"semicolon, line comment":     "; -- done\n",
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		for ending, suffix := range endings {
+			t.Run(tt.name+"/"+ending, func(t *testing.T) {
+				result, err := parser.ParseSQL(tt.body + suffix)
+				if err != nil {
+					t.Fatalf("ParseSQL() error = %v", err)
+				}
+				if len(result.Statements) != 2 {
+					t.Fatalf("expected 2 statements, got %d", len(result.Statements))
+				}
+				last := result.Statements[1]
+				if last.SQL != tt.want {
+					t.Errorf("expected SQL %q, got %q", tt.want, last.SQL)
+				}
+				if last.LineNumber != tt.line {
+					t.Errorf("expected line %d, got %d", tt.line, last.LineNumber)
+				}
+			})
+		}
+	}
+}
+
+func TestParseSQLWithRecovery(t *testing.T) {
+	sql := "UPDATE users SET active = false;\nSELEC * FROM users;\nDROP TABLE old_users;"
+
+	// Without recovery the first failure stops parsing
+	if _, err := NewParser().ParseSQL(sql); err == nil {
+		t.Fatal("ParseSQL() expected error for invalid statement")
+	}
+
+	result, err := NewParser().ParseSQLWithRecovery(sql)
+	if err != nil {
+		t.Fatalf("ParseSQLWithRecovery() error = %v", err)
+	}
+
+	if len(result.Statements) != 2 {
+		t.Fatalf("expected 2 parsed statements, got %d", len(result.Statements))
+	}
+	if result.Statements[0].SQL != "UPDATE users SET active = false" || result.Statements[0].LineNumber != 1 {
+		t.Errorf("unexpected first statement %q at line %d", result.Statements[0].SQL, result.Statements[0].LineNumber)
+	}
+	if result.Statements[1].SQL != "DROP TABLE old_users" || result.Statements[1].LineNumber != 3 {
+		t.Errorf("unexpected second statement %q at line %d", result.Statements[1].SQL, result.Statements[1].LineNumber)
+	}
+
+	if len(result.Errors) != 1 {
+		t.Fatalf("expected 1 parse error, got %d", len(result.Errors))
+	}
+	parseErr := result.Errors[0]
+	if parseErr.SQL != "SELEC * FROM users" || parseErr.LineNumber != 2 || parseErr.Statement != 2 {
+		t.Errorf("unexpected parse error %+v", parseErr)
+	}
+	if !strings.Contains(parseErr.Error(), "parse error at line 2, statement 2") {
+		t.Errorf("unexpected error message %q", parseErr.Error())
+	}
+}
+
 // Helper function to generate large SQL content for testing
 func generateLargeSQL(numStatements int) string {
 	var sql string
@@ -598,3 +898,76 @@ func TestASTContent(t *testing.T) {
 		})
 	}
 }
+
+func TestScan(t *testing.T) {
+	sql := "SELECT 'a', 42 -- note\nFROM users"
+	tokens, err := Scan(sql)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	want := []struct {
+		text string
+		kind TokenKind
+	}{
+		{"SELECT", TokenKeyword},
+		{"'a'", TokenString},
+		{",", TokenOther},
+		{"42", TokenNumber},
+		{"-- note", TokenComment},
+		{"FROM", TokenKeyword},
+		{"users", TokenOther},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(want), len(tokens), tokens)
+	}
+	for i, token := range tokens {
+		if text := sql[token.Start:token.End]; text != want[i].text || token.Kind != want[i].kind {
+			t.Errorf("Token %d: got %q (kind %d), want %q (kind %d)", i, text, token.Kind, want[i].text, want[i].kind)
+		}
+	}
+}
+
+func TestParseSQL_ErrorLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		wantStartLine int
+		wantErrorLine int
+	}{
+		{
+			name:          "error on the first line of the statement",
+			sql:           "SELECT 1;\nSELEC * FROM users;",
+			wantStartLine: 2,
+			wantErrorLine: 2,
+		},
+		{
+			name:          "error on a later line of the statement",
+			sql:           "SELECT 1;\nUPDATE users\nSET active = false\nWHER id = 1;",
+			wantStartLine: 2,
+			wantErrorLine: 4,
+		},
+		{
+			name:          "multibyte characters before the error",
+			sql:           "SELECT 'ユーザー'\n,, 1;",
+			wantStartLine: 1,
+			wantErrorLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser().ParseSQL(tt.sql)
+			var stmtErr StatementError
+			if !errors.As(err, &stmtErr) {
+				t.Fatalf("expected a StatementError, got %v", err)
+			}
+			if stmtErr.LineNumber != tt.wantStartLine {
+				t.Errorf("LineNumber = %d, want %d", stmtErr.LineNumber, tt.wantStartLine)
+			}
+			if got := stmtErr.ErrorLine(); got != tt.wantErrorLine {
+				t.Errorf("ErrorLine() = %d, want %d", got, tt.wantErrorLine)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// TokenKind classifies SQL tokens for highlighting
+type TokenKind int
+
+const (
+	TokenOther TokenKind = iota
+	TokenKeyword
+	TokenString
+	TokenNumber
+	TokenComment
+)
+
+// Token is a classified span of SQL text as byte offsets; text between tokens is whitespace
+type Token struct {
+	Start int
+	End   int
+	Kind  TokenKind
+}
+
+// Scan splits SQL into tokens with PostgreSQL's lexer, without parsing it
+func Scan(sql string) ([]Token, error) {
+	result, err := pg_query.Scan(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]Token, 0, len(result.Tokens))
+	for _, scanned := range result.Tokens {
+		kind := TokenOther
+		switch {
+		case scanned.KeywordKind != pg_query.KeywordKind_NO_KEYWORD:
+			kind = TokenKeyword
+		case scanned.Token == pg_query.Token_SCONST || scanned.Token == pg_query.Token_USCONST:
+			kind = TokenString
+		case scanned.Token == pg_query.Token_ICONST || scanned.Token == pg_query.Token_FCONST:
+			kind = TokenNumber
+		case scanned.Token == pg_query.Token_SQL_COMMENT || scanned.Token == pg_query.Token_C_COMMENT:
+			kind = TokenComment
+		}
+		tokens = append(tokens, Token{Start: int(scanned.Start), End: int(scanned.End), Kind: kind})
+	}
+	return tokens, nil
+}
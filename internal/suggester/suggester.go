@@ -7,6 +7,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/nnaka2992/pg-lock-check/internal/identifier"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,6 +37,8 @@ type Suggestion struct {
 
 // Step represents a single step in a migration suggestion
 type Step struct {
+	ID                  string   // Identifier, unique within the suggestion
+	DependsOn           []string // IDs of steps that must finish before this one
 	Description         string
 	CanRunInTransaction bool
 	Type                string // "sql", "command", "procedural"
@@ -61,14 +64,17 @@ type operationDef struct {
 	Description string `yaml:"description"`
 	IsPartial   bool   `yaml:"partial_alternative,omitempty"`
 	Steps       []struct {
-		Type                string `yaml:"type"`
-		Description         string `yaml:"description"`
-		SQL                 string `yaml:"sql,omitempty"`
-		SQLTemplate         string `yaml:"sql_template,omitempty"`
-		Command             string `yaml:"command,omitempty"`
-		CommandTemplate     string `yaml:"command_template,omitempty"`
-		Notes               string `yaml:"notes,omitempty"`
-		CanRunInTransaction bool   `yaml:"can_run_in_transaction"`
+		ID                  string   `yaml:"id,omitempty"`
+		DependsOn           []string `yaml:"depends_on,omitempty"`
+		Type                string   `yaml:"type"`
+		Description         string   `yaml:"description"`
+		SQL                 string   `yaml:"sql,omitempty"`
+		SQLTemplate         string   `yaml:"sql_template,omitempty"`
+		Command             string   `yaml:"command,omitempty"`
+		CommandTemplate     string   `yaml:"command_template,omitempty"`
+		Notes               string   `yaml:"notes,omitempty"`
+		Tool                string   `yaml:"tool,omitempty"`
+		CanRunInTransaction bool     `yaml:"can_run_in_transaction"`
 	} `yaml:"steps"`
 }
 
@@ -89,12 +95,37 @@ func init() {
 	}
 }
 
+// Supported table rewrite tools for VACUUM FULL and CLUSTER alternatives
+const (
+	RepackToolPgRepack  = "pg_repack"
+	RepackToolPgSqueeze = "pg_squeeze"
+)
+
+// Options configures suggestion rendering
+type Options struct {
+	// RepackTool selects which tool steps are rendered for table rewrites (default: pg_repack)
+	RepackTool string
+
+	// RepackFlags are extra options inserted after pg_repack in its commands, e.g. "--no-order -j 4"
+	RepackFlags string
+}
+
 // suggester implements the Suggester interface
-type suggester struct{}
+type suggester struct {
+	options Options
+}
 
 // NewSuggester creates a new suggester instance
 func NewSuggester() Suggester {
-	return &suggester{}
+	return NewSuggesterWithOptions(Options{})
+}
+
+// NewSuggesterWithOptions creates a new suggester instance with the given options
+func NewSuggesterWithOptions(options Options) Suggester {
+	if options.RepackTool == "" {
+		options.RepackTool = RepackToolPgRepack
+	}
+	return &suggester{options: options}
 }
 
 // GetSuggestion returns a safe migration suggestion for the given operation
@@ -108,6 +139,7 @@ func (s *suggester) GetSuggestion(operation string, metadata OperationMetadata)
 	if err := s.validateCriticalFields(operation, metadata); err != nil {
 		return nil, err
 	}
+	metadata = unquoteIdentifiers(metadata)
 
 	suggestion := &Suggestion{
 		Operation:   operation,
@@ -117,8 +149,16 @@ func (s *suggester) GetSuggestion(operation string, metadata OperationMetadata)
 		Steps:       make([]Step, 0, len(def.Steps)),
 	}
 
+	rendered := make(map[string]bool, len(def.Steps))
 	for _, stepDef := range def.Steps {
+		// Skip steps written for a different repack tool
+		if stepDef.Tool != "" && stepDef.Tool != s.options.RepackTool {
+			continue
+		}
+
 		step := Step{
+			ID:                  stepDef.ID,
+			DependsOn:           s.stepDependencies(stepDef.DependsOn, suggestion.Steps, rendered),
 			Description:         stepDef.Description,
 			CanRunInTransaction: stepDef.CanRunInTransaction,
 			Type:                stepDef.Type,
@@ -174,18 +214,118 @@ func (s *suggester) GetSuggestion(operation string, metadata OperationMetadata)
 			step.Notes = content
 		}
 
+		if step.ID == "" {
+			step.ID = fmt.Sprintf("step-%d", len(suggestion.Steps)+1)
+		}
+		rendered[step.ID] = true
 		suggestion.Steps = append(suggestion.Steps, step)
 	}
 
 	return suggestion, nil
 }
 
+// stepDependencies resolves a step's dependencies: steps run in order unless the definition lists
+// its dependencies, and dependencies on steps skipped for another repack tool are dropped
+func (s *suggester) stepDependencies(dependsOn []string, previous []Step, rendered map[string]bool) []string {
+	if dependsOn == nil {
+		if len(previous) == 0 {
+			return nil
+		}
+		return []string{previous[len(previous)-1].ID}
+	}
+
+	var resolved []string
+	for _, id := range dependsOn {
+		if rendered[id] {
+			resolved = append(resolved, id)
+		}
+	}
+	return resolved
+}
+
+// identifierFields are the metadata fields holding identifiers, which templates quote with ident or idents
+var identifierFields = map[string]bool{
+	"tableName":      true,
+	"indexName":      true,
+	"columnName":     true,
+	"column":         true,
+	"columns":        true,
+	"constraintName": true,
+	"viewName":       true,
+	"schema":         true,
+	"targetTable":    true,
+	"sourceTable":    true,
+	"idColumn":       true,
+	"refTable":       true,
+	"refColumns":     true,
+	"parentTable":    true,
+	"partition":      true,
+}
+
+// unquoteIdentifiers returns a copy of metadata with already quoted identifiers unquoted, so every
+// identifier is quoted once by the templates, and names derived from it such as idx_<table>_<column>
+// are built from the plain name
+func unquoteIdentifiers(metadata OperationMetadata) OperationMetadata {
+	unquoted := make(OperationMetadata, len(metadata))
+	for field, value := range metadata {
+		if identifierFields[field] {
+			switch v := value.(type) {
+			case string:
+				value = identifier.Unquote(v)
+			case []string:
+				names := make([]string, len(v))
+				for i, name := range v {
+					names[i] = identifier.Unquote(name)
+				}
+				value = names
+			}
+		}
+		unquoted[field] = value
+	}
+	return unquoted
+}
+
+// quoteIdentifier quotes an identifier that needs it; a missing field renders as nothing
+func quoteIdentifier(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return identifier.Quote(fmt.Sprint(value))
+}
+
+// quoteIdentifiers quotes each identifier that needs it; a single identifier is treated as a list of one
+func quoteIdentifiers(value interface{}) []string {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = []string{v}
+	case []string:
+		names = v
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = identifier.Quote(name)
+	}
+	return quoted
+}
+
 // HasSuggestion returns true if a suggestion exists for the given operation
 func (s *suggester) HasSuggestion(operation string) bool {
 	_, exists := operations[operation]
 	return exists
 }
 
+// RunsOutsideTransaction reports whether the suggestion defined for the operation has a step that
+// cannot run in a transaction, independent of the options and metadata it is rendered with
+func RunsOutsideTransaction(operation string) bool {
+	for _, stepDef := range operations[operation].Steps {
+		if !stepDef.CanRunInTransaction {
+			return true
+		}
+	}
+	return false
+}
+
 // substituteTemplate renders a template with the given metadata
 func (s *suggester) substituteTemplate(tmplStr string, metadata OperationMetadata) string {
 	// Handle empty template
@@ -197,6 +337,16 @@ func (s *suggester) substituteTemplate(tmplStr string, metadata OperationMetadat
 	funcMap := template.FuncMap{
 		"join":   strings.Join,
 		"printf": fmt.Sprintf,
+		// ident and idents quote identifiers such as reserved words or names with special characters
+		"ident":  quoteIdentifier,
+		"idents": quoteIdentifiers,
+		// repackFlags renders the configured pg_repack options with a leading space
+		"repackFlags": func() string {
+			if s.options.RepackFlags == "" {
+				return ""
+			}
+			return " " + s.options.RepackFlags
+		},
 		"required": func(value interface{}, fieldName string) (interface{}, error) {
 			if value == nil || value == "" {
 				return nil, fmt.Errorf("missing required field: %s", s.fieldDisplayName(fieldName))
@@ -250,6 +400,7 @@ func (s *suggester) fieldDisplayName(field string) string {
 		"column":           "Column",
 		"viewName":         "ViewName",
 		"schema":           "Schema",
+		"parentTable":      "ParentTable",
 	}
 
 	if display, ok := displayNames[field]; ok {
@@ -266,12 +417,14 @@ func (s *suggester) fieldDisplayName(field string) string {
 func (s *suggester) validateCriticalFields(operation string, metadata OperationMetadata) error {
 	// Only validate fields that would cause invalid SQL
 	criticalFields := map[string][]string{
-		"CREATE INDEX":        {"tableName", "columns"},
-		"CREATE UNIQUE INDEX": {"tableName", "columns"},
-		"DROP INDEX":          {"indexName"},
-		"REINDEX":             {"indexName"},
-		"REINDEX TABLE":       {"tableName"},
-		"REINDEX SCHEMA":      {"schema"},
+		"CREATE INDEX":                             {"tableName", "columns"},
+		"CREATE UNIQUE INDEX":                      {"tableName", "columns"},
+		"CREATE INDEX ON PARTITIONED TABLE":        {"tableName", "columns"},
+		"CREATE UNIQUE INDEX ON PARTITIONED TABLE": {"tableName", "columns"},
+		"DROP INDEX":                               {"indexName"},
+		"REINDEX":                                  {"indexName"},
+		"REINDEX TABLE":                            {"tableName"},
+		"REINDEX SCHEMA":                           {"schema"},
 		// DML operations can use defaults, so less critical
 		"UPDATE without WHERE": {"tableName", "idColumn", "columnsValues"},
 		"DELETE without WHERE": {"tableName", "idColumn"},
@@ -282,7 +435,9 @@ func (s *suggester) validateCriticalFields(operation string, metadata OperationM
 		"ALTER TABLE ADD PRIMARY KEY":                  {"tableName", "columns"},
 		"ALTER TABLE ADD CONSTRAINT CHECK":             {"tableName", "constraintName"},
 		"ALTER TABLE SET NOT NULL":                     {"tableName", "column"},
-		"CLUSTER":                                      {"tableName", "indexName"},
+		"ALTER TABLE ENABLE ROW LEVEL SECURITY":        {"tableName"},
+		"ALTER TABLE ATTACH PARTITION":                 {"parentTable", "partition", "bounds"},
+		"CLUSTER":                                      {"tableName"},
 		"REFRESH MATERIALIZED VIEW":                    {"viewName"},
 		"VACUUM FULL":                                  {"tableName"},
 	}
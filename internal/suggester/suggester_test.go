@@ -2,8 +2,11 @@ package suggester
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
 )
 
 // Test helpers
@@ -201,8 +204,104 @@ func TestSuggester_SpecialCharacters(t *testing.T) {
 				t.Fatalf("GetSuggestion() error = %v", err)
 			}
 
-			if len(suggestion.Steps) > 0 && suggestion.Steps[0].Type == "sql" {
-				tt.checkSQL(t, suggestion.Steps[0].SQL)
+			var sql []string
+			for _, step := range suggestion.Steps {
+				if step.Type == "sql" {
+					sql = append(sql, step.SQL)
+				}
+			}
+			tt.checkSQL(t, strings.Join(sql, "\n"))
+		})
+	}
+}
+
+func TestSuggester_QuotesIdentifiers(t *testing.T) {
+	s := NewSuggester()
+
+	tests := []struct {
+		name      string
+		operation string
+		metadata  OperationMetadata
+		wantSQL   []string
+	}{
+		{
+			name:      "hyphen in table name",
+			operation: "CREATE INDEX",
+			metadata:  OperationMetadata{"tableName": "user-accounts", "columns": []string{"email"}},
+			wantSQL:   []string{`CREATE INDEX CONCURRENTLY "idx_user-accounts_email" ON "user-accounts" (email);`},
+		},
+		{
+			name:      "reserved words and mixed case",
+			operation: "CREATE UNIQUE INDEX",
+			metadata:  OperationMetadata{"tableName": "user", "columns": []string{"Email", "order"}},
+			wantSQL:   []string{`CREATE UNIQUE INDEX CONCURRENTLY "uniq_user_Email_order" ON "user" ("Email", "order");`},
+		},
+		{
+			name:      "already quoted identifiers are quoted once",
+			operation: "CREATE INDEX",
+			metadata:  OperationMetadata{"tableName": `"user-accounts"`, "columns": []string{`"first name"`}},
+			wantSQL:   []string{`CREATE INDEX CONCURRENTLY "idx_user-accounts_first name" ON "user-accounts" ("first name");`},
+		},
+		{
+			name:      "space in column name",
+			operation: "ALTER TABLE SET NOT NULL",
+			metadata:  OperationMetadata{"tableName": "Order Items", "column": "first name"},
+			wantSQL: []string{
+				`ALTER TABLE "Order Items" ADD CONSTRAINT "Order Items_first name_not_null" CHECK ("first name" IS NOT NULL) NOT VALID;`,
+				`ALTER TABLE "Order Items" ALTER COLUMN "first name" SET NOT NULL;`,
+			},
+		},
+		{
+			name:      "foreign key columns and referenced table",
+			operation: "ALTER TABLE ADD FOREIGN KEY",
+			metadata: OperationMetadata{
+				"tableName":  "order",
+				"columns":    []string{"user-id"},
+				"refTable":   "user",
+				"refColumns": []string{"id"},
+			},
+			wantSQL: []string{`ALTER TABLE "order" ADD CONSTRAINT "order_user-id_fkey" FOREIGN KEY ("user-id") REFERENCES "user" (id) NOT VALID;`},
+		},
+		{
+			name:      "schema-qualified index with a space",
+			operation: "REINDEX",
+			metadata:  OperationMetadata{"indexName": "My Index", "schema": "Sales Data"},
+			wantSQL:   []string{`REINDEX INDEX CONCURRENTLY "Sales Data"."My Index";`},
+		},
+		{
+			name:      "column type change helpers",
+			operation: "ALTER TABLE ALTER COLUMN TYPE",
+			metadata:  OperationMetadata{"tableName": "Users", "columnName": "user", "newType": "bigint"},
+			wantSQL: []string{
+				`ALTER TABLE "Users" ADD COLUMN user_new bigint;`,
+				`CREATE TRIGGER "Users_user_sync_trigger"`,
+				`ALTER TABLE "Users" RENAME COLUMN user_new TO "user";`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion, err := s.GetSuggestion(tt.operation, tt.metadata)
+			if err != nil {
+				t.Fatalf("GetSuggestion() error = %v", err)
+			}
+
+			var all []string
+			for _, step := range suggestion.Steps {
+				if step.Type != "sql" {
+					continue
+				}
+				all = append(all, step.SQL)
+				// Every rendered SQL step must be accepted by PostgreSQL's parser
+				if _, err := pg_query.Parse(step.SQL); err != nil {
+					t.Errorf("Step %s is not valid SQL: %v\n%s", step.ID, err, step.SQL)
+				}
+			}
+			for _, want := range tt.wantSQL {
+				if !strings.Contains(strings.Join(all, "\n"), want) {
+					t.Errorf("Expected SQL to contain %q, got:\n%s", want, strings.Join(all, "\n"))
+				}
 			}
 		})
 	}
@@ -255,10 +354,14 @@ func TestSuggester_HasSuggestion(t *testing.T) {
 		{"has suggestion - ALTER TABLE ALTER COLUMN TYPE", "ALTER TABLE ALTER COLUMN TYPE"},
 		{"has suggestion - ALTER TABLE ADD PRIMARY KEY", "ALTER TABLE ADD PRIMARY KEY"},
 		{"has suggestion - ALTER TABLE ADD CONSTRAINT CHECK", "ALTER TABLE ADD CONSTRAINT CHECK"},
+		{"has suggestion - ALTER TABLE ADD FOREIGN KEY", "ALTER TABLE ADD FOREIGN KEY"},
 		{"has suggestion - ALTER TABLE SET NOT NULL", "ALTER TABLE SET NOT NULL"},
+		{"has suggestion - ALTER TABLE ENABLE ROW LEVEL SECURITY", "ALTER TABLE ENABLE ROW LEVEL SECURITY"},
+		{"has suggestion - ALTER TABLE ATTACH PARTITION", "ALTER TABLE ATTACH PARTITION"},
 
 		// Maintenance Operations with suggestions
 		{"has suggestion - CLUSTER", "CLUSTER"},
+		{"has suggestion - CLUSTER without table", "CLUSTER without table"},
 		{"has suggestion - REFRESH MATERIALIZED VIEW", "REFRESH MATERIALIZED VIEW"},
 		{"has suggestion - VACUUM FULL", "VACUUM FULL"},
 	}
@@ -359,6 +462,31 @@ func TestSuggester_DMLOperations(t *testing.T) {
 
 		// Procedural notes should mention MERGE
 		assertProceduralStep(t, suggestion.Steps[1], "MERGE", "WHEN MATCHED")
+		if strings.Contains(suggestion.Steps[1].Notes, "DELETE") {
+			t.Errorf("Notes should not mention DELETE branches for a MERGE without them, got:\n%s", suggestion.Steps[1].Notes)
+		}
+	})
+
+	t.Run("MERGE with DELETE branches", func(t *testing.T) {
+		metadata := OperationMetadata{
+			"sourceTable":              "staging_users",
+			"targetTable":              "users",
+			"mergeCondition":           "target.user_id = source.user_id",
+			"matchedAction":            "DELETE",
+			"notMatchedAction":         "DO NOTHING",
+			"deleteMatched":            true,
+			"deleteNotMatchedBySource": true,
+		}
+
+		suggestion, err := s.GetSuggestion("MERGE without WHERE", metadata)
+		if err != nil {
+			t.Fatalf("GetSuggestion() error = %v", err)
+		}
+
+		assertProceduralStep(t, suggestion.Steps[1],
+			"Keep WHEN MATCHED ... THEN DELETE",
+			"Never batch WHEN NOT MATCHED BY SOURCE THEN DELETE by source chunk",
+			"delete the target rows missing from staging_users in batches")
 	})
 }
 
@@ -377,12 +505,46 @@ func TestSuggester_IndexOperations(t *testing.T) {
 			t.Fatalf("GetSuggestion() error = %v", err)
 		}
 
-		want := "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\n"
+		if len(suggestion.Steps) != 3 {
+			t.Fatalf("Steps count = %v, want 3", len(suggestion.Steps))
+		}
+
+		// Step 1: Check for an INVALID index from a failed attempt
+		want := "SELECT indisvalid FROM pg_index WHERE indexrelid = to_regclass('idx_users_email');\n" +
+			"-- false: a failed earlier attempt left it INVALID; drop it outside a transaction before creating it\n"
 		assertSQLStep(t, suggestion.Steps[0], want)
 
-		// Must run outside transaction
-		if suggestion.Steps[0].CanRunInTransaction {
-			t.Errorf("CREATE INDEX CONCURRENTLY must run outside transaction")
+		// Step 2: Create the index concurrently
+		want = "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);\n"
+		assertSQLStep(t, suggestion.Steps[1], want)
+
+		// Step 3: Verify the new index is valid
+		if !strings.HasPrefix(suggestion.Steps[2].SQL, "SELECT indisvalid FROM pg_index WHERE indexrelid = 'idx_users_email'::regclass;\n") {
+			t.Errorf("Step 3 should verify pg_index.indisvalid, got:\n%s", suggestion.Steps[2].SQL)
+		}
+
+		// Only the create step must run outside a transaction
+		for i, step := range suggestion.Steps {
+			if step.CanRunInTransaction != (i != 1) {
+				t.Errorf("Step %d CanRunInTransaction = %v, want %v", i+1, step.CanRunInTransaction, i != 1)
+			}
+		}
+	})
+
+	t.Run("indexes are only dropped when a failed attempt left them INVALID", func(t *testing.T) {
+		metadata := OperationMetadata{"tableName": "users", "columns": []string{"email"}}
+		for _, op := range []string{"CREATE INDEX", "CREATE UNIQUE INDEX", "CREATE INDEX ON PARTITIONED TABLE", "CREATE UNIQUE INDEX ON PARTITIONED TABLE"} {
+			suggestion, err := s.GetSuggestion(op, metadata)
+			if err != nil {
+				t.Fatalf("GetSuggestion(%q) error = %v", op, err)
+			}
+			for i, step := range suggestion.Steps {
+				for _, line := range strings.Split(step.SQL+step.Notes, "\n") {
+					if strings.Contains(line, "DROP INDEX") && !strings.Contains(line, "INVALID") {
+						t.Errorf("%s step %d drops an index that may be valid: %s", op, i+1, line)
+					}
+				}
+			}
 		}
 	})
 
@@ -398,8 +560,10 @@ func TestSuggester_IndexOperations(t *testing.T) {
 		}
 
 		expectedName := fmt.Sprintf(defaultIndexNamePattern, "orders", "status_created_at")
-		if !strings.Contains(suggestion.Steps[0].SQL, expectedName) {
-			t.Errorf("Should generate default index name %s", expectedName)
+		for i, step := range suggestion.Steps {
+			if !strings.Contains(step.SQL, expectedName) {
+				t.Errorf("Step %d should use default index name %s", i+1, expectedName)
+			}
 		}
 	})
 
@@ -415,7 +579,14 @@ func TestSuggester_IndexOperations(t *testing.T) {
 			t.Fatalf("GetSuggestion() error = %v", err)
 		}
 
-		sql := suggestion.Steps[0].SQL
+		if !strings.Contains(suggestion.Steps[0].SQL, "to_regclass('uniq_users_email_tenant')") {
+			t.Errorf("Check step should look up the unique index, got %q", suggestion.Steps[0].SQL)
+		}
+		if !strings.Contains(suggestion.Steps[2].SQL, "duplicate values") {
+			t.Errorf("Verify step should mention duplicate values, got %q", suggestion.Steps[2].SQL)
+		}
+
+		sql := suggestion.Steps[1].SQL
 		if !strings.Contains(sql, "CREATE UNIQUE INDEX CONCURRENTLY") {
 			t.Errorf("Should use CREATE UNIQUE INDEX CONCURRENTLY")
 		}
@@ -423,6 +594,39 @@ func TestSuggester_IndexOperations(t *testing.T) {
 			t.Errorf("Should include both columns in order")
 		}
 	})
+
+	t.Run("CREATE INDEX ON PARTITIONED TABLE per partition", func(t *testing.T) {
+		metadata := OperationMetadata{
+			"tableName": "events",
+			"indexName": "idx_events_at",
+			"columns":   []string{"at"},
+		}
+
+		suggestion, err := s.GetSuggestion("CREATE INDEX ON PARTITIONED TABLE", metadata)
+		if err != nil {
+			t.Fatalf("GetSuggestion() error = %v", err)
+		}
+
+		if want := "CREATE INDEX IF NOT EXISTS idx_events_at ON ONLY events (at);\n"; suggestion.Steps[0].SQL != want {
+			t.Errorf("Parent step SQL = %q, want %q", suggestion.Steps[0].SQL, want)
+		}
+		if !suggestion.Steps[0].CanRunInTransaction {
+			t.Errorf("ON ONLY step should be able to run in a transaction")
+		}
+
+		last := suggestion.Steps[len(suggestion.Steps)-1]
+		if last.CanRunInTransaction {
+			t.Errorf("Per-partition step should not run in a transaction")
+		}
+		for _, want := range []string{
+			"CREATE INDEX CONCURRENTLY <partition>_at_idx ON <partition> (at)",
+			"ALTER INDEX idx_events_at ATTACH PARTITION <partition>_at_idx",
+		} {
+			if !strings.Contains(last.Notes, want) {
+				t.Errorf("Per-partition step should contain %q, got:\n%s", want, last.Notes)
+			}
+		}
+	})
 }
 
 func TestSuggester_REINDEXOperations(t *testing.T) {
@@ -572,6 +776,35 @@ func TestSuggester_AlterTableOperations_AlterType(t *testing.T) {
 	}
 }
 
+func TestSuggester_AlterTableOperations_AlterTypeUsing(t *testing.T) {
+	s := NewSuggester()
+
+	metadata := OperationMetadata{
+		"tableName":         "events",
+		"columnName":        "payload",
+		"newType":           "JSONB",
+		"usingExpression":   "payload::jsonb",
+		"triggerExpression": "new.payload::jsonb",
+	}
+
+	suggestion, err := s.GetSuggestion("ALTER TABLE ALTER COLUMN TYPE", metadata)
+	if err != nil {
+		t.Fatalf("GetSuggestion() error = %v", err)
+	}
+
+	// Sync trigger applies the USING expression instead of a plain cast
+	sql := suggestion.Steps[1].SQL
+	if !strings.Contains(sql, "NEW.payload_new := new.payload::jsonb;") {
+		t.Errorf("Sync function should apply the USING expression, got:\n%s", sql)
+	}
+	if strings.Contains(sql, "::JSONB") {
+		t.Errorf("Sync function should not fall back to a plain cast, got:\n%s", sql)
+	}
+
+	// Backfill applies the same expression
+	assertProceduralStep(t, suggestion.Steps[2], "UPDATE events SET payload_new = payload::jsonb")
+}
+
 func TestSuggester_AlterTableOperations_Constraints(t *testing.T) {
 	s := NewSuggester()
 
@@ -655,6 +888,94 @@ func TestSuggester_AlterTableOperations_Constraints(t *testing.T) {
 			t.Errorf("Step 4 should drop the check constraint")
 		}
 	})
+
+	t.Run("ENABLE ROW LEVEL SECURITY", func(t *testing.T) {
+		metadata := OperationMetadata{
+			"tableName": "accounts",
+			"policies":  []string{"CREATE POLICY tenant_isolation ON accounts USING (tenant_id = current_setting('app.tenant_id')::int);"},
+		}
+
+		suggestion, err := s.GetSuggestion("ALTER TABLE ENABLE ROW LEVEL SECURITY", metadata)
+		if err != nil {
+			t.Fatalf("GetSuggestion() error = %v", err)
+		}
+
+		if len(suggestion.Steps) != 3 {
+			t.Fatalf("Steps count = %v, want 3", len(suggestion.Steps))
+		}
+		if !strings.Contains(suggestion.Description, "denies every role but the owner access") {
+			t.Errorf("Description should warn that RLS without policies denies access, got %q", suggestion.Description)
+		}
+
+		// Step 1: Policies come before RLS is enabled
+		assertStep(t, suggestion.Steps[0], "sql", true)
+		if !strings.Contains(suggestion.Steps[0].SQL, "CREATE POLICY tenant_isolation ON accounts") {
+			t.Errorf("Step 1 should create the given policies, got:\n%s", suggestion.Steps[0].SQL)
+		}
+		if strings.Contains(suggestion.Steps[0].SQL, "ENABLE ROW LEVEL SECURITY") {
+			t.Errorf("Step 1 should not enable row level security, got:\n%s", suggestion.Steps[0].SQL)
+		}
+
+		// Step 2: Verify with a test role
+		assertProceduralStep(t, suggestion.Steps[1], "SET ROLE <test_role>")
+
+		// Step 3: Enable in a short transaction with lock_timeout
+		assertStep(t, suggestion.Steps[2], "sql", true)
+		for _, want := range []string{"SET LOCAL lock_timeout", "ALTER TABLE accounts ENABLE ROW LEVEL SECURITY;", "FORCE ROW LEVEL SECURITY", "denies all access"} {
+			if !strings.Contains(suggestion.Steps[2].SQL, want) {
+				t.Errorf("Step 3 should contain %q, got:\n%s", want, suggestion.Steps[2].SQL)
+			}
+		}
+	})
+
+	t.Run("ATTACH PARTITION", func(t *testing.T) {
+		metadata := OperationMetadata{
+			"parentTable": "events",
+			"partition":   "events_2024",
+			"bounds":      "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')",
+			"boundsCheck": "<partition_key> IS NOT NULL AND <partition_key> >= '2024-01-01' AND <partition_key> < '2025-01-01'",
+		}
+
+		suggestion, err := s.GetSuggestion("ALTER TABLE ATTACH PARTITION", metadata)
+		if err != nil {
+			t.Fatalf("GetSuggestion() error = %v", err)
+		}
+		if len(suggestion.Steps) != 4 {
+			t.Fatalf("Steps count = %v, want 4", len(suggestion.Steps))
+		}
+
+		// The bounds are proven by a validated CHECK constraint before the attach
+		want := "ALTER TABLE events_2024 ADD CONSTRAINT events_2024_partition_check CHECK (<partition_key> IS NOT NULL AND <partition_key> >= '2024-01-01' AND <partition_key> < '2025-01-01') NOT VALID;\n"
+		assertSQLStep(t, suggestion.Steps[0], want)
+		want = "ALTER TABLE events_2024 VALIDATE CONSTRAINT events_2024_partition_check;\n"
+		assertSQLStep(t, suggestion.Steps[1], want)
+
+		assertStep(t, suggestion.Steps[2], "sql", true)
+		if !strings.Contains(suggestion.Steps[2].SQL, "ALTER TABLE events ATTACH PARTITION events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');") {
+			t.Errorf("Step 3 should attach the partition, got:\n%s", suggestion.Steps[2].SQL)
+		}
+
+		want = "ALTER TABLE events_2024 DROP CONSTRAINT events_2024_partition_check;\n"
+		assertSQLStep(t, suggestion.Steps[3], want)
+	})
+
+	t.Run("ATTACH PARTITION without bounds", func(t *testing.T) {
+		// DEFAULT and HASH partitions have no bounds a CHECK constraint can express
+		_, err := s.GetSuggestion("ALTER TABLE ATTACH PARTITION", OperationMetadata{"parentTable": "events", "partition": "events_default"})
+		if err == nil || !strings.Contains(err.Error(), "Bounds") {
+			t.Errorf("Expected a missing bounds error, got %v", err)
+		}
+	})
+
+	t.Run("ENABLE ROW LEVEL SECURITY without policies", func(t *testing.T) {
+		suggestion, err := s.GetSuggestion("ALTER TABLE ENABLE ROW LEVEL SECURITY", OperationMetadata{"tableName": "accounts"})
+		if err != nil {
+			t.Fatalf("GetSuggestion() error = %v", err)
+		}
+		if !strings.Contains(suggestion.Steps[0].SQL, "CREATE POLICY accounts_access ON accounts") {
+			t.Errorf("Step 1 should fall back to a placeholder policy, got:\n%s", suggestion.Steps[0].SQL)
+		}
+	})
 }
 
 func TestSuggester_MaintenanceOperations(t *testing.T) {
@@ -682,6 +1003,23 @@ func TestSuggester_MaintenanceOperations(t *testing.T) {
 		assertExternalStep(t, suggestion.Steps[0], "pg_repack -t users -i users_pkey -d <YOUR_DATABASE>")
 	})
 
+	t.Run("CLUSTER without table", func(t *testing.T) {
+		suggestion, err := s.GetSuggestion("CLUSTER without table", OperationMetadata{})
+		if err != nil {
+			t.Fatalf("GetSuggestion() error = %v", err)
+		}
+		if len(suggestion.Steps) != 2 {
+			t.Fatalf("Steps count = %v, want 2", len(suggestion.Steps))
+		}
+
+		// The clustered tables are listed first, then repacked one by one
+		assertStep(t, suggestion.Steps[0], "sql", true)
+		if !strings.Contains(suggestion.Steps[0].SQL, "WHERE i.indisclustered") {
+			t.Errorf("Step 1 should list the clustered tables, got:\n%s", suggestion.Steps[0].SQL)
+		}
+		assertProceduralStep(t, suggestion.Steps[1], "pg_repack -t <table_name> -i <index_name>")
+	})
+
 	t.Run("REFRESH MATERIALIZED VIEW", func(t *testing.T) {
 		metadata := OperationMetadata{
 			"viewName": "sales_summary",
@@ -724,6 +1062,131 @@ func TestSuggester_MaintenanceOperations(t *testing.T) {
 	})
 }
 
+func TestSuggester_RepackTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      string
+		flags     string
+		operation string
+		metadata  OperationMetadata
+		wantType  string
+		want      string
+	}{
+		{
+			name:      "VACUUM FULL default tool",
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "logs"},
+			wantType:  "external",
+			want:      "pg_repack -n -t logs -d <YOUR_DATABASE>\n",
+		},
+		{
+			name:      "VACUUM FULL with pg_repack",
+			tool:      RepackToolPgRepack,
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "logs"},
+			wantType:  "external",
+			want:      "pg_repack -n -t logs -d <YOUR_DATABASE>\n",
+		},
+		{
+			name:      "VACUUM FULL with pg_squeeze",
+			tool:      RepackToolPgSqueeze,
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "logs"},
+			wantType:  "sql",
+			want:      "SELECT squeeze.squeeze_table('public', 'logs');\n",
+		},
+		{
+			name:      "VACUUM FULL with pg_squeeze and schema",
+			tool:      RepackToolPgSqueeze,
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "logs", "schema": "audit"},
+			wantType:  "sql",
+			want:      "SELECT squeeze.squeeze_table('audit', 'logs');\n",
+		},
+		{
+			name:      "CLUSTER with pg_repack",
+			tool:      RepackToolPgRepack,
+			operation: "CLUSTER",
+			metadata:  OperationMetadata{"tableName": "users", "indexName": "users_pkey"},
+			wantType:  "external",
+			want:      "pg_repack -t users -i users_pkey -d <YOUR_DATABASE>\n",
+		},
+		{
+			name:      "CLUSTER with pg_squeeze",
+			tool:      RepackToolPgSqueeze,
+			operation: "CLUSTER",
+			metadata:  OperationMetadata{"tableName": "users", "indexName": "users_pkey"},
+			wantType:  "sql",
+			want:      "SELECT squeeze.squeeze_table('public', 'users', 'users_pkey');\n",
+		},
+		{
+			name:      "CLUSTER on the previous index with pg_repack",
+			tool:      RepackToolPgRepack,
+			operation: "CLUSTER",
+			metadata:  OperationMetadata{"tableName": "users"},
+			wantType:  "external",
+			want:      "pg_repack -t users -d <YOUR_DATABASE>\n",
+		},
+		{
+			name:      "CLUSTER on the previous index with pg_squeeze",
+			tool:      RepackToolPgSqueeze,
+			operation: "CLUSTER",
+			metadata:  OperationMetadata{"tableName": "users", "schema": "app"},
+			wantType:  "sql",
+			want:      "SELECT squeeze.squeeze_table('app', 'users', (SELECT c.relname FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid WHERE i.indrelid = 'app.users'::regclass AND i.indisclustered));\n",
+		},
+		{
+			name:      "VACUUM FULL with pg_repack flags",
+			flags:     "-j 4 --wait-timeout 60 --no-superuser-check",
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "logs"},
+			wantType:  "external",
+			want:      "pg_repack -j 4 --wait-timeout 60 --no-superuser-check -n -t logs -d <YOUR_DATABASE>\n",
+		},
+		{
+			name:      "CLUSTER with pg_repack flags",
+			tool:      RepackToolPgRepack,
+			flags:     "--no-order -j 4",
+			operation: "CLUSTER",
+			metadata:  OperationMetadata{"tableName": "users", "indexName": "users_pkey"},
+			wantType:  "external",
+			want:      "pg_repack --no-order -j 4 -t users -i users_pkey -d <YOUR_DATABASE>\n",
+		},
+		{
+			name:      "pg_squeeze ignores pg_repack flags",
+			tool:      RepackToolPgSqueeze,
+			flags:     "-j 4",
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "logs"},
+			wantType:  "sql",
+			want:      "SELECT squeeze.squeeze_table('public', 'logs');\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSuggesterWithOptions(Options{RepackTool: tt.tool, RepackFlags: tt.flags})
+			suggestion, err := s.GetSuggestion(tt.operation, tt.metadata)
+			if err != nil {
+				t.Fatalf("GetSuggestion() error = %v", err)
+			}
+			if len(suggestion.Steps) != 1 {
+				t.Fatalf("expected 1 step, got %d", len(suggestion.Steps))
+			}
+
+			step := suggestion.Steps[0]
+			assertStep(t, step, tt.wantType, false)
+			got := step.Command
+			if tt.wantType == "sql" {
+				got = step.SQL
+			}
+			if got != tt.want {
+				t.Errorf("rendered step = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSuggester_TemplateRendering(t *testing.T) {
 	s := NewSuggester()
 
@@ -756,7 +1219,7 @@ func TestSuggester_TemplateRendering(t *testing.T) {
 			t.Fatalf("GetSuggestion() error = %v", err)
 		}
 
-		if !strings.Contains(suggestion.Steps[0].SQL, "(email, tenant_id, status)") {
+		if !strings.Contains(suggestion.Steps[1].SQL, "(email, tenant_id, status)") {
 			t.Errorf("Should join columns with commas and parentheses")
 		}
 	})
@@ -946,6 +1409,14 @@ func TestSuggester_AllOperationsValidation(t *testing.T) {
 			"CREATE UNIQUE INDEX",
 			OperationMetadata{"tableName": "test", "columns": []string{"col"}},
 		},
+		{
+			"CREATE INDEX ON PARTITIONED TABLE",
+			OperationMetadata{"tableName": "test", "columns": []string{"col"}},
+		},
+		{
+			"CREATE UNIQUE INDEX ON PARTITIONED TABLE",
+			OperationMetadata{"tableName": "test", "columns": []string{"col"}},
+		},
 		{
 			"REINDEX",
 			OperationMetadata{"indexName": "idx_test"},
@@ -988,10 +1459,25 @@ func TestSuggester_AllOperationsValidation(t *testing.T) {
 				"checkExpression": "col > 0",
 			},
 		},
+		{
+			"ALTER TABLE ADD FOREIGN KEY",
+			OperationMetadata{
+				"tableName": "test", "columns": []string{"parent_id"},
+				"refTable": "parent",
+			},
+		},
 		{
 			"ALTER TABLE SET NOT NULL",
 			OperationMetadata{"tableName": "test", "column": "col"},
 		},
+		{
+			"ALTER TABLE ENABLE ROW LEVEL SECURITY",
+			OperationMetadata{"tableName": "test"},
+		},
+		{
+			"ALTER TABLE ATTACH PARTITION",
+			OperationMetadata{"parentTable": "test", "partition": "test_1", "bounds": "FOR VALUES IN (1)", "boundsCheck": "<partition_key> IN (1)"},
+		},
 		// Maintenance Operations
 		{
 			"CLUSTER",
@@ -1005,6 +1491,10 @@ func TestSuggester_AllOperationsValidation(t *testing.T) {
 			"VACUUM FULL",
 			OperationMetadata{"tableName": "test"},
 		},
+		{
+			"CLUSTER without table",
+			OperationMetadata{},
+		},
 	}
 
 	for _, op := range operations {
@@ -1065,6 +1555,83 @@ func TestSuggester_AllOperationsValidation(t *testing.T) {
 	}
 }
 
+func TestSuggester_StepDependencies(t *testing.T) {
+	indexMetadata := OperationMetadata{"tableName": "test", "columns": []string{"col"}}
+
+	tests := []struct {
+		name      string
+		options   Options
+		operation string
+		metadata  OperationMetadata
+		wantIDs   []string
+		wantDeps  [][]string
+	}{
+		{
+			name:      "ADD PRIMARY KEY attaches after the concurrent build",
+			operation: "ALTER TABLE ADD PRIMARY KEY",
+			metadata:  OperationMetadata{"tableName": "test", "columns": []string{"id"}},
+			wantIDs:   []string{"create-unique-index", "add-primary-key"},
+			wantDeps:  [][]string{nil, {"create-unique-index"}},
+		},
+		{
+			name:      "partitioned index steps without dependencies can run in parallel",
+			operation: "CREATE INDEX ON PARTITIONED TABLE",
+			metadata:  indexMetadata,
+			wantIDs:   []string{"create-parent-index", "list-partitions", "index-partitions"},
+			wantDeps:  [][]string{nil, nil, {"create-parent-index", "list-partitions"}},
+		},
+		{
+			name:      "steps without listed dependencies run in order",
+			operation: "ALTER TABLE ALTER COLUMN TYPE",
+			metadata:  OperationMetadata{"tableName": "test", "columnName": "col", "newType": "bigint"},
+			wantIDs:   []string{"add-column", "add-sync-trigger", "backfill", "swap-columns"},
+			wantDeps:  [][]string{nil, {"add-column"}, {"add-sync-trigger"}, {"backfill"}},
+		},
+		{
+			name:      "steps for another repack tool are skipped",
+			options:   Options{RepackTool: RepackToolPgSqueeze},
+			operation: "VACUUM FULL",
+			metadata:  OperationMetadata{"tableName": "test"},
+			wantIDs:   []string{"squeeze-table"},
+			wantDeps:  [][]string{nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion, err := NewSuggesterWithOptions(tt.options).GetSuggestion(tt.operation, tt.metadata)
+			if err != nil {
+				t.Fatalf("GetSuggestion() error = %v", err)
+			}
+			if len(suggestion.Steps) != len(tt.wantIDs) {
+				t.Fatalf("Expected %d steps, got %d", len(tt.wantIDs), len(suggestion.Steps))
+			}
+			for i, step := range suggestion.Steps {
+				if step.ID != tt.wantIDs[i] {
+					t.Errorf("Step %d ID = %q, want %q", i+1, step.ID, tt.wantIDs[i])
+				}
+				if !reflect.DeepEqual(step.DependsOn, tt.wantDeps[i]) {
+					t.Errorf("Step %d DependsOn = %v, want %v", i+1, step.DependsOn, tt.wantDeps[i])
+				}
+			}
+		})
+	}
+
+	// Every step needs an ID that is unique within its suggestion
+	for operation, def := range operations {
+		seen := make(map[string]bool)
+		for i, step := range def.Steps {
+			if step.ID == "" {
+				t.Errorf("%s step %d has no id", operation, i+1)
+			}
+			if seen[step.ID] && step.Tool == "" {
+				t.Errorf("%s has duplicate step id %q", operation, step.ID)
+			}
+			seen[step.ID] = true
+		}
+	}
+}
+
 func TestSuggester_TransactionModeConsistency(t *testing.T) {
 	s := NewSuggester()
 
@@ -1089,6 +1656,10 @@ func TestSuggester_TransactionModeConsistency(t *testing.T) {
 				metadata = OperationMetadata{"viewName": "test_view"}
 			}
 
+			if !RunsOutsideTransaction(op) {
+				t.Errorf("RunsOutsideTransaction(%q) = false, want true", op)
+			}
+
 			suggestion, err := s.GetSuggestion(op, metadata)
 			if err != nil {
 				t.Fatalf("GetSuggestion() error = %v", err)
@@ -1106,6 +1677,15 @@ func TestSuggester_TransactionModeConsistency(t *testing.T) {
 	}
 }
 
+func TestRunsOutsideTransaction(t *testing.T) {
+	// Every step of these runs in a transaction, and unknown operations have no steps
+	for _, op := range []string{"ALTER TABLE ADD CONSTRAINT CHECK", "SELECT"} {
+		if RunsOutsideTransaction(op) {
+			t.Errorf("RunsOutsideTransaction(%q) = true, want false", op)
+		}
+	}
+}
+
 // Benchmarks
 func BenchmarkSuggester_GetSuggestion(b *testing.B) {
 	s := NewSuggester()